@@ -4,23 +4,71 @@ import (
 	"encoding/json"
 	"errors"
 	"fmt"
+	"reflect"
 	"slices"
+	"strings"
+	"time"
 
+	"go.nhat.io/clock"
 	"go.nhat.io/otp"
 	"go.nhat.io/secretstorage"
+	"go.uber.org/multierr"
 )
 
-// ErrAccountNotFound indicates that the account was not found.
-var ErrAccountNotFound = errors.New("account not found")
+var (
+	// ErrAccountNotFound indicates that the account was not found.
+	ErrAccountNotFound = errors.New("account not found")
+	// ErrAccountExists indicates that the account already exists.
+	ErrAccountExists = errors.New("account already exists")
+	// ErrUnsupportedSortKey indicates that the accounts cannot be sorted by the given key.
+	ErrUnsupportedSortKey = errors.New("unsupported sort key")
+)
+
+// SortKey is the order of the accounts returned by ListAccountsSorted.
+type SortKey int
+
+const (
+	// SortByName sorts the accounts by name.
+	SortByName SortKey = iota
+	// SortByIssuer groups the accounts by issuer, sorted by name within each issuer.
+	SortByIssuer
+	// SortByCreatedAt sorts the accounts from the most recently created.
+	SortByCreatedAt
+	// SortByUpdatedAt sorts the accounts from the most recently updated.
+	SortByUpdatedAt
+)
 
-var accountStorage secretstorage.Storage[Account] = secretstorage.NewKeyringStorage[Account]()
+const (
+	// TypeTOTP is the type of accounts using time-based one-time passwords.
+	TypeTOTP = "totp"
+	// TypeHOTP is the type of accounts using counter-based one-time passwords.
+	TypeHOTP = "hotp"
+)
+
+var (
+	accountStorage secretstorage.Storage[Account] = secretstorage.NewKeyringStorage[Account]()
+	accountClock   clock.Clock                    = clock.New()
+)
 
 // Account represents an account.
+//
+// The zero values of Digits, Period and Algorithm mean the defaults: 6 digits, 30 seconds and SHA1. CreatedAt and
+// UpdatedAt are set when the account is stored. ImageURL is the icon of the account, from the otpauth image parameter.
+// The empty Type means TypeTOTP. Epoch is the time the TOTP time steps are counted from, in unix seconds, see WithEpoch.
 type Account struct {
 	Name       string         `json:"name" toml:"name" yaml:"name"`
 	TOTPSecret otp.TOTPSecret `json:"totp_secret" toml:"totp_secret" yaml:"totp_secret"`
 	Issuer     string         `json:"issuer" toml:"issuer" yaml:"issuer"`
+	Type       string         `json:"type" toml:"type" yaml:"type"`
+	Counter    uint64         `json:"counter" toml:"counter" yaml:"counter"`
+	Digits     int            `json:"digits" toml:"digits" yaml:"digits"`
+	Algorithm  string         `json:"algorithm" toml:"algorithm" yaml:"algorithm"`
+	Period     uint           `json:"period" toml:"period" yaml:"period"`
+	Epoch      int64          `json:"epoch,omitempty" toml:"epoch,omitempty" yaml:"epoch,omitempty"`
+	ImageURL   string         `json:"image_url,omitempty" toml:"image_url,omitempty" yaml:"image_url,omitempty"`
 	Metadata   map[string]any `json:"metadata" toml:"metadata" yaml:"metadata"`
+	CreatedAt  time.Time      `json:"created_at" toml:"created_at" yaml:"created_at"`
+	UpdatedAt  time.Time      `json:"updated_at" toml:"updated_at" yaml:"updated_at"`
 }
 
 // UnmarshalText implements the encoding.TextUnmarshaler interface.
@@ -50,6 +98,46 @@ func (a Account) MarshalText() (text []byte, err error) {
 	return data, nil
 }
 
+// Validate checks that the account has a name and a valid base32 secret, and that its digits, algorithm and period are
+// supported.
+func (a Account) Validate() error {
+	if a.Name == "" {
+		return ErrMissingAccountName
+	}
+
+	if a.TOTPSecret == otp.NoTOTPSecret {
+		return ErrMissingTOTPSecret
+	}
+
+	if err := validateTOTPSecret(a.TOTPSecret); err != nil {
+		return err
+	}
+
+	c := &generateTOTPConfig{}
+
+	return c.withDefaults(a)
+}
+
+// String implements the fmt.Stringer interface. The secrets are masked so that the account can be logged safely.
+func (a Account) String() string {
+	type account Account
+
+	c := account(a)
+	c.TOTPSecret = otp.TOTPSecret(maskSecret(a.TOTPSecret.String()))
+
+	if prev, ok := a.Metadata[PreviousSecretMetadataKey].(string); ok {
+		c.Metadata = make(map[string]any, len(a.Metadata))
+
+		for k, v := range a.Metadata {
+			c.Metadata[k] = v
+		}
+
+		c.Metadata[PreviousSecretMetadataKey] = maskSecret(prev)
+	}
+
+	return fmt.Sprintf("%+v", c)
+}
+
 // GetAccount returns the account.
 func GetAccount(namespace, account string) (Account, error) {
 	configMu.RLock()
@@ -71,32 +159,292 @@ func getAccount(namespace string, account string) (Account, error) {
 	return a, nil
 }
 
-// SetAccount persists the account.
-func SetAccount(namespace string, account Account) error {
+// ListAccountsSorted returns all the accounts in the namespace like ListAccounts, in the given order. The accounts that
+// are equal for the key are sorted by name.
+func ListAccountsSorted(namespace string, by SortKey) ([]Account, error) {
+	var cmp func(a, b Account) int
+
+	switch by {
+	case SortByName:
+		cmp = func(Account, Account) int { return 0 }
+
+	case SortByIssuer:
+		cmp = func(a, b Account) int { return strings.Compare(a.Issuer, b.Issuer) }
+
+	case SortByCreatedAt:
+		cmp = func(a, b Account) int { return b.CreatedAt.Compare(a.CreatedAt) }
+
+	case SortByUpdatedAt:
+		cmp = func(a, b Account) int { return b.UpdatedAt.Compare(a.UpdatedAt) }
+
+	default:
+		return nil, fmt.Errorf("failed to list accounts in namespace %s: %w: %d", namespace, ErrUnsupportedSortKey, by)
+	}
+
+	configMu.RLock()
+	defer configMu.RUnlock()
+
+	accounts, err := listAccounts(namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	// The accounts are sorted by name already.
+	slices.SortStableFunc(accounts, cmp)
+
+	return accounts, nil
+}
+
+// AccountExists tells whether the account is in the storage. The error is only for the storage failures.
+func AccountExists(namespace, account string) (bool, error) {
+	configMu.RLock()
+	defer configMu.RUnlock()
+
+	_, err := getAccount(namespace, account)
+	if err != nil {
+		if errors.Is(err, ErrAccountNotFound) {
+			return false, nil
+		}
+
+		return false, err
+	}
+
+	return true, nil
+}
+
+// ListAccounts returns all the accounts in the namespace, sorted by name. Accounts that are listed in the namespace but
+// missing in the storage are skipped.
+func ListAccounts(namespace string) ([]Account, error) {
+	configMu.RLock()
+	defer configMu.RUnlock()
+
+	return listAccounts(namespace)
+}
+
+func listAccounts(namespace string) ([]Account, error) {
+	n, err := getNamespace(namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	accounts, err := getAccounts(namespace, n.Accounts)
+	if err != nil {
+		return nil, err
+	}
+
+	slices.SortFunc(accounts, func(a, b Account) int {
+		return strings.Compare(a.Name, b.Name)
+	})
+
+	return accounts, nil
+}
+
+// FilterAccounts returns the accounts in the namespace for which the predicate returns true, sorted by name.
+func FilterAccounts(namespace string, pred func(Account) bool) ([]Account, error) {
+	configMu.RLock()
+	defer configMu.RUnlock()
+
+	accounts, err := listAccounts(namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	return slices.DeleteFunc(accounts, func(a Account) bool {
+		return !pred(a)
+	}), nil
+}
+
+// FilterAccountsByMetadata returns the accounts in the namespace that have the metadata key set to the value. The
+// values are compared with reflect.DeepEqual, so the numbers read from a storage that uses json are float64.
+func FilterAccountsByMetadata(namespace, key string, value any) ([]Account, error) {
+	return FilterAccounts(namespace, func(a Account) bool {
+		v, ok := a.Metadata[key]
+
+		return ok && reflect.DeepEqual(v, value)
+	})
+}
+
+// SetAccountOption is an option to configure SetAccount.
+type SetAccountOption interface {
+	applySetAccountOption(cfg *setAccountConfig)
+}
+
+type setAccountOptionFunc func(cfg *setAccountConfig)
+
+func (f setAccountOptionFunc) applySetAccountOption(cfg *setAccountConfig) {
+	f(cfg)
+}
+
+type setAccountConfig struct {
+	validateSecret bool
+	minSecretBits  int
+}
+
+// WithValidateSecret rejects the account with ErrInvalidSecret if its TOTP secret is not a valid base32 string. An empty
+// secret is allowed.
+func WithValidateSecret() SetAccountOption {
+	return setAccountOptionFunc(func(cfg *setAccountConfig) {
+		cfg.validateSecret = true
+	})
+}
+
+// WithRejectWeakSecrets rejects the account with ErrWeakSecret if its TOTP secret has fewer than DefaultMinSecretBits
+// bits, only zeros, or is a well-known test secret such as "NBSWY3DP". An empty secret is allowed.
+func WithRejectWeakSecrets() SetAccountOption {
+	return WithMinSecretBits(DefaultMinSecretBits)
+}
+
+// WithMinSecretBits rejects the weak secrets like WithRejectWeakSecrets, with the given minimum number of bits.
+func WithMinSecretBits(bits int) SetAccountOption {
+	return setAccountOptionFunc(func(cfg *setAccountConfig) {
+		cfg.minSecretBits = bits
+	})
+}
+
+// SetAccount persists the account. The creation time of an existing account is kept.
+func SetAccount(namespace string, account Account, opts ...SetAccountOption) error {
+	var cfg setAccountConfig
+
+	for _, opt := range opts {
+		opt.applySetAccountOption(&cfg)
+	}
+
+	if err := validateName(account.Name); err != nil {
+		return fmt.Errorf("failed to store account in namespace %s: %w", namespace, err)
+	}
+
+	if cfg.validateSecret {
+		if err := validateTOTPSecret(account.TOTPSecret); err != nil {
+			return fmt.Errorf("failed to store account %s in namespace %s: %w", account.Name, namespace, err)
+		}
+	}
+
+	if cfg.minSecretBits > 0 {
+		if err := validateSecretStrength(account.TOTPSecret, cfg.minSecretBits); err != nil {
+			return fmt.Errorf("failed to store account %s in namespace %s: %w", account.Name, namespace, err)
+		}
+	}
+
 	configMu.Lock()
 	defer configMu.Unlock()
 
+	existing, err := getAccount(namespace, account.Name)
+	if err != nil && !errors.Is(err, ErrAccountNotFound) {
+		return err
+	}
+
+	existed := err == nil
+
+	if existed {
+		account.CreatedAt = existing.CreatedAt
+	}
+
 	if err := setAccount(namespace, account); err != nil {
 		return err
 	}
 
+	// Roll back the account if it cannot be listed in the namespace, so that the storage and the namespace do not
+	// diverge.
+	if err := addNamespaceAccount(namespace, account.Name, account.Issuer); err != nil {
+		return multierr.Append(err, restoreAccount(namespace, account.Name, existing, existed))
+	}
+
+	return nil
+}
+
+// GetAccountOrCreate returns the account if it exists. Otherwise, it stores the given account, creating the namespace
+// if needed, and returns the stored account. The boolean tells whether the account was created.
+func GetAccountOrCreate(namespace string, account Account) (Account, bool, error) {
+	if err := validateName(account.Name); err != nil {
+		return Account{}, false, fmt.Errorf("failed to store account in namespace %s: %w", namespace, err)
+	}
+
+	configMu.Lock()
+	defer configMu.Unlock()
+
+	existing, err := getAccount(namespace, account.Name)
+	if err == nil {
+		return existing, false, nil
+	}
+
+	if !errors.Is(err, ErrAccountNotFound) {
+		return Account{}, false, err
+	}
+
+	if err := createNamespace(newConfigOptions(), namespace, namespace); err != nil && !errors.Is(err, ErrNamespaceExists) {
+		return Account{}, false, err
+	}
+
+	if err := setAccount(namespace, account); err != nil {
+		return Account{}, false, err
+	}
+
+	if err := addNamespaceAccount(namespace, account.Name, account.Issuer); err != nil {
+		return Account{}, false, multierr.Append(err, restoreAccount(namespace, account.Name, Account{}, false))
+	}
+
+	created, err := getAccount(namespace, account.Name)
+	if err != nil {
+		return Account{}, false, err
+	}
+
+	return created, true, nil
+}
+
+// addNamespaceAccount adds the account to the namespace if it is not there yet, and records its issuer in the index. The
+// caller must hold configMu, which makes the read-modify-write of the account list atomic within the process.
+func addNamespaceAccount(namespace, account, issuer string) error {
 	n, err := getNamespace(namespace)
 	if err != nil {
-		return fmt.Errorf("failed to get namespace %s for creating account %s: %w", namespace, account.Name, errors.Unwrap(err))
+		return fmt.Errorf("failed to get namespace %s for creating account %s: %w", namespace, account, errors.Unwrap(err))
 	}
 
-	if slices.Contains(n.Accounts, account.Name) {
-		return nil
+	indexed := n.indexAccount(account, issuer)
+
+	if slices.Contains(n.Accounts, account) {
+		if !indexed {
+			return nil
+		}
+
+		return updateNamespace(namespace, n)
 	}
 
-	n.Accounts = append(n.Accounts, account.Name)
+	n.Accounts = append(n.Accounts, account)
 
 	slices.Sort(n.Accounts)
 
 	return updateNamespace(namespace, n)
 }
 
+// restoreAccount puts back the previous version of the account, or deletes it if it did not exist.
+func restoreAccount(namespace, account string, prev Account, existed bool) error {
+	if !existed {
+		if err := deleteAccount(namespace, account); err != nil && !errors.Is(err, secretstorage.ErrNotFound) {
+			return fmt.Errorf("failed to roll back account %s in namespace %s: %w", account, namespace, errors.Unwrap(err))
+		}
+
+		return nil
+	}
+
+	if err := accountStorage.Set(serviceName, formatAccount(namespace, account), prev); err != nil {
+		return fmt.Errorf("failed to roll back account %s in namespace %s: %w", account, namespace, err)
+	}
+
+	return nil
+}
+
+// setAccount stores the account. UpdatedAt is always set to now, CreatedAt only when it is not set yet.
 func setAccount(namespace string, account Account) error {
+	now := accountClock.Now()
+
+	if account.CreatedAt.IsZero() {
+		account.CreatedAt = now
+	}
+
+	account.UpdatedAt = now
+
+	codeCache.invalidate(namespace, account.Name)
+
 	if err := accountStorage.Set(serviceName, formatAccount(namespace, account.Name), account); err != nil {
 		return fmt.Errorf("failed to store account %s in namespace %s: %w", account.Name, namespace, err)
 	}
@@ -104,6 +452,42 @@ func setAccount(namespace string, account Account) error {
 	return nil
 }
 
+// CopyAccount copies the account src to dst in the same namespace, and adds dst to the namespace. The source account is
+// kept. It returns ErrAccountExists if dst already exists.
+func CopyAccount(namespace, src, dst string) error {
+	if err := validateName(dst); err != nil {
+		return fmt.Errorf("failed to copy account %s in namespace %s: %w", src, namespace, err)
+	}
+
+	configMu.Lock()
+	defer configMu.Unlock()
+
+	account, err := getAccount(namespace, src)
+	if err != nil {
+		return err
+	}
+
+	_, err = getAccount(namespace, dst)
+	if err == nil {
+		return fmt.Errorf("failed to copy account %s to %s in namespace %s: %w", src, dst, namespace, ErrAccountExists)
+	} else if !errors.Is(err, ErrAccountNotFound) {
+		return err
+	}
+
+	account.Name = dst
+	account.CreatedAt = time.Time{}
+
+	if err := setAccount(namespace, account); err != nil {
+		return err
+	}
+
+	if err := addNamespaceAccount(namespace, dst, account.Issuer); err != nil {
+		return multierr.Append(err, restoreAccount(namespace, dst, Account{}, false))
+	}
+
+	return nil
+}
+
 // DeleteAccount deletes the account and removes it from the namespace.
 func DeleteAccount(namespace string, account string) error {
 	configMu.Lock()
@@ -119,6 +503,8 @@ func DeleteAccount(namespace string, account string) error {
 			return s == account
 		})
 
+		n.unindexAccount(account)
+
 		if err := updateNamespace(namespace, n); err != nil {
 			return fmt.Errorf("failed to remove account %s from namespace %s: %w", account, namespace, errors.Unwrap(err))
 		}
@@ -131,7 +517,47 @@ func DeleteAccount(namespace string, account string) error {
 	return nil
 }
 
+// DeleteAccounts deletes the accounts and removes them from the namespace with a single update of the namespace. The
+// failures to delete the accounts are aggregated.
+func DeleteAccounts(namespace string, accounts []string) error {
+	configMu.Lock()
+	defer configMu.Unlock()
+
+	n, err := getNamespace(namespace)
+	if err != nil && !errors.Is(err, ErrNamespaceNotFound) {
+		return fmt.Errorf("failed to get namespace %s for deleting accounts: %w", namespace, errors.Unwrap(err))
+	}
+
+	remaining := slices.DeleteFunc(slices.Clone(n.Accounts), func(s string) bool {
+		return slices.Contains(accounts, s)
+	})
+
+	if len(remaining) != len(n.Accounts) {
+		n.Accounts = remaining
+
+		for _, account := range accounts {
+			n.unindexAccount(account)
+		}
+
+		if err := updateNamespace(namespace, n); err != nil {
+			return fmt.Errorf("failed to remove accounts from namespace %s: %w", namespace, errors.Unwrap(err))
+		}
+	}
+
+	var errs error
+
+	for _, account := range accounts {
+		if err := deleteAccount(namespace, account); err != nil && !errors.Is(err, secretstorage.ErrNotFound) {
+			errs = multierr.Append(errs, err)
+		}
+	}
+
+	return errs
+}
+
 func deleteAccount(namespace string, account string) error {
+	codeCache.invalidate(namespace, account)
+
 	if err := accountStorage.Delete(serviceName, formatAccount(namespace, account)); err != nil {
 		return fmt.Errorf("failed to delete account %s in namespace %s: %w", account, namespace, err)
 	}
@@ -139,6 +565,22 @@ func deleteAccount(namespace string, account string) error {
 	return nil
 }
 
+// SetAccountClock sets the clock used for the account timestamps.
+func SetAccountClock(c clock.Clock) func() {
+	configMu.Lock()
+	defer configMu.Unlock()
+
+	prev := accountClock
+	accountClock = c
+
+	return func() {
+		configMu.Lock()
+		defer configMu.Unlock()
+
+		accountClock = prev
+	}
+}
+
 // SetAccountStorage sets the account storage.
 func SetAccountStorage(s secretstorage.Storage[Account]) func() {
 	configMu.Lock()
@@ -148,10 +590,39 @@ func SetAccountStorage(s secretstorage.Storage[Account]) func() {
 	accountStorage = s
 
 	return func() {
+		configMu.Lock()
+		defer configMu.Unlock()
+
 		accountStorage = ns
 	}
 }
 
+// accountKeyEscaper escapes the separator in the namespace and the account name so that the key is unambiguous. The
+// names without "/" and "%" are not changed. The new names cannot contain "/", but the accounts stored before
+// validateName may, see MigrateAccountKeys.
+var (
+	accountKeyEscaper   = strings.NewReplacer("%", "%25", "/", "%2F")
+	accountKeyUnescaper = strings.NewReplacer("%2F", "/", "%25", "%")
+)
+
+// formatAccount returns the storage key of the account, e.g. namespace/account.
 func formatAccount(namespace, account string) string {
-	return fmt.Sprintf("%s/%s", namespace, account)
+	return fmt.Sprintf("%s/%s", accountKeyEscaper.Replace(namespace), accountKeyEscaper.Replace(account))
+}
+
+// parseAccountKey is the reverse of formatAccount. The keys that formatAccount does not produce, such as the legacy keys
+// with a "/" in the namespace or the account name, are not parsed.
+func parseAccountKey(key string) (namespace, account string, ok bool) {
+	namespace, account, ok = strings.Cut(key, "/")
+	if !ok {
+		return "", "", false
+	}
+
+	namespace, account = accountKeyUnescaper.Replace(namespace), accountKeyUnescaper.Replace(account)
+
+	if formatAccount(namespace, account) != key {
+		return "", "", false
+	}
+
+	return namespace, account, true
 }
@@ -1,18 +1,26 @@
 package authenticator_test
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"github.com/stretchr/testify/require"
+	"go.nhat.io/clock"
+	"go.nhat.io/otp"
 	"go.nhat.io/secretstorage"
 	mockss "go.nhat.io/secretstorage/mock"
 
 	"go.nhat.io/authenticator"
 )
 
+var accountTime = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
 func TestAccount_MarshalText(t *testing.T) {
 	t.Parallel()
 
@@ -20,7 +28,14 @@ func TestAccount_MarshalText(t *testing.T) {
 		Name:       "john.doe@example.com",
 		TOTPSecret: "NBSWY3DP",
 		Issuer:     "example.com",
+		Type:       authenticator.TypeHOTP,
+		Counter:    42,
+		Digits:     8,
+		Algorithm:  authenticator.AlgorithmSHA512,
+		Period:     60,
 		Metadata:   map[string]any{"message": "foobar"},
+		CreatedAt:  accountTime,
+		UpdatedAt:  accountTime.Add(time.Hour),
 	}
 
 	data, err := json.Marshal(expected)
@@ -34,6 +49,24 @@ func TestAccount_MarshalText(t *testing.T) {
 	assert.Equal(t, expected, actual)
 }
 
+func TestAccount_UnmarshalText_WithoutTimestamps(t *testing.T) {
+	t.Parallel()
+
+	var actual authenticator.Account
+
+	err := json.Unmarshal([]byte(`"{\"name\": \"john.doe@example.com\", \"totp_secret\": \"NBSWY3DP\"}"`), &actual)
+	require.NoError(t, err)
+
+	expected := authenticator.Account{
+		Name:       "john.doe@example.com",
+		TOTPSecret: "NBSWY3DP",
+	}
+
+	assert.Equal(t, expected, actual)
+	assert.True(t, actual.CreatedAt.IsZero())
+	assert.True(t, actual.UpdatedAt.IsZero())
+}
+
 func TestAccount_UnmarshalText_Error(t *testing.T) {
 	t.Parallel()
 
@@ -60,6 +93,43 @@ func TestAccount_MarshalText_Error(t *testing.T) {
 	assert.Empty(t, actual)
 }
 
+func TestAccount_String(t *testing.T) {
+	t.Parallel()
+
+	account := authenticator.Account{
+		Name:       "john.doe@example.com",
+		TOTPSecret: "NBSWY3DPEHPK3PXP",
+		Issuer:     "example.com",
+		Metadata:   map[string]any{authenticator.PreviousSecretMetadataKey: "JBSWY3DPEHPK3PXP"},
+	}
+
+	for _, format := range []string{"%v", "%+v", "%s"} {
+		actual := fmt.Sprintf(format, account)
+
+		assert.Contains(t, actual, "john.doe@example.com")
+		assert.Contains(t, actual, "NB****")
+		assert.NotContains(t, actual, "NBSWY3DPEHPK3PXP")
+		assert.NotContains(t, actual, "JBSWY3DPEHPK3PXP")
+	}
+
+	// The secrets are not masked when the account is stored.
+	data, err := account.MarshalText()
+	require.NoError(t, err)
+
+	assert.Contains(t, string(data), "NBSWY3DPEHPK3PXP")
+	assert.Contains(t, string(data), "JBSWY3DPEHPK3PXP")
+	assert.Equal(t, "JBSWY3DPEHPK3PXP", account.Metadata[authenticator.PreviousSecretMetadataKey])
+}
+
+func TestAccount_String_ShortSecret(t *testing.T) {
+	t.Parallel()
+
+	actual := authenticator.Account{Name: "john.doe@example.com", TOTPSecret: "NBSW"}.String()
+
+	assert.Contains(t, actual, "TOTPSecret:****")
+	assert.NotContains(t, actual, "NBSW")
+}
+
 func TestGetAccount_Success(t *testing.T) {
 	setConfigFile(t)
 
@@ -112,6 +182,187 @@ func TestGetAccount_StorageError(t *testing.T) {
 	assert.Empty(t, authenticator.Account{}, actual)
 }
 
+func TestListAccounts_Success(t *testing.T) {
+	setNamespaceStorage(t, func(s *mockss.Storage[authenticator.Namespace]) {
+		s.On("Get", "go.nhat.io/authenticator", t.Name()).
+			Return(authenticator.Namespace{
+				Name:     t.Name(),
+				Accounts: []string{"john.doe@example.com", "missing@example.com", "jane.doe@example.com"},
+			}, nil)
+	})
+
+	setAccountStorage(t, func(s *mockss.Storage[authenticator.Account]) {
+		s.On("Get", "go.nhat.io/authenticator", "TestListAccounts_Success/john.doe@example.com").
+			Return(authenticator.Account{Name: "john.doe@example.com", TOTPSecret: "NBSWY3DP"}, nil)
+
+		s.On("Get", "go.nhat.io/authenticator", "TestListAccounts_Success/missing@example.com").
+			Return(authenticator.Account{}, secretstorage.ErrNotFound)
+
+		s.On("Get", "go.nhat.io/authenticator", "TestListAccounts_Success/jane.doe@example.com").
+			Return(authenticator.Account{Name: "jane.doe@example.com", TOTPSecret: "JBSWY3DP"}, nil)
+	})
+
+	actual, err := authenticator.ListAccounts(t.Name())
+	require.NoError(t, err)
+
+	expected := []authenticator.Account{
+		{Name: "jane.doe@example.com", TOTPSecret: "JBSWY3DP"},
+		{Name: "john.doe@example.com", TOTPSecret: "NBSWY3DP"},
+	}
+
+	assert.Equal(t, expected, actual)
+}
+
+func TestFilterAccountsByMetadata(t *testing.T) {
+	setNamespaceStorage(t, func(s *mockss.Storage[authenticator.Namespace]) {
+		s.On("Get", "go.nhat.io/authenticator", t.Name()).
+			Return(authenticator.Namespace{
+				Name:     t.Name(),
+				Accounts: []string{"john.doe@example.com", "jane.doe@example.com", "jack.doe@example.com", "jill.doe@example.com"},
+			}, nil)
+	})
+
+	setAccountStorage(t, func(s *mockss.Storage[authenticator.Account]) {
+		s.On("Get", "go.nhat.io/authenticator", "TestFilterAccountsByMetadata/john.doe@example.com").
+			Return(authenticator.Account{Name: "john.doe@example.com", Metadata: map[string]any{"env": "prod"}}, nil)
+
+		s.On("Get", "go.nhat.io/authenticator", "TestFilterAccountsByMetadata/jane.doe@example.com").
+			Return(authenticator.Account{Name: "jane.doe@example.com", Metadata: map[string]any{"env": "dev"}}, nil)
+
+		s.On("Get", "go.nhat.io/authenticator", "TestFilterAccountsByMetadata/jack.doe@example.com").
+			Return(authenticator.Account{Name: "jack.doe@example.com"}, nil)
+
+		s.On("Get", "go.nhat.io/authenticator", "TestFilterAccountsByMetadata/jill.doe@example.com").
+			Return(authenticator.Account{Name: "jill.doe@example.com", Metadata: map[string]any{"env": "prod", "team": "ops"}}, nil)
+	})
+
+	actual, err := authenticator.FilterAccountsByMetadata(t.Name(), "env", "prod")
+	require.NoError(t, err)
+
+	expected := []authenticator.Account{
+		{Name: "jill.doe@example.com", Metadata: map[string]any{"env": "prod", "team": "ops"}},
+		{Name: "john.doe@example.com", Metadata: map[string]any{"env": "prod"}},
+	}
+
+	assert.Equal(t, expected, actual)
+}
+
+func TestFilterAccounts_NamespaceNotFound(t *testing.T) {
+	setNamespaceStorage(t, func(s *mockss.Storage[authenticator.Namespace]) {
+		s.On("Get", "go.nhat.io/authenticator", t.Name()).
+			Return(authenticator.Namespace{}, secretstorage.ErrNotFound)
+	})
+
+	actual, err := authenticator.FilterAccounts(t.Name(), func(authenticator.Account) bool { return true })
+	require.ErrorIs(t, err, authenticator.ErrNamespaceNotFound)
+	assert.Nil(t, actual)
+}
+
+func TestListAccountsSorted(t *testing.T) {
+	day := func(d int) time.Time {
+		return time.Date(2024, time.January, d, 0, 0, 0, 0, time.UTC)
+	}
+
+	accounts := map[string]authenticator.Account{
+		"alice": {Name: "alice", Issuer: "github.com", CreatedAt: day(2), UpdatedAt: day(2)},
+		"bob":   {Name: "bob", Issuer: "example.com", CreatedAt: day(3), UpdatedAt: day(3)},
+		"carol": {Name: "carol", Issuer: "github.com", CreatedAt: day(1), UpdatedAt: day(4)},
+		"dave":  {Name: "dave", Issuer: "example.com", CreatedAt: day(1), UpdatedAt: day(1)},
+	}
+
+	testCases := []struct {
+		scenario string
+		by       authenticator.SortKey
+		expected []string
+	}{
+		{
+			scenario: "name",
+			by:       authenticator.SortByName,
+			expected: []string{"alice", "bob", "carol", "dave"},
+		},
+		{
+			scenario: "issuer",
+			by:       authenticator.SortByIssuer,
+			expected: []string{"bob", "dave", "alice", "carol"},
+		},
+		{
+			scenario: "created at",
+			by:       authenticator.SortByCreatedAt,
+			expected: []string{"bob", "alice", "carol", "dave"},
+		},
+		{
+			scenario: "updated at",
+			by:       authenticator.SortByUpdatedAt,
+			expected: []string{"carol", "bob", "alice", "dave"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.scenario, func(t *testing.T) {
+			setNamespaceStorage(t, func(s *mockss.Storage[authenticator.Namespace]) {
+				s.On("Get", "go.nhat.io/authenticator", "namespace").
+					Return(authenticator.Namespace{Name: "Namespace", Accounts: []string{"dave", "carol", "bob", "alice"}}, nil)
+			})
+
+			setAccountStorage(t, func(s *mockss.Storage[authenticator.Account]) {
+				for name, a := range accounts {
+					s.On("Get", "go.nhat.io/authenticator", "namespace/"+name).
+						Return(a, nil)
+				}
+			})
+
+			actual, err := authenticator.ListAccountsSorted("namespace", tc.by)
+			require.NoError(t, err)
+
+			names := make([]string, 0, len(actual))
+
+			for _, a := range actual {
+				names = append(names, a.Name)
+			}
+
+			assert.Equal(t, tc.expected, names)
+		})
+	}
+}
+
+func TestListAccountsSorted_UnsupportedSortKey(t *testing.T) {
+	t.Parallel()
+
+	actual, err := authenticator.ListAccountsSorted("namespace", authenticator.SortKey(42))
+	require.ErrorIs(t, err, authenticator.ErrUnsupportedSortKey)
+	assert.Nil(t, actual)
+}
+
+func TestListAccounts_NamespaceNotFound(t *testing.T) {
+	setNamespaceStorage(t, func(s *mockss.Storage[authenticator.Namespace]) {
+		s.On("Get", "go.nhat.io/authenticator", t.Name()).
+			Return(authenticator.Namespace{}, secretstorage.ErrNotFound)
+	})
+
+	actual, err := authenticator.ListAccounts(t.Name())
+	require.ErrorIs(t, err, authenticator.ErrNamespaceNotFound)
+	assert.Empty(t, actual)
+}
+
+func TestListAccounts_StorageError(t *testing.T) {
+	setNamespaceStorage(t, func(s *mockss.Storage[authenticator.Namespace]) {
+		s.On("Get", "go.nhat.io/authenticator", t.Name()).
+			Return(authenticator.Namespace{
+				Name:     t.Name(),
+				Accounts: []string{"jane.doe@example.com", "john.doe@example.com"},
+			}, nil)
+	})
+
+	setAccountStorage(t, func(s *mockss.Storage[authenticator.Account]) {
+		s.On("Get", "go.nhat.io/authenticator", "TestListAccounts_StorageError/jane.doe@example.com").
+			Return(authenticator.Account{}, assert.AnError)
+	})
+
+	actual, err := authenticator.ListAccounts(t.Name())
+	require.EqualError(t, err, `failed to get account jane.doe@example.com in namespace TestListAccounts_StorageError: assert.AnError general error for testing`)
+	assert.Empty(t, actual)
+}
+
 func TestSetAccount_Success(t *testing.T) {
 	setConfigFile(t)
 
@@ -175,6 +426,9 @@ func TestSetAccount_AccountAddedMoreThanOnce(t *testing.T) {
 
 func TestSetAccount_FailedToSet(t *testing.T) {
 	setAccountStorage(t, func(s *mockss.Storage[authenticator.Account]) {
+		s.On("Get", "go.nhat.io/authenticator", "TestSetAccount_FailedToSet/john.doe@example.com").
+			Return(authenticator.Account{}, secretstorage.ErrNotFound)
+
 		s.On("Set", "go.nhat.io/authenticator", "TestSetAccount_FailedToSet/john.doe@example.com", mock.Anything).
 			Return(assert.AnError)
 	})
@@ -221,6 +475,333 @@ func TestSetAccount_FailedToUpdateNamespace(t *testing.T) {
 	require.EqualError(t, err, `failed to update namespace TestSetAccount_FailedToUpdateNamespace: assert.AnError general error for testing`)
 }
 
+func TestSetServiceName(t *testing.T) {
+	setConfigFile(t)
+	setAccountClock(t)
+
+	t.Cleanup(authenticator.SetServiceName("example.com/myapp"))
+
+	setNamespaceStorage(t, func(s *mockss.Storage[authenticator.Namespace]) {
+		s.On("Get", "example.com/myapp", "namespace").
+			Return(authenticator.Namespace{Name: "Namespace"}, nil)
+
+		s.On("Set", "example.com/myapp", "namespace", authenticator.Namespace{
+			Name:     "Namespace",
+			Accounts: []string{"john.doe@example.com"},
+			Issuers:  map[string][]string{"": {"john.doe@example.com"}},
+		}).
+			Return(nil)
+	})
+
+	setAccountStorage(t, func(s *mockss.Storage[authenticator.Account]) {
+		s.On("Get", "example.com/myapp", "namespace/john.doe@example.com").
+			Return(authenticator.Account{}, secretstorage.ErrNotFound)
+
+		s.On("Set", "example.com/myapp", "namespace/john.doe@example.com", mockss.Anything).
+			Return(nil)
+	})
+
+	err := authenticator.SetAccount("namespace", authenticator.Account{Name: "john.doe@example.com", TOTPSecret: "NBSWY3DP"})
+	require.NoError(t, err)
+}
+
+func TestAccountExists(t *testing.T) {
+	testCases := []struct {
+		scenario       string
+		mockStorage    func(s *mockss.Storage[authenticator.Account])
+		expectedResult bool
+		expectedError  string
+	}{
+		{
+			scenario: "exists",
+			mockStorage: func(s *mockss.Storage[authenticator.Account]) {
+				s.On("Get", "go.nhat.io/authenticator", "namespace/john.doe@example.com").
+					Return(authenticator.Account{Name: "john.doe@example.com"}, nil)
+			},
+			expectedResult: true,
+		},
+		{
+			scenario: "not found",
+			mockStorage: func(s *mockss.Storage[authenticator.Account]) {
+				s.On("Get", "go.nhat.io/authenticator", "namespace/john.doe@example.com").
+					Return(authenticator.Account{}, secretstorage.ErrNotFound)
+			},
+		},
+		{
+			scenario: "error",
+			mockStorage: func(s *mockss.Storage[authenticator.Account]) {
+				s.On("Get", "go.nhat.io/authenticator", "namespace/john.doe@example.com").
+					Return(authenticator.Account{}, assert.AnError)
+			},
+			expectedError: `failed to get account john.doe@example.com in namespace namespace: assert.AnError general error for testing`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.scenario, func(t *testing.T) {
+			setAccountStorage(t, tc.mockStorage)
+
+			actual, err := authenticator.AccountExists("namespace", "john.doe@example.com")
+
+			if tc.expectedError != "" {
+				require.EqualError(t, err, tc.expectedError)
+			} else {
+				require.NoError(t, err)
+			}
+
+			assert.Equal(t, tc.expectedResult, actual)
+		})
+	}
+}
+
+func TestSetAccount_InvalidName(t *testing.T) {
+	t.Parallel()
+
+	for _, name := range []string{"", ".", "..", "../evil", "evil/..", `..\evil`, "github/john.doe", `github\john.doe`, "john\ndoe", "john\x00doe"} {
+		t.Run(name, func(t *testing.T) {
+			t.Parallel()
+
+			err := authenticator.SetAccount("namespace", authenticator.Account{Name: name, TOTPSecret: "NBSWY3DP"})
+			require.ErrorIs(t, err, authenticator.ErrInvalidName)
+		})
+	}
+}
+
+func TestSetAccount_ValidName(t *testing.T) {
+	setConfigFile(t)
+
+	useInMemoryStorage(t)
+
+	err := authenticator.CreateNamespace("namespace", "Namespace")
+	require.NoError(t, err)
+
+	for _, name := range []string{"john.doe@example.com", "john..doe@example.com", "50%off"} {
+		err := authenticator.SetAccount("namespace", authenticator.Account{Name: name, TOTPSecret: "NBSWY3DP"})
+		require.NoError(t, err)
+	}
+}
+
+func TestAccount_LegacyKey(t *testing.T) {
+	setConfigFile(t)
+	setAccountClock(t)
+
+	t.Setenv("AUTHENTICATOR_TOTP_SECRET", "")
+
+	accounts, namespaces := useInMemoryStorage(t)
+
+	// The accounts stored before the keys were escaped.
+	err := namespaces.Set("go.nhat.io/authenticator", "namespace", authenticator.Namespace{
+		Name:     "Namespace",
+		Accounts: []string{"50%off", "john.doe@example.com", "work/john"},
+	})
+	require.NoError(t, err)
+
+	for _, name := range []string{"50%off", "john.doe@example.com", "work/john"} {
+		err = accounts.Set("go.nhat.io/authenticator", "namespace/"+name, authenticator.Account{Name: name, TOTPSecret: "NBSWY3DP"})
+		require.NoError(t, err)
+	}
+
+	// The legacy key of another namespace, it must not be taken for the account work/john of namespace.
+	err = accounts.Set("go.nhat.io/authenticator", "namespace/work/jane", authenticator.Account{Name: "jane", TOTPSecret: "NBSWY3DP"})
+	require.NoError(t, err)
+
+	// The legacy keys are not read.
+	_, err = authenticator.GetAccount("namespace", "50%off")
+	require.ErrorIs(t, err, authenticator.ErrAccountNotFound)
+
+	report, err := authenticator.RepairNamespace("namespace")
+	require.NoError(t, err)
+	assert.Equal(t, authenticator.RepairReport{Legacy: []string{"50%off", "work/john"}}, report)
+
+	migrated, err := authenticator.MigrateAccountKeys("namespace")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"50%off", "work/john"}, migrated)
+
+	actual, err := authenticator.GetAccount("namespace", "50%off")
+	require.NoError(t, err)
+	assert.Equal(t, otp.TOTPSecret("NBSWY3DP"), actual.TOTPSecret)
+
+	code, err := authenticator.GenerateTOTP(context.Background(), "namespace", "work/john", authenticator.WithClock(clock.Fix(accountTime)))
+	require.NoError(t, err)
+	assert.Equal(t, otp.OTP("191882"), code)
+
+	for _, key := range []string{"namespace/50%off", "namespace/work/john"} {
+		_, err = accounts.Get("go.nhat.io/authenticator", key)
+		require.ErrorIs(t, err, secretstorage.ErrNotFound)
+	}
+
+	_, err = accounts.Get("go.nhat.io/authenticator", "namespace/work/jane")
+	require.NoError(t, err)
+
+	report, err = authenticator.RepairNamespace("namespace")
+	require.NoError(t, err)
+	assert.True(t, report.Consistent())
+
+	// The migration is done once.
+	migrated, err = authenticator.MigrateAccountKeys("namespace")
+	require.NoError(t, err)
+	assert.Empty(t, migrated)
+}
+
+func TestRepairNamespace_LegacyKey(t *testing.T) {
+	setConfigFile(t)
+	setAccountClock(t)
+
+	accounts, namespaces := useInMemoryStorage(t)
+
+	err := namespaces.Set("go.nhat.io/authenticator", "namespace", authenticator.Namespace{
+		Name:     "Namespace",
+		Accounts: []string{"work/john"},
+	})
+	require.NoError(t, err)
+
+	err = accounts.Set("go.nhat.io/authenticator", "namespace/work/john", authenticator.Account{Name: "work/john", TOTPSecret: "NBSWY3DP"})
+	require.NoError(t, err)
+
+	// The account a/b of namespace team is not taken for the account b of namespace team/a.
+	err = accounts.Set("go.nhat.io/authenticator", "team/a/b", authenticator.Account{Name: "b", TOTPSecret: "NBSWY3DP"})
+	require.NoError(t, err)
+
+	err = namespaces.Set("go.nhat.io/authenticator", "team", authenticator.Namespace{Name: "Team"})
+	require.NoError(t, err)
+
+	report, err := authenticator.RepairNamespace("team")
+	require.NoError(t, err)
+	assert.True(t, report.Consistent())
+
+	report, err = authenticator.RepairNamespace("namespace", authenticator.WithFix())
+	require.NoError(t, err)
+	assert.Equal(t, authenticator.RepairReport{Legacy: []string{"work/john"}, Repaired: true}, report)
+
+	actual, err := authenticator.GetAccount("namespace", "work/john")
+	require.NoError(t, err)
+	assert.Equal(t, otp.TOTPSecret("NBSWY3DP"), actual.TOTPSecret)
+
+	n, err := authenticator.GetNamespace("namespace")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"work/john"}, n.Accounts)
+}
+
+func TestGetAccountOrCreate_NotExisting(t *testing.T) {
+	setConfigFile(t)
+	setAccountClock(t)
+
+	useInMemoryStorage(t)
+
+	actual, created, err := authenticator.GetAccountOrCreate("namespace", authenticator.Account{
+		Name:       "john.doe@example.com",
+		TOTPSecret: "NBSWY3DP",
+	})
+	require.NoError(t, err)
+
+	expected := authenticator.Account{
+		Name:       "john.doe@example.com",
+		TOTPSecret: "NBSWY3DP",
+		CreatedAt:  accountTime,
+		UpdatedAt:  accountTime,
+	}
+
+	assert.True(t, created)
+	assert.Equal(t, expected, actual)
+
+	n, err := authenticator.GetNamespace("namespace")
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"john.doe@example.com"}, n.Accounts)
+}
+
+func TestGetAccountOrCreate_Existing(t *testing.T) {
+	setConfigFile(t)
+	setAccountClock(t)
+
+	useInMemoryStorage(t)
+
+	err := authenticator.CreateNamespace("namespace", "Namespace")
+	require.NoError(t, err)
+
+	err = authenticator.SetAccount("namespace", authenticator.Account{Name: "john.doe@example.com", TOTPSecret: "NBSWY3DP"})
+	require.NoError(t, err)
+
+	actual, created, err := authenticator.GetAccountOrCreate("namespace", authenticator.Account{
+		Name:       "john.doe@example.com",
+		TOTPSecret: "JBSWY3DP",
+	})
+	require.NoError(t, err)
+
+	assert.False(t, created)
+	assert.Equal(t, otp.TOTPSecret("NBSWY3DP"), actual.TOTPSecret)
+
+	n, err := authenticator.GetNamespace("namespace")
+	require.NoError(t, err)
+
+	assert.Equal(t, "Namespace", n.Name)
+	assert.Equal(t, []string{"john.doe@example.com"}, n.Accounts)
+}
+
+func TestCopyAccount_Success(t *testing.T) {
+	setAccountClock(t)
+
+	setAccountStorage(t, func(s *mockss.Storage[authenticator.Account]) {
+		s.On("Get", "go.nhat.io/authenticator", "namespace/prod").
+			Return(authenticator.Account{
+				Name:       "prod",
+				TOTPSecret: "NBSWY3DP",
+				Issuer:     "example.com",
+				CreatedAt:  time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC),
+				UpdatedAt:  time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC),
+			}, nil)
+
+		s.On("Get", "go.nhat.io/authenticator", "namespace/prod-backup").
+			Return(authenticator.Account{}, secretstorage.ErrNotFound)
+
+		s.On("Set", "go.nhat.io/authenticator", "namespace/prod-backup", authenticator.Account{
+			Name:       "prod-backup",
+			TOTPSecret: "NBSWY3DP",
+			Issuer:     "example.com",
+			CreatedAt:  accountTime,
+			UpdatedAt:  accountTime,
+		}).
+			Return(nil)
+	})
+
+	setNamespaceStorage(t, func(s *mockss.Storage[authenticator.Namespace]) {
+		s.On("Get", "go.nhat.io/authenticator", "namespace").
+			Return(authenticator.Namespace{Name: "namespace", Accounts: []string{"prod"}}, nil)
+
+		s.On("Set", "go.nhat.io/authenticator", "namespace",
+			authenticator.Namespace{Name: "namespace", Accounts: []string{"prod", "prod-backup"}}).
+			Return(nil)
+	})
+
+	err := authenticator.CopyAccount("namespace", "prod", "prod-backup")
+	require.NoError(t, err)
+}
+
+func TestCopyAccount_DestinationExists(t *testing.T) {
+	setAccountStorage(t, func(s *mockss.Storage[authenticator.Account]) {
+		s.On("Get", "go.nhat.io/authenticator", "namespace/prod").
+			Return(authenticator.Account{Name: "prod", TOTPSecret: "NBSWY3DP"}, nil)
+
+		s.On("Get", "go.nhat.io/authenticator", "namespace/prod-backup").
+			Return(authenticator.Account{Name: "prod-backup", TOTPSecret: "JBSWY3DP"}, nil)
+	})
+
+	err := authenticator.CopyAccount("namespace", "prod", "prod-backup")
+	require.ErrorIs(t, err, authenticator.ErrAccountExists)
+	require.EqualError(t, err, `failed to copy account prod to prod-backup in namespace namespace: account already exists`)
+}
+
+func TestCopyAccount_SourceNotFound(t *testing.T) {
+	setAccountStorage(t, func(s *mockss.Storage[authenticator.Account]) {
+		s.On("Get", "go.nhat.io/authenticator", "namespace/prod").
+			Return(authenticator.Account{}, secretstorage.ErrNotFound)
+	})
+
+	err := authenticator.CopyAccount("namespace", "prod", "prod-backup")
+	require.ErrorIs(t, err, authenticator.ErrAccountNotFound)
+	require.EqualError(t, err, `failed to get account prod in namespace namespace: account not found`)
+}
+
 func TestDeleteAccount_FailedToGetNamespace(t *testing.T) {
 	setNamespaceStorage(t, func(s *mockss.Storage[authenticator.Namespace]) {
 		s.On("Get", "go.nhat.io/authenticator", t.Name()).Once().
@@ -372,6 +953,331 @@ func TestDeleteAccount_Success(t *testing.T) {
 	require.NoError(t, err)
 }
 
+func TestDeleteAccounts_Success(t *testing.T) {
+	setNamespaceStorage(t, func(s *mockss.Storage[authenticator.Namespace]) {
+		s.On("Get", "go.nhat.io/authenticator", t.Name()).Once().
+			Return(authenticator.Namespace{
+				Name:     t.Name(),
+				Accounts: []string{"a@example.com", "b@example.com", "c@example.com", "d@example.com", "e@example.com"},
+			}, nil)
+
+		s.On("Set", "go.nhat.io/authenticator", t.Name(), authenticator.Namespace{
+			Name:     t.Name(),
+			Accounts: []string{"b@example.com", "d@example.com"},
+		}).Once().
+			Return(nil)
+	})
+
+	setAccountStorage(t, func(s *mockss.Storage[authenticator.Account]) {
+		for _, account := range []string{"a@example.com", "c@example.com", "e@example.com"} {
+			s.On("Delete", "go.nhat.io/authenticator", "TestDeleteAccounts_Success/"+account).Once().
+				Return(nil)
+		}
+	})
+
+	err := authenticator.DeleteAccounts(t.Name(), []string{"a@example.com", "c@example.com", "e@example.com"})
+	require.NoError(t, err)
+}
+
+func TestDeleteAccounts_FailedToDelete(t *testing.T) {
+	setNamespaceStorage(t, func(s *mockss.Storage[authenticator.Namespace]) {
+		s.On("Get", "go.nhat.io/authenticator", t.Name()).Once().
+			Return(authenticator.Namespace{
+				Name:     t.Name(),
+				Accounts: []string{"a@example.com", "b@example.com", "c@example.com"},
+			}, nil)
+
+		s.On("Set", "go.nhat.io/authenticator", t.Name(), mock.Anything).Once().
+			Return(nil)
+	})
+
+	setAccountStorage(t, func(s *mockss.Storage[authenticator.Account]) {
+		s.On("Delete", "go.nhat.io/authenticator", "TestDeleteAccounts_FailedToDelete/a@example.com").Once().
+			Return(assert.AnError)
+
+		s.On("Delete", "go.nhat.io/authenticator", "TestDeleteAccounts_FailedToDelete/b@example.com").Once().
+			Return(secretstorage.ErrNotFound)
+
+		s.On("Delete", "go.nhat.io/authenticator", "TestDeleteAccounts_FailedToDelete/c@example.com").Once().
+			Return(assert.AnError)
+	})
+
+	err := authenticator.DeleteAccounts(t.Name(), []string{"a@example.com", "b@example.com", "c@example.com"})
+	require.ErrorIs(t, err, assert.AnError)
+	require.EqualError(t, err, "failed to delete account a@example.com in namespace TestDeleteAccounts_FailedToDelete: assert.AnError general error for testing; "+
+		"failed to delete account c@example.com in namespace TestDeleteAccounts_FailedToDelete: assert.AnError general error for testing")
+}
+
+func TestDeleteAccounts_CouldNotUpdateNamespace(t *testing.T) {
+	setNamespaceStorage(t, func(s *mockss.Storage[authenticator.Namespace]) {
+		s.On("Get", "go.nhat.io/authenticator", t.Name()).Once().
+			Return(authenticator.Namespace{
+				Name:     t.Name(),
+				Accounts: []string{"a@example.com"},
+			}, nil)
+
+		s.On("Set", "go.nhat.io/authenticator", t.Name(), mock.Anything).Once().
+			Return(assert.AnError)
+	})
+
+	// No account is deleted.
+	setAccountStorage(t, func(*mockss.Storage[authenticator.Account]) {})
+
+	err := authenticator.DeleteAccounts(t.Name(), []string{"a@example.com"})
+	require.EqualError(t, err, "failed to remove accounts from namespace TestDeleteAccounts_CouldNotUpdateNamespace: assert.AnError general error for testing")
+}
+
+func TestSetAccount_Timestamps(t *testing.T) {
+	setConfigFile(t)
+
+	createdAt := accountTime
+	updatedAt := accountTime.Add(24 * time.Hour)
+
+	useInMemoryStorage(t)
+
+	err := authenticator.CreateNamespace(t.Name(), t.Name())
+	require.NoError(t, err)
+
+	restore := authenticator.SetAccountClock(clock.Fix(createdAt))
+
+	err = authenticator.SetAccount(t.Name(), authenticator.Account{Name: "john.doe@example.com", TOTPSecret: "NBSWY3DP"})
+	require.NoError(t, err)
+
+	restore()
+
+	actual, err := authenticator.GetAccount(t.Name(), "john.doe@example.com")
+	require.NoError(t, err)
+
+	assert.Equal(t, createdAt, actual.CreatedAt)
+	assert.Equal(t, createdAt, actual.UpdatedAt)
+
+	t.Cleanup(authenticator.SetAccountClock(clock.Fix(updatedAt)))
+
+	// The creation time is kept even if the caller does not pass it.
+	err = authenticator.SetAccount(t.Name(), authenticator.Account{Name: "john.doe@example.com", TOTPSecret: "JBSWY3DP"})
+	require.NoError(t, err)
+
+	actual, err = authenticator.GetAccount(t.Name(), "john.doe@example.com")
+	require.NoError(t, err)
+
+	assert.Equal(t, otp.TOTPSecret("JBSWY3DP"), actual.TOTPSecret)
+	assert.Equal(t, createdAt, actual.CreatedAt)
+	assert.Equal(t, updatedAt, actual.UpdatedAt)
+}
+
+func TestSetAccount_Timestamps_LegacyAccount(t *testing.T) {
+	setConfigFile(t)
+	setAccountClock(t)
+
+	accounts, _ := useInMemoryStorage(t)
+
+	err := authenticator.CreateNamespace(t.Name(), t.Name())
+	require.NoError(t, err)
+
+	// Accounts stored before the timestamps were introduced have none.
+	err = accounts.Set("go.nhat.io/authenticator", t.Name()+"/john.doe@example.com", authenticator.Account{Name: "john.doe@example.com"})
+	require.NoError(t, err)
+
+	err = authenticator.SetAccount(t.Name(), authenticator.Account{Name: "john.doe@example.com", TOTPSecret: "NBSWY3DP"})
+	require.NoError(t, err)
+
+	actual, err := authenticator.GetAccount(t.Name(), "john.doe@example.com")
+	require.NoError(t, err)
+
+	assert.Equal(t, accountTime, actual.CreatedAt)
+	assert.Equal(t, accountTime, actual.UpdatedAt)
+}
+
+func TestSetAccount_ValidateSecret_Invalid(t *testing.T) {
+	t.Parallel()
+
+	// The storage is never reached, so it is not mocked.
+	err := authenticator.SetAccount(t.Name(), authenticator.Account{Name: "john.doe@example.com", TOTPSecret: "secret"},
+		authenticator.WithValidateSecret(),
+	)
+
+	require.ErrorIs(t, err, authenticator.ErrInvalidSecret)
+	require.ErrorContains(t, err, "failed to store account john.doe@example.com in namespace TestSetAccount_ValidateSecret_Invalid: invalid totp secret")
+}
+
+func TestSetAccount_ValidateSecret_Valid(t *testing.T) {
+	setConfigFile(t)
+
+	useInMemoryStorage(t)
+
+	err := authenticator.CreateNamespace(t.Name(), t.Name())
+	require.NoError(t, err)
+
+	for _, secret := range []otp.TOTPSecret{"NBSWY3DP", "nbswy3dp", ""} {
+		err = authenticator.SetAccount(t.Name(), authenticator.Account{Name: "john.doe@example.com", TOTPSecret: secret},
+			authenticator.WithValidateSecret(),
+		)
+		require.NoError(t, err, "secret %q", secret)
+
+		actual, err := authenticator.GetAccount(t.Name(), "john.doe@example.com")
+		require.NoError(t, err)
+
+		assert.Equal(t, secret, actual.TOTPSecret)
+	}
+}
+
+func TestSetAccount_RejectWeakSecrets_Weak(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		scenario      string
+		secret        otp.TOTPSecret
+		opts          []authenticator.SetAccountOption
+		expectedError string
+	}{
+		{
+			scenario:      "too short",
+			secret:        "HXDMVJECJJWSRB3H",
+			expectedError: "weak totp secret: 80 bits, must be at least 128",
+		},
+		{
+			scenario:      "shorter than the custom minimum",
+			secret:        "HXDMVJECJJWSRB3HWIZR4IFUGFTMXBOZ",
+			opts:          []authenticator.SetAccountOption{authenticator.WithMinSecretBits(256)},
+			expectedError: "weak totp secret: 160 bits, must be at least 256",
+		},
+		{
+			scenario:      "all zeros",
+			secret:        "AAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAA",
+			expectedError: "weak totp secret: all zeros",
+		},
+		{
+			scenario:      "test secret",
+			secret:        "gezd gnbv gy3t qojq gezd gnbv gy3t qojq",
+			expectedError: "weak totp secret: well-known test secret",
+		},
+		{
+			scenario:      "short test secret",
+			secret:        "NBSWY3DP",
+			expectedError: "weak totp secret: 40 bits, must be at least 128",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.scenario, func(t *testing.T) {
+			t.Parallel()
+
+			opts := append([]authenticator.SetAccountOption{authenticator.WithRejectWeakSecrets()}, tc.opts...)
+
+			// The storage is never reached, so it is not mocked.
+			err := authenticator.SetAccount(t.Name(), authenticator.Account{Name: "john.doe@example.com", TOTPSecret: tc.secret}, opts...)
+
+			require.ErrorIs(t, err, authenticator.ErrWeakSecret)
+			require.ErrorContains(t, err, tc.expectedError)
+		})
+	}
+}
+
+func TestSetAccount_RejectWeakSecrets_Strong(t *testing.T) {
+	setConfigFile(t)
+
+	useInMemoryStorage(t)
+
+	err := authenticator.CreateNamespace(t.Name(), t.Name())
+	require.NoError(t, err)
+
+	err = authenticator.SetAccount(t.Name(), authenticator.Account{Name: "john.doe@example.com", TOTPSecret: "HXDMVJECJJWSRB3HWIZR4IFUGFTMXBOZ"},
+		authenticator.WithRejectWeakSecrets(),
+	)
+	require.NoError(t, err)
+
+	err = authenticator.SetAccount(t.Name(), authenticator.Account{Name: "jane.doe@example.com", TOTPSecret: "HXDMVJECJJWSRB3H"},
+		authenticator.WithMinSecretBits(80),
+	)
+	require.NoError(t, err)
+}
+
+func TestSetAccount_RollbackOnNamespaceFailure(t *testing.T) {
+	existing := authenticator.Account{
+		Name:       "john.doe@example.com",
+		TOTPSecret: "JBSWY3DP",
+		CreatedAt:  time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC),
+		UpdatedAt:  time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC),
+	}
+
+	namespaceNotFound := func(s *mockss.Storage[authenticator.Namespace]) {
+		s.On("Get", "go.nhat.io/authenticator", "namespace").
+			Return(authenticator.Namespace{}, secretstorage.ErrNotFound)
+	}
+
+	failedToUpdateNamespace := func(s *mockss.Storage[authenticator.Namespace]) {
+		s.On("Get", "go.nhat.io/authenticator", "namespace").
+			Return(authenticator.Namespace{Name: "namespace"}, nil)
+
+		s.On("Set", "go.nhat.io/authenticator", "namespace", mock.Anything).
+			Return(assert.AnError)
+	}
+
+	testCases := []struct {
+		scenario      string
+		existing      *authenticator.Account
+		mockNamespace func(s *mockss.Storage[authenticator.Namespace])
+		expectedError string
+	}{
+		{
+			scenario:      "new account, namespace not found",
+			mockNamespace: namespaceNotFound,
+			expectedError: "failed to get namespace namespace for creating account john.doe@example.com: namespace not found",
+		},
+		{
+			scenario:      "new account, failed to update namespace",
+			mockNamespace: failedToUpdateNamespace,
+			expectedError: "failed to update namespace namespace: assert.AnError general error for testing",
+		},
+		{
+			scenario:      "existing account, namespace not found",
+			existing:      &existing,
+			mockNamespace: namespaceNotFound,
+			expectedError: "failed to get namespace namespace for creating account john.doe@example.com: namespace not found",
+		},
+		{
+			scenario:      "existing account, failed to update namespace",
+			existing:      &existing,
+			mockNamespace: failedToUpdateNamespace,
+			expectedError: "failed to update namespace namespace: assert.AnError general error for testing",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.scenario, func(t *testing.T) {
+			setAccountClock(t)
+			setNamespaceStorage(t, tc.mockNamespace)
+
+			accounts := authenticator.NewInMemoryStorage[authenticator.Account]()
+
+			t.Cleanup(authenticator.SetAccountStorage(accounts))
+
+			if tc.existing != nil {
+				err := accounts.Set("go.nhat.io/authenticator", "namespace/john.doe@example.com", *tc.existing)
+				require.NoError(t, err)
+			}
+
+			err := authenticator.SetAccount("namespace", authenticator.Account{Name: "john.doe@example.com", TOTPSecret: "NBSWY3DP"})
+			require.EqualError(t, err, tc.expectedError)
+
+			actual, err := accounts.Get("go.nhat.io/authenticator", "namespace/john.doe@example.com")
+
+			if tc.existing == nil {
+				require.ErrorIs(t, err, secretstorage.ErrNotFound, "the account must not be left behind")
+			} else {
+				require.NoError(t, err)
+				assert.Equal(t, *tc.existing, actual, "the previous account must be restored")
+			}
+		})
+	}
+}
+
+func setAccountClock(t *testing.T) {
+	t.Helper()
+
+	t.Cleanup(authenticator.SetAccountClock(clock.Fix(accountTime)))
+}
+
 func setAccountStorage(t *testing.T, mocks ...func(s *mockss.Storage[authenticator.Account])) {
 	t.Helper()
 
@@ -380,3 +1286,138 @@ func setAccountStorage(t *testing.T, mocks ...func(s *mockss.Storage[authenticat
 
 	t.Cleanup(reset)
 }
+
+func useInMemoryStorage(tb testing.TB) (secretstorage.Storage[authenticator.Account], secretstorage.Storage[authenticator.Namespace]) {
+	tb.Helper()
+
+	accounts := authenticator.NewInMemoryStorage[authenticator.Account]()
+	namespaces := authenticator.NewInMemoryStorage[authenticator.Namespace]()
+
+	tb.Cleanup(authenticator.UseStorage(accounts, namespaces))
+
+	return accounts, namespaces
+}
+
+func TestRotateTOTPSecret(t *testing.T) {
+	setAccountClock(t)
+	setConfigFile(t)
+
+	useInMemoryStorage(t)
+
+	t.Setenv("AUTHENTICATOR_TOTP_SECRET", "")
+
+	c := clock.Fix(accountTime)
+
+	err := authenticator.CreateNamespace("namespace", "Namespace")
+	require.NoError(t, err)
+
+	err = authenticator.SetAccount("namespace", authenticator.Account{Name: "john.doe@example.com", TOTPSecret: "NBSWY3DP"})
+	require.NoError(t, err)
+
+	before, err := authenticator.GenerateTOTP(context.Background(), "namespace", "john.doe@example.com", authenticator.WithClock(c))
+	require.NoError(t, err)
+	assert.Equal(t, otp.OTP("191882"), before)
+
+	old, err := authenticator.RotateTOTPSecret(context.Background(), "namespace", "john.doe@example.com", "JBSWY3DP")
+	require.NoError(t, err)
+	assert.Equal(t, otp.TOTPSecret("NBSWY3DP"), old)
+
+	after, err := authenticator.GenerateTOTP(context.Background(), "namespace", "john.doe@example.com", authenticator.WithClock(c))
+	require.NoError(t, err)
+	assert.Equal(t, otp.OTP("180965"), after)
+
+	actual, err := authenticator.GetAccount("namespace", "john.doe@example.com")
+	require.NoError(t, err)
+	assert.Equal(t, otp.TOTPSecret("JBSWY3DP"), actual.TOTPSecret)
+	assert.NotContains(t, actual.Metadata, authenticator.PreviousSecretMetadataKey)
+}
+
+func TestRotateTOTPSecret_KeepPreviousSecret(t *testing.T) {
+	setConfigFile(t)
+
+	useInMemoryStorage(t)
+
+	err := authenticator.CreateNamespace("namespace", "Namespace")
+	require.NoError(t, err)
+
+	err = authenticator.SetAccount("namespace", authenticator.Account{
+		Name:       "john.doe@example.com",
+		TOTPSecret: "NBSWY3DP",
+		Metadata:   map[string]any{"team": "platform"},
+	})
+	require.NoError(t, err)
+
+	updatedAt := accountTime.Add(time.Hour)
+
+	t.Cleanup(authenticator.SetAccountClock(clock.Fix(updatedAt)))
+
+	_, err = authenticator.RotateTOTPSecret(context.Background(), "namespace", "john.doe@example.com", "JBSWY3DP",
+		authenticator.WithKeepPreviousSecret(),
+	)
+	require.NoError(t, err)
+
+	actual, err := authenticator.GetAccount("namespace", "john.doe@example.com")
+	require.NoError(t, err)
+
+	expected := map[string]any{
+		"team":                                  "platform",
+		authenticator.PreviousSecretMetadataKey: "NBSWY3DP",
+	}
+
+	assert.Equal(t, expected, actual.Metadata)
+	assert.Equal(t, updatedAt, actual.UpdatedAt)
+}
+
+func TestRotateTOTPSecret_InvalidSecret(t *testing.T) {
+	setAccountStorage(t, func(*mockss.Storage[authenticator.Account]) {})
+
+	for _, secret := range []otp.TOTPSecret{otp.NoTOTPSecret, "invalid!"} {
+		old, err := authenticator.RotateTOTPSecret(context.Background(), "namespace", "john.doe@example.com", secret)
+		require.ErrorIs(t, err, authenticator.ErrInvalidSecret)
+		assert.Empty(t, old)
+	}
+}
+
+func TestRotateTOTPSecret_AccountNotFound(t *testing.T) {
+	setConfigFile(t)
+
+	useInMemoryStorage(t)
+
+	old, err := authenticator.RotateTOTPSecret(context.Background(), "namespace", "john.doe@example.com", "JBSWY3DP")
+	require.ErrorIs(t, err, authenticator.ErrAccountNotFound)
+	assert.Empty(t, old)
+}
+
+func TestSetAccount_Concurrent(t *testing.T) {
+	setConfigFile(t)
+
+	useInMemoryStorage(t)
+
+	err := authenticator.CreateNamespace("namespace", "Namespace")
+	require.NoError(t, err)
+
+	const count = 50
+
+	var wg sync.WaitGroup
+
+	expected := make([]string, count)
+
+	for i := range count {
+		expected[i] = fmt.Sprintf("account-%02d", i)
+
+		wg.Add(1)
+
+		go func(name string) {
+			defer wg.Done()
+
+			assert.NoError(t, authenticator.SetAccount("namespace", authenticator.Account{Name: name, TOTPSecret: "NBSWY3DP"}))
+		}(expected[i])
+	}
+
+	wg.Wait()
+
+	n, err := authenticator.GetNamespace("namespace")
+	require.NoError(t, err)
+
+	assert.Equal(t, expected, n.Accounts)
+}
@@ -2,29 +2,318 @@ package authenticator
 
 import (
 	"bufio"
+	"encoding/json"
 	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"slices"
 	"sync"
+	"time"
 
 	"github.com/pelletier/go-toml/v2"
 )
 
 const (
-	serviceName = "go.nhat.io/authenticator"
+	defaultServiceName = "go.nhat.io/authenticator"
 
 	configFile = `.authenticator.toml`
 
 	envConfigFile = "AUTHENTICATOR_CONFIG"
+
+	// currentConfigVersion is the version of the config file format written by this package.
+	currentConfigVersion = 1
+)
+
+var (
+	configMu             sync.RWMutex
+	configStore          ConfigStore
+	defaultConfigOptions []ConfigOption
+	serviceName          = defaultServiceName
 )
 
-var configMu sync.RWMutex
+// ErrUnsupportedConfigVersion indicates that the config file was written by a newer version of this package.
+var ErrUnsupportedConfigVersion = errors.New("unsupported config version")
+
+// ConfigOption is an option to configure where the list of namespaces is stored.
+type ConfigOption interface {
+	applyConfigOption(o *configOptions)
+}
+
+type configOptionFunc func(o *configOptions)
+
+func (f configOptionFunc) applyConfigOption(o *configOptions) {
+	f(o)
+}
+
+type configOptions struct {
+	file                      string
+	lockTimeout               time.Duration
+	caseInsensitiveNamespaces bool
+	withoutRegistry           bool
+	saveOptions               []SaveConfigOption
+}
+
+// configFile returns the config file from the options, or the default one.
+func (o configOptions) configFile() string {
+	if o.file != "" {
+		return o.file
+	}
+
+	return getConfigFile()
+}
+
+// store returns the config file from the options if it is set, then the store set by SetConfigStore, then the default
+// config file.
+func (o configOptions) store() ConfigStore {
+	if o.file == "" && configStore != nil {
+		return configStore
+	}
+
+	return fileConfigStore{path: o.configFile(), lockTimeout: o.lockTimeout, saveOptions: o.saveOptions}
+}
+
+// newConfigOptions applies the options set by SetDefaultConfigOptions, then the given ones. The caller must hold
+// configMu.
+func newConfigOptions(opts ...ConfigOption) configOptions {
+	var o configOptions
+
+	for _, opt := range defaultConfigOptions {
+		opt.applyConfigOption(&o)
+	}
+
+	for _, opt := range opts {
+		opt.applyConfigOption(&o)
+	}
+
+	return o
+}
+
+// WithConfigFile sets the config file to use instead of the one from the AUTHENTICATOR_CONFIG env or
+// $HOME/.authenticator.toml.
+func WithConfigFile(path string) ConfigOption {
+	return configOptionFunc(func(o *configOptions) {
+		o.file = path
+	})
+}
+
+// WithCaseSensitiveNamespaces sets whether the namespace ids that differ only by case, e.g. "work" and "Work", are
+// distinct. When false, CreateNamespace returns ErrNamespaceExists for a case variant of an existing id. Defaults to
+// true.
+func WithCaseSensitiveNamespaces(sensitive bool) ConfigOption {
+	return configOptionFunc(func(o *configOptions) {
+		o.caseInsensitiveNamespaces = !sensitive
+	})
+}
+
+// WithoutConfigRegistry makes the namespace storage the only source of the namespaces, the config file is neither read
+// nor written. The namespace storage must implement KeyLister to list the namespaces, otherwise GetAllNamespaceIDs
+// returns ErrNamespaceListingUnsupported. Use SetDefaultConfigOptions to disable the registry for the functions that do
+// not take a ConfigOption too.
+func WithoutConfigRegistry() ConfigOption {
+	return configOptionFunc(func(o *configOptions) {
+		o.withoutRegistry = true
+	})
+}
+
+// WithConfigSaveOptions sets the options to format the config file when it is written, e.g. WithMultilineArrays to keep
+// the diffs minimal when the config file is under version control.
+func WithConfigSaveOptions(opts ...SaveConfigOption) ConfigOption {
+	return configOptionFunc(func(o *configOptions) {
+		o.saveOptions = append(o.saveOptions, opts...)
+	})
+}
+
+// SaveConfigOption is an option to configure how SaveConfig formats the config.
+type SaveConfigOption interface {
+	applySaveConfigOption(o *saveConfigOptions)
+}
+
+type saveConfigOptionFunc func(o *saveConfigOptions)
+
+func (f saveConfigOptionFunc) applySaveConfigOption(o *saveConfigOptions) {
+	f(o)
+}
+
+type saveConfigOptions struct {
+	multilineArrays bool
+	indent          string
+}
+
+// WithMultilineArrays writes one element of the arrays per line in toml, so that adding a namespace adds its line and a
+// comma to the previous one.
+func WithMultilineArrays() SaveConfigOption {
+	return saveConfigOptionFunc(func(o *saveConfigOptions) {
+		o.multilineArrays = true
+	})
+}
+
+// WithConfigIndent sets the indentation, e.g. of the elements of the multiline arrays in toml. In json, the config is
+// indented with it. Defaults to two spaces in toml, and no indentation in json.
+func WithConfigIndent(indent string) SaveConfigOption {
+	return saveConfigOptionFunc(func(o *saveConfigOptions) {
+		o.indent = indent
+	})
+}
+
+// SetServiceName sets the keyring service under which the namespaces and the accounts are stored. Defaults to
+// "go.nhat.io/authenticator", which is shared by all the applications using this package, so they see and may
+// overwrite each other's accounts. An application can use its own service name to keep its secrets apart. The returned
+// func restores the previous name.
+func SetServiceName(name string) func() {
+	configMu.Lock()
+	defer configMu.Unlock()
+
+	prev := serviceName
+	serviceName = name
+
+	return func() {
+		configMu.Lock()
+		defer configMu.Unlock()
+
+		serviceName = prev
+	}
+}
+
+// ConfigStore keeps the list of namespace ids. By default, it is the config file.
+type ConfigStore interface {
+	// Load returns the namespace ids.
+	Load() ([]string, error)
+	// Save replaces the namespace ids.
+	Save(ids []string) error
+}
+
+// configLocker is implemented by the config stores that need a lock for a read-modify-write, e.g. the config file that
+// is shared between processes.
+type configLocker interface {
+	lock() (func(), error)
+}
+
+// lockConfigStore acquires the lock of the store if it has one.
+func lockConfigStore(s ConfigStore) (func(), error) {
+	if l, ok := s.(configLocker); ok {
+		return l.lock()
+	}
+
+	return func() {}, nil
+}
+
+// SetConfigStore sets the store of the namespace ids, nil means the config file. WithConfigFile takes precedence over
+// the store.
+func SetConfigStore(s ConfigStore) func() {
+	configMu.Lock()
+	defer configMu.Unlock()
+
+	prev := configStore
+	configStore = s
+
+	return func() {
+		configMu.Lock()
+		defer configMu.Unlock()
+
+		configStore = prev
+	}
+}
+
+// SetDefaultConfigOptions sets the config options of every call, before the options of the call. They also apply to the
+// functions that do not take a ConfigOption, such as the imports, ExportAll, GetAccountOrCreate, SelfTest and
+// TOTPSecretProvider.SetTOTPSecret. For example, SetDefaultConfigOptions(WithoutConfigRegistry()) stops the package from
+// reading and writing the config file at all. The returned func restores the previous options.
+func SetDefaultConfigOptions(opts ...ConfigOption) func() {
+	configMu.Lock()
+	defer configMu.Unlock()
+
+	prev := defaultConfigOptions
+	defaultConfigOptions = opts
+
+	return func() {
+		configMu.Lock()
+		defer configMu.Unlock()
+
+		defaultConfigOptions = prev
+	}
+}
+
+type fileConfigStore struct {
+	path        string
+	lockTimeout time.Duration
+	saveOptions []SaveConfigOption
+}
+
+func (s fileConfigStore) Load() ([]string, error) {
+	cfg, err := loadConfigFile(s.path)
+	if err != nil {
+		return nil, err
+	}
 
-type config struct {
+	return cfg.Namespaces, nil
+}
+
+func (s fileConfigStore) Save(ids []string) error {
+	return saveConfigFile(s.path, Config{Namespaces: ids}, s.saveOptions...)
+}
+
+// lock acquires the lock of the config file.
+func (s fileConfigStore) lock() (func(), error) {
+	timeout := s.lockTimeout
+	if timeout <= 0 {
+		timeout = defaultConfigLockTimeout
+	}
+
+	return lockConfigFile(s.path, timeout)
+}
+
+type inMemoryConfigStore struct {
+	mu  sync.Mutex
+	ids []string
+}
+
+func (s *inMemoryConfigStore) Load() ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return slices.Clone(s.ids), nil
+}
+
+func (s *inMemoryConfigStore) Save(ids []string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.ids = slices.Clone(ids)
+
+	return nil
+}
+
+// NewInMemoryConfigStore returns a config store that keeps the namespace ids in memory, for tests and for the
+// applications that do not want a config file.
+func NewInMemoryConfigStore() ConfigStore {
+	return &inMemoryConfigStore{}
+}
+
+// Config is the content of the config file: the list of namespace ids.
+type Config struct {
+	Version    int      `json:"version" toml:"version" yaml:"version"`
 	Namespaces []string `json:"namespaces" toml:"namespaces" yaml:"namespaces"`
 }
 
+// migrateConfig upgrades the config to the current version, one version at a time.
+func migrateConfig(cfg Config) Config {
+	for cfg.Version < currentConfigVersion {
+		switch cfg.Version {
+		case 0:
+			// Version 0 has no version field, the namespaces are kept as is.
+			cfg.Version = 1
+
+		default:
+			// There is no migration from an unknown version, the loop must not spin on it.
+			cfg.Version = currentConfigVersion
+		}
+	}
+
+	return cfg
+}
+
 func getConfigFile() string {
 	userConfigFile := os.Getenv(envConfigFile)
 	if userConfigFile == "" {
@@ -39,42 +328,138 @@ func getConfigFile() string {
 	return userConfigFile
 }
 
-func loadConfigFile() (config, error) {
-	f, err := os.Open(getConfigFile())
+func loadConfigFile(path string) (Config, error) {
+	f, err := os.Open(filepath.Clean(path))
 	if err != nil {
 		if errors.Is(err, os.ErrNotExist) {
-			return config{}, nil
+			return migrateConfig(Config{}), nil
 		}
 
-		return config{}, fmt.Errorf("failed to open config file: %w", err)
+		return Config{}, fmt.Errorf("failed to open config file: %w", err)
 	}
 
 	defer f.Close() //nolint: errcheck
 
-	var cfg config
+	return LoadConfig(f, "toml")
+}
+
+// LoadConfig reads the config in toml or json format, e.g. from a section of a larger config. The config is upgraded to
+// the current version.
+func LoadConfig(r io.Reader, format string) (Config, error) {
+	var (
+		cfg Config
+		err error
+	)
+
+	switch format {
+	case "toml":
+		err = toml.NewDecoder(r).Decode(&cfg)
+
+	case "json":
+		err = json.NewDecoder(r).Decode(&cfg)
+
+	default:
+		return Config{}, fmt.Errorf("failed to load config: %w %s", ErrUnsupportedFormat, format)
+	}
+
+	if err != nil {
+		return Config{}, fmt.Errorf("failed to decode config: %w", err)
+	}
+
+	if cfg.Version < 0 || cfg.Version > currentConfigVersion {
+		return Config{}, fmt.Errorf("failed to load config: %w: %d", ErrUnsupportedConfigVersion, cfg.Version)
+	}
+
+	return migrateConfig(cfg), nil
+}
+
+// SaveConfig writes the config in toml or json format. The version is set to the current one. The output only depends
+// on the config and the options.
+func SaveConfig(w io.Writer, cfg Config, format string, opts ...SaveConfigOption) error {
+	cfg.Version = currentConfigVersion
+
+	var o saveConfigOptions
+
+	for _, opt := range opts {
+		opt.applySaveConfigOption(&o)
+	}
+
+	var err error
+
+	switch format {
+	case "toml":
+		err = encodeTOMLConfig(w, cfg, o)
+
+	case "json":
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", o.indent)
+
+		err = enc.Encode(cfg)
+
+	default:
+		return fmt.Errorf("failed to save config: %w %s", ErrUnsupportedFormat, format)
+	}
+
+	if err != nil {
+		return fmt.Errorf("failed to encode config: %w", err)
+	}
+
+	return nil
+}
+
+// encodeTOMLConfig writes the config in toml.
+func encodeTOMLConfig(w io.Writer, cfg Config, o saveConfigOptions) error {
+	enc := toml.NewEncoder(w).SetArraysMultiline(o.multilineArrays)
 
-	if err := toml.NewDecoder(f).Decode(&cfg); err != nil {
-		return config{}, fmt.Errorf("failed to decode config file: %w", err)
+	if o.indent != "" {
+		enc.SetIndentSymbol(o.indent)
 	}
 
-	return cfg, nil
+	return enc.Encode(cfg) //nolint: wrapcheck
 }
 
-func saveConfigFile(cfg config) error {
-	f, err := os.OpenFile(getConfigFile(), os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600)
+// saveConfigFile writes the config to a temp file in the same directory and renames it over the original, so that the
+// config file is never observed half-written.
+func saveConfigFile(path string, cfg Config, opts ...SaveConfigOption) (err error) {
+	path = filepath.Clean(path)
+
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
 	if err != nil {
 		return fmt.Errorf("failed to open config file: %w", err)
 	}
 
-	defer f.Close() //nolint: errcheck
+	defer func() {
+		if err != nil {
+			_ = f.Close()           //nolint: errcheck
+			_ = os.Remove(f.Name()) //nolint: errcheck
+		}
+	}()
+
+	if err := f.Chmod(0o600); err != nil {
+		return fmt.Errorf("failed to open config file: %w", err)
+	}
 
 	buf := bufio.NewWriter(f)
 
-	if err := toml.NewEncoder(buf).Encode(cfg); err != nil {
+	if err := SaveConfig(buf, cfg, "toml", opts...); err != nil {
+		return fmt.Errorf("failed to write config file: %w", err)
+	}
+
+	if err := buf.Flush(); err != nil {
 		return fmt.Errorf("failed to write config file: %w", err)
 	}
 
-	buf.Flush() //nolint: errcheck,gosec
+	if err := f.Sync(); err != nil {
+		return fmt.Errorf("failed to write config file: %w", err)
+	}
+
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to write config file: %w", err)
+	}
+
+	if err := os.Rename(f.Name(), path); err != nil {
+		return fmt.Errorf("failed to write config file: %w", err)
+	}
 
 	return nil
 }
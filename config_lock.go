@@ -0,0 +1,61 @@
+package authenticator
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"time"
+)
+
+const (
+	defaultConfigLockTimeout = 10 * time.Second
+	configLockRetryInterval  = 10 * time.Millisecond
+)
+
+// ErrConfigLockTimeout indicates that the config file is locked by another process for too long.
+var ErrConfigLockTimeout = errors.New("timed out waiting for config file lock")
+
+// WithConfigLockTimeout sets how long to wait for the lock of the config file held by another process. Defaults to 10
+// seconds.
+func WithConfigLockTimeout(timeout time.Duration) ConfigOption {
+	return configOptionFunc(func(o *configOptions) {
+		o.lockTimeout = timeout
+	})
+}
+
+// lockConfigFile acquires an exclusive advisory lock for the config file. The lock is taken on a sibling ".lock" file
+// because the config file itself is replaced on every write.
+func lockConfigFile(path string, timeout time.Duration) (func(), error) {
+	f, err := os.OpenFile(path+".lock", os.O_RDWR|os.O_CREATE, 0o600) //nolint: gosec
+	if err != nil {
+		return nil, fmt.Errorf("failed to open config lock file: %w", err)
+	}
+
+	deadline := time.Now().Add(timeout)
+
+	for {
+		locked, err := tryLockFile(f)
+		if err != nil {
+			_ = f.Close() //nolint: errcheck
+
+			return nil, fmt.Errorf("failed to lock config file: %w", err)
+		}
+
+		if locked {
+			break
+		}
+
+		if time.Now().After(deadline) {
+			_ = f.Close() //nolint: errcheck
+
+			return nil, fmt.Errorf("failed to lock config file %s: %w after %s", path, ErrConfigLockTimeout, timeout)
+		}
+
+		time.Sleep(configLockRetryInterval)
+	}
+
+	return func() {
+		_ = unlockFile(f) //nolint: errcheck
+		_ = f.Close()     //nolint: errcheck
+	}, nil
+}
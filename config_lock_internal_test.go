@@ -0,0 +1,74 @@
+package authenticator
+
+import (
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLockConfigFile_Serialization(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "config.toml")
+
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		holders int
+		maxSeen int
+	)
+
+	for range 2 {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for range 20 {
+				unlock, err := lockConfigFile(path, time.Second)
+				if !assert.NoError(t, err) {
+					return
+				}
+
+				mu.Lock()
+				holders++
+				maxSeen = max(maxSeen, holders)
+				mu.Unlock()
+
+				time.Sleep(time.Millisecond)
+
+				mu.Lock()
+				holders--
+				mu.Unlock()
+
+				unlock()
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	assert.Equal(t, 1, maxSeen)
+}
+
+func TestLockConfigFile_Timeout(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "config.toml")
+
+	unlock, err := lockConfigFile(path, time.Second)
+	require.NoError(t, err)
+
+	defer unlock()
+
+	start := time.Now()
+
+	_, err = lockConfigFile(path, 50*time.Millisecond)
+	require.ErrorIs(t, err, ErrConfigLockTimeout)
+
+	assert.GreaterOrEqual(t, time.Since(start), 50*time.Millisecond)
+}
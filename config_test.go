@@ -1,11 +1,18 @@
 package authenticator_test
 
 import (
+	"bytes"
+	"context"
+	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 
+	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
+
+	"go.nhat.io/authenticator"
 )
 
 func setConfigFile(t *testing.T) {
@@ -26,3 +33,403 @@ func setConfigFileWithContent(t *testing.T, content string) {
 
 	t.Setenv("AUTHENTICATOR_CONFIG", file)
 }
+
+func TestSaveConfigFile_Atomic(t *testing.T) {
+	dir := t.TempDir()
+	file := filepath.Join(dir, "config.toml")
+	opt := authenticator.WithConfigFile(file)
+
+	useInMemoryStorage(t)
+
+	err := authenticator.CreateNamespace("ns-0", "Namespace", opt)
+	require.NoError(t, err)
+
+	done := make(chan struct{})
+	observed := make(chan []byte, 1)
+
+	go func() {
+		defer close(observed)
+
+		for {
+			select {
+			case <-done:
+				return
+
+			default:
+			}
+
+			content, err := os.ReadFile(file) //nolint: gosec
+			if err != nil || len(content) == 0 {
+				observed <- content
+
+				return
+			}
+		}
+	}()
+
+	for i := 1; i < 50; i++ {
+		err := authenticator.CreateNamespace(fmt.Sprintf("ns-%d", i), "Namespace", opt)
+		require.NoError(t, err)
+	}
+
+	close(done)
+
+	for content := range observed {
+		t.Fatalf("config file observed missing or empty: %q", content)
+	}
+
+	info, err := os.Stat(file)
+	require.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+
+	tmpFiles, err := filepath.Glob(filepath.Join(dir, "*.tmp"))
+	require.NoError(t, err)
+	require.Empty(t, tmpFiles, "temp files must not be left behind")
+
+	actual, err := authenticator.GetAllNamespaceIDs(opt)
+	require.NoError(t, err)
+	assert.Len(t, actual, 50)
+}
+
+func TestLoadConfigFile_MigrateVersion0(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "config.toml")
+	opt := authenticator.WithConfigFile(file)
+
+	err := os.WriteFile(file, []byte(`namespaces = ["personal", "work"]`), 0o600)
+	require.NoError(t, err)
+
+	useInMemoryStorage(t)
+
+	actual, err := authenticator.GetAllNamespaceIDs(opt)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"personal", "work"}, actual)
+
+	err = authenticator.CreateNamespace("family", "Family", opt)
+	require.NoError(t, err)
+
+	content, err := os.ReadFile(file) //nolint: gosec
+	require.NoError(t, err)
+
+	expected := `version = 1
+namespaces = ['family', 'personal', 'work']
+`
+
+	assert.Equal(t, expected, string(content))
+}
+
+func TestLoadConfigFile_UnsupportedVersion(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "config.toml")
+
+	err := os.WriteFile(file, []byte("version = 2\nnamespaces = [\"work\"]\n"), 0o600)
+	require.NoError(t, err)
+
+	actual, err := authenticator.GetAllNamespaceIDs(authenticator.WithConfigFile(file))
+	require.ErrorIs(t, err, authenticator.ErrUnsupportedConfigVersion)
+	require.EqualError(t, err, "failed to load config: unsupported config version: 2")
+	assert.Empty(t, actual)
+}
+
+func TestLoadConfig(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		scenario string
+		format   string
+		content  string
+	}{
+		{
+			scenario: "toml",
+			format:   "toml",
+			content:  "namespaces = ['personal', 'work']\n",
+		},
+		{
+			scenario: "json",
+			format:   "json",
+			content:  `{"version": 1, "namespaces": ["personal", "work"]}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.scenario, func(t *testing.T) {
+			t.Parallel()
+
+			actual, err := authenticator.LoadConfig(strings.NewReader(tc.content), tc.format)
+			require.NoError(t, err)
+
+			expected := authenticator.Config{
+				Version:    1,
+				Namespaces: []string{"personal", "work"},
+			}
+
+			assert.Equal(t, expected, actual)
+		})
+	}
+}
+
+func TestLoadConfig_Error(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		scenario      string
+		format        string
+		content       string
+		expectedError string
+	}{
+		{
+			scenario:      "unsupported format",
+			format:        "yaml",
+			expectedError: "failed to load config: unsupported format yaml",
+		},
+		{
+			scenario:      "invalid json",
+			format:        "json",
+			content:       `{`,
+			expectedError: "failed to decode config: unexpected EOF",
+		},
+		{
+			scenario:      "unsupported version",
+			format:        "json",
+			content:       `{"version": 2}`,
+			expectedError: "failed to load config: unsupported config version: 2",
+		},
+		{
+			scenario:      "negative version",
+			format:        "toml",
+			content:       "version = -1\nnamespaces = ['a']",
+			expectedError: "failed to load config: unsupported config version: -1",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.scenario, func(t *testing.T) {
+			t.Parallel()
+
+			actual, err := authenticator.LoadConfig(strings.NewReader(tc.content), tc.format)
+			require.EqualError(t, err, tc.expectedError)
+			assert.Empty(t, actual)
+		})
+	}
+}
+
+func TestSaveConfig(t *testing.T) {
+	t.Parallel()
+
+	cfg := authenticator.Config{Namespaces: []string{"personal", "work"}}
+
+	testCases := []struct {
+		format   string
+		expected string
+	}{
+		{
+			format:   "toml",
+			expected: "version = 1\nnamespaces = ['personal', 'work']\n",
+		},
+		{
+			format:   "json",
+			expected: `{"version":1,"namespaces":["personal","work"]}` + "\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.format, func(t *testing.T) {
+			t.Parallel()
+
+			buf := new(bytes.Buffer)
+
+			err := authenticator.SaveConfig(buf, cfg, tc.format)
+			require.NoError(t, err)
+
+			assert.Equal(t, tc.expected, buf.String())
+
+			actual, err := authenticator.LoadConfig(buf, tc.format)
+			require.NoError(t, err)
+
+			assert.Equal(t, []string{"personal", "work"}, actual.Namespaces)
+		})
+	}
+}
+
+func TestSaveConfig_Options(t *testing.T) {
+	t.Parallel()
+
+	cfg := authenticator.Config{Namespaces: []string{"personal", "work"}}
+
+	testCases := []struct {
+		scenario string
+		format   string
+		opts     []authenticator.SaveConfigOption
+		expected string
+	}{
+		{
+			scenario: "toml multiline arrays",
+			format:   "toml",
+			opts:     []authenticator.SaveConfigOption{authenticator.WithMultilineArrays()},
+			expected: "version = 1\nnamespaces = [\n  'personal',\n  'work'\n]\n",
+		},
+		{
+			scenario: "toml indent",
+			format:   "toml",
+			opts:     []authenticator.SaveConfigOption{authenticator.WithMultilineArrays(), authenticator.WithConfigIndent("\t")},
+			expected: "version = 1\nnamespaces = [\n\t'personal',\n\t'work'\n]\n",
+		},
+		{
+			scenario: "json indent",
+			format:   "json",
+			opts:     []authenticator.SaveConfigOption{authenticator.WithConfigIndent("  ")},
+			expected: "{\n  \"version\": 1,\n  \"namespaces\": [\n    \"personal\",\n    \"work\"\n  ]\n}\n",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.scenario, func(t *testing.T) {
+			t.Parallel()
+
+			buf := new(bytes.Buffer)
+
+			err := authenticator.SaveConfig(buf, cfg, tc.format, tc.opts...)
+			require.NoError(t, err)
+
+			assert.Equal(t, tc.expected, buf.String())
+		})
+	}
+}
+
+func TestSaveConfigFile_Deterministic(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "config.toml")
+	opts := []authenticator.ConfigOption{
+		authenticator.WithConfigFile(file),
+		authenticator.WithConfigSaveOptions(authenticator.WithMultilineArrays()),
+	}
+
+	useInMemoryStorage(t)
+
+	err := authenticator.CreateNamespace("personal", "Personal", opts...)
+	require.NoError(t, err)
+
+	before, err := os.ReadFile(file) //nolint: gosec
+	require.NoError(t, err)
+
+	// Saving the same config again produces the same output.
+	buf := new(bytes.Buffer)
+
+	err = authenticator.SaveConfig(buf, authenticator.Config{Namespaces: []string{"personal"}}, "toml", authenticator.WithMultilineArrays())
+	require.NoError(t, err)
+	assert.Equal(t, string(before), buf.String())
+
+	err = authenticator.CreateNamespace("work", "Work", opts...)
+	require.NoError(t, err)
+
+	after, err := os.ReadFile(file) //nolint: gosec
+	require.NoError(t, err)
+
+	// Adding a namespace adds its line and a comma to the previous one.
+	beforeLines := strings.Split(string(before), "\n")
+	afterLines := strings.Split(string(after), "\n")
+
+	require.Len(t, afterLines, len(beforeLines)+1)
+	assert.Equal(t, "  'personal'", beforeLines[2])
+	assert.Equal(t, "  'personal',", afterLines[2])
+	assert.Equal(t, "  'work'", afterLines[3])
+	assert.Equal(t, beforeLines[3:], afterLines[4:])
+}
+
+func TestSaveConfig_MultilineArrays_Brackets(t *testing.T) {
+	t.Parallel()
+
+	cfg := authenticator.Config{
+		Version:    1,
+		Namespaces: []string{"[personal]", "work]\n]", "]"},
+	}
+
+	buf := new(bytes.Buffer)
+
+	err := authenticator.SaveConfig(buf, cfg, "toml", authenticator.WithMultilineArrays())
+	require.NoError(t, err)
+
+	actual, err := authenticator.LoadConfig(buf, "toml")
+	require.NoError(t, err)
+	assert.Equal(t, cfg, actual)
+}
+
+func TestSaveConfig_UnsupportedFormat(t *testing.T) {
+	t.Parallel()
+
+	buf := new(bytes.Buffer)
+
+	err := authenticator.SaveConfig(buf, authenticator.Config{}, "yaml")
+	require.ErrorIs(t, err, authenticator.ErrUnsupportedFormat)
+	assert.Empty(t, buf.String())
+}
+
+func TestSetDefaultConfigOptions_WithoutConfigRegistry(t *testing.T) {
+	setAccountClock(t)
+	setConfigFile(t)
+	useInMemoryStorage(t)
+
+	t.Setenv("AUTHENTICATOR_TOTP_SECRET", "")
+	t.Cleanup(authenticator.SetDefaultConfigOptions(authenticator.WithoutConfigRegistry()))
+
+	configFile := os.Getenv("AUTHENTICATOR_CONFIG")
+
+	_, err := authenticator.ImportAccounts("imported", strings.NewReader(`{"accounts": [
+		{"name": "john.doe@example.com", "totp_secret": "NBSWY3DP"}
+	]}`), "json")
+	require.NoError(t, err)
+
+	_, _, err = authenticator.GetAccountOrCreate("created", authenticator.Account{Name: "john.doe@example.com", TOTPSecret: "NBSWY3DP"})
+	require.NoError(t, err)
+
+	err = authenticator.TOTPSecretFromAccount("provider", "john.doe@example.com").SetTOTPSecret(context.Background(), "NBSWY3DP", "example.com")
+	require.NoError(t, err)
+
+	buf := new(bytes.Buffer)
+
+	err = authenticator.ExportAll(buf)
+	require.NoError(t, err)
+
+	err = authenticator.DeleteAllNamespaces()
+	require.NoError(t, err)
+
+	_, err = authenticator.ImportAll(buf, authenticator.ConflictOverwrite)
+	require.NoError(t, err)
+
+	buf.Reset()
+
+	err = authenticator.ExportNamespace("imported", buf)
+	require.NoError(t, err)
+
+	_, err = authenticator.ImportNamespace(buf)
+	require.NoError(t, err)
+
+	err = authenticator.SelfTest(context.Background())
+	require.NoError(t, err)
+
+	ids, err := authenticator.GetAllNamespaceIDs()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"created", "imported", "provider"}, ids)
+
+	entries, err := os.ReadDir(filepath.Dir(configFile))
+	require.NoError(t, err)
+	assert.Empty(t, entries, "the config file is not touched")
+}
+
+func TestSetDefaultConfigOptions_WithConfigFile(t *testing.T) {
+	setConfigFile(t)
+	useInMemoryStorage(t)
+
+	file := filepath.Join(t.TempDir(), "config.toml")
+
+	t.Cleanup(authenticator.SetDefaultConfigOptions(authenticator.WithConfigFile(file)))
+
+	_, err := authenticator.ImportAccounts("namespace", strings.NewReader(`{"accounts": [
+		{"name": "john.doe@example.com", "totp_secret": "NBSWY3DP"}
+	]}`), "json")
+	require.NoError(t, err)
+
+	_, err = os.Stat(os.Getenv("AUTHENTICATOR_CONFIG"))
+	require.ErrorIs(t, err, os.ErrNotExist)
+
+	ids, err := authenticator.GetAllNamespaceIDs(authenticator.WithConfigFile(file))
+	require.NoError(t, err)
+	assert.Equal(t, []string{"namespace"}, ids)
+}
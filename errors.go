@@ -0,0 +1,15 @@
+package authenticator
+
+// markedError keeps the message of the wrapped error while also matching the mark with errors.Is.
+type markedError struct {
+	mark error
+	err  error
+}
+
+func (e *markedError) Error() string {
+	return e.err.Error()
+}
+
+func (e *markedError) Unwrap() []error {
+	return []error{e.mark, e.err}
+}
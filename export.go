@@ -0,0 +1,433 @@
+package authenticator
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+
+	"go.uber.org/multierr"
+)
+
+// ConflictPolicy decides what to do when an imported account already exists.
+type ConflictPolicy int
+
+const (
+	// ConflictSkip keeps the existing account and skips the imported one.
+	ConflictSkip ConflictPolicy = iota
+	// ConflictOverwrite replaces the existing account with the imported one.
+	ConflictOverwrite
+)
+
+type importConfig struct {
+	conflictPolicy ConflictPolicy
+	defaultIssuer  string
+	dryRun         bool
+	report         *ImportReport
+	result         *ImportResult
+	skipInvalid    bool
+	// resultPrefix is prepended to the names in the result, e.g. the namespace when importing all of them.
+	resultPrefix string
+}
+
+// ImportReport tells what an import would do with each account, see WithDryRun.
+type ImportReport struct {
+	// Created are the accounts that do not exist yet.
+	Created []string
+	// Overwritten are the existing accounts that would be replaced.
+	Overwritten []string
+	// Skipped are the existing accounts that would be kept.
+	Skipped []string
+}
+
+// ImportResult tells which accounts an import stored and which entries it skipped. With WithDryRun, it tells which
+// accounts the import would store.
+type ImportResult struct {
+	// Imported are the accounts that were stored.
+	Imported []string
+	// Skipped are the entries that were not stored, with the reason.
+	Skipped []SkippedEntry
+}
+
+// SkippedEntry is an entry that an import did not store.
+type SkippedEntry struct {
+	Name   string
+	Reason string
+}
+
+// imported records that the account was stored.
+func (c importConfig) imported(name string) {
+	if c.result != nil {
+		c.result.Imported = append(c.result.Imported, c.resultPrefix+name)
+	}
+}
+
+// skipped records that the entry was not stored.
+func (c importConfig) skipped(name, reason string) {
+	if c.result != nil {
+		c.result.Skipped = append(c.result.Skipped, SkippedEntry{Name: c.resultPrefix + name, Reason: reason})
+	}
+}
+
+// validAccounts returns the accounts to import. Nothing is imported if any account is invalid, unless WithSkipInvalid
+// is set, then the invalid accounts are skipped and recorded in the result.
+func (c importConfig) validAccounts(accounts []Account) ([]Account, error) {
+	if !c.skipInvalid {
+		return accounts, validateImportedAccounts(accounts)
+	}
+
+	valid := make([]Account, 0, len(accounts))
+
+	for _, a := range accounts {
+		if err := a.Validate(); err != nil {
+			c.skipped(a.Name, err.Error())
+
+			continue
+		}
+
+		valid = append(valid, a)
+	}
+
+	return valid, nil
+}
+
+// preview reports what importing the account would do, without storing it.
+func (c importConfig) preview(namespace string, account Account, policy ConflictPolicy) error {
+	_, err := getAccount(namespace, account.Name)
+	if err != nil && !errors.Is(err, ErrAccountNotFound) {
+		return err
+	}
+
+	if err == nil && policy == ConflictSkip {
+		c.skipped(account.Name, "account already exists")
+	} else {
+		c.imported(account.Name)
+	}
+
+	if c.report == nil {
+		return nil
+	}
+
+	switch {
+	case err != nil:
+		c.report.Created = append(c.report.Created, account.Name)
+
+	case policy == ConflictSkip:
+		c.report.Skipped = append(c.report.Skipped, account.Name)
+
+	default:
+		c.report.Overwritten = append(c.report.Overwritten, account.Name)
+	}
+
+	return nil
+}
+
+// ImportOption is an option to configure the import.
+type ImportOption interface {
+	applyImportOption(cfg *importConfig)
+}
+
+type importOptionFunc func(cfg *importConfig)
+
+func (f importOptionFunc) applyImportOption(cfg *importConfig) {
+	f(cfg)
+}
+
+func newImportConfig(opts ...ImportOption) importConfig {
+	cfg := importConfig{result: &ImportResult{}}
+
+	for _, opt := range opts {
+		opt.applyImportOption(&cfg)
+	}
+
+	return cfg
+}
+
+// WithConflictPolicy sets what to do when an imported account already exists. Defaults to ConflictSkip. Only
+// ImportNamespace supports it, the other imports overwrite the existing accounts.
+func WithConflictPolicy(p ConflictPolicy) ImportOption {
+	return importOptionFunc(func(cfg *importConfig) {
+		cfg.conflictPolicy = p
+	})
+}
+
+// WithSkipInvalid skips the invalid accounts instead of failing the whole import. They are reported in the result with
+// the reason, e.g. to show a remediation list.
+func WithSkipInvalid() ImportOption {
+	return importOptionFunc(func(cfg *importConfig) {
+		cfg.skipInvalid = true
+	})
+}
+
+// currentExportVersion is the version of the document written by ExportAll.
+const currentExportVersion = 1
+
+// ErrUnsupportedExportVersion indicates that the document was written by a newer version of this package, or is not an
+// export of all the namespaces.
+var ErrUnsupportedExportVersion = errors.New("unsupported export version")
+
+// WithDryRun validates the imported accounts and detects the conflicts, but does not write anything. What the import
+// would do is written to the report, which may be nil.
+func WithDryRun(report *ImportReport) ImportOption {
+	return importOptionFunc(func(cfg *importConfig) {
+		cfg.dryRun = true
+		cfg.report = report
+	})
+}
+
+type exportedAccount Account
+
+type exportedNamespace struct {
+	ID       string            `json:"id"`
+	Name     string            `json:"name"`
+	Accounts []exportedAccount `json:"accounts"`
+}
+
+type exportedVault struct {
+	Version    int                 `json:"version"`
+	Namespaces []exportedNamespace `json:"namespaces"`
+}
+
+// ExportNamespace writes the namespace and all of its accounts to the writer as a JSON document. The accounts are sorted
+// by name so that the output is deterministic.
+//
+// The document contains the TOTP secrets in plain text.
+func ExportNamespace(namespace string, w io.Writer) error {
+	configMu.RLock()
+	defer configMu.RUnlock()
+
+	doc, err := exportNamespace(namespace)
+	if err != nil {
+		return err
+	}
+
+	if err := encodeExport(w, doc); err != nil {
+		return fmt.Errorf("failed to export namespace %s: %w", namespace, err)
+	}
+
+	return nil
+}
+
+func exportNamespace(namespace string) (exportedNamespace, error) {
+	n, err := getNamespace(namespace)
+	if err != nil {
+		return exportedNamespace{}, err
+	}
+
+	accounts, err := listAccounts(namespace)
+	if err != nil {
+		return exportedNamespace{}, err
+	}
+
+	doc := exportedNamespace{
+		ID:       namespace,
+		Name:     n.Name,
+		Accounts: make([]exportedAccount, 0, len(accounts)),
+	}
+
+	for _, a := range accounts {
+		doc.Accounts = append(doc.Accounts, exportedAccount(a))
+	}
+
+	return doc, nil
+}
+
+func encodeExport(w io.Writer, doc any) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "    ")
+
+	return enc.Encode(doc) //nolint: wrapcheck
+}
+
+// ImportNamespace reads a JSON document produced by ExportNamespace and recreates the namespace and its accounts. The
+// result tells which accounts were stored and which were skipped.
+func ImportNamespace(r io.Reader, opts ...ImportOption) (ImportResult, error) {
+	cfg := newImportConfig(opts...)
+
+	var doc exportedNamespace
+
+	if err := json.NewDecoder(r).Decode(&doc); err != nil {
+		return ImportResult{}, fmt.Errorf("failed to decode namespace: %w", err)
+	}
+
+	configMu.Lock()
+	defer configMu.Unlock()
+
+	err := importNamespace(doc, cfg)
+
+	return *cfg.result, err
+}
+
+func importNamespace(doc exportedNamespace, cfg importConfig) error {
+	accounts := make([]Account, len(doc.Accounts))
+
+	for i, a := range doc.Accounts {
+		accounts[i] = Account(a)
+
+		if accounts[i].Issuer == "" {
+			accounts[i].Issuer = cfg.defaultIssuer
+		}
+	}
+
+	accounts, err := cfg.validAccounts(accounts)
+	if err != nil {
+		return fmt.Errorf("failed to import namespace %s: %w", doc.ID, err)
+	}
+
+	if cfg.dryRun {
+		for _, a := range accounts {
+			if err := cfg.preview(doc.ID, a, cfg.conflictPolicy); err != nil {
+				return err
+			}
+		}
+
+		return nil
+	}
+
+	if err := createNamespace(newConfigOptions(), doc.ID, doc.Name); err != nil && !errors.Is(err, ErrNamespaceExists) {
+		return err
+	}
+
+	n, err := getNamespace(doc.ID)
+	if err != nil {
+		return fmt.Errorf("failed to get namespace %s for importing: %w", doc.ID, errors.Unwrap(err))
+	}
+
+	for _, a := range accounts {
+		var stored bool
+
+		if stored, err = importAccount(doc.ID, a, cfg.conflictPolicy); err != nil {
+			break
+		}
+
+		if stored {
+			cfg.imported(a.Name)
+
+			n.indexAccount(a.Name, a.Issuer)
+		} else {
+			cfg.skipped(a.Name, "account already exists")
+		}
+
+		if !slices.Contains(n.Accounts, a.Name) {
+			n.Accounts = append(n.Accounts, a.Name)
+		}
+	}
+
+	slices.Sort(n.Accounts)
+
+	return multierr.Combine(err, updateNamespace(doc.ID, n))
+}
+
+// ExportAll writes all the namespaces in the config and their accounts to the writer as a single JSON document, to be
+// restored with ImportAll.
+//
+// The document contains the TOTP secrets of every account in plain text, it must be stored as securely as the keyring
+// itself.
+func ExportAll(w io.Writer) error {
+	configMu.RLock()
+	defer configMu.RUnlock()
+
+	ids, err := loadNamespaceIDs(newConfigOptions())
+	if err != nil {
+		return err
+	}
+
+	doc := exportedVault{
+		Version:    currentExportVersion,
+		Namespaces: make([]exportedNamespace, 0, len(ids)),
+	}
+
+	for _, id := range ids {
+		n, err := exportNamespace(id)
+		if err != nil {
+			return err
+		}
+
+		doc.Namespaces = append(doc.Namespaces, n)
+	}
+
+	if err := encodeExport(w, doc); err != nil {
+		return fmt.Errorf("failed to export all namespaces: %w", err)
+	}
+
+	return nil
+}
+
+// ImportAll reads a JSON document produced by ExportAll and recreates all the namespaces and their accounts. The policy
+// decides what to do with the accounts that already exist, it overrides WithConflictPolicy. The accounts in the result
+// are recorded as namespace/account.
+func ImportAll(r io.Reader, policy ConflictPolicy, opts ...ImportOption) (ImportResult, error) {
+	var doc exportedVault
+
+	if err := json.NewDecoder(r).Decode(&doc); err != nil {
+		return ImportResult{}, fmt.Errorf("failed to decode export: %w", err)
+	}
+
+	if doc.Version < 1 || doc.Version > currentExportVersion {
+		return ImportResult{}, fmt.Errorf("failed to import all namespaces: %w: %d", ErrUnsupportedExportVersion, doc.Version)
+	}
+
+	configMu.Lock()
+	defer configMu.Unlock()
+
+	cfg := newImportConfig(opts...)
+	cfg.conflictPolicy = policy
+
+	// Validate every namespace before importing any of them, unless the invalid accounts are skipped.
+	if !cfg.skipInvalid {
+		if err := validateExportedNamespaces(doc.Namespaces); err != nil {
+			return ImportResult{}, err
+		}
+	}
+
+	for _, n := range doc.Namespaces {
+		nsCfg := cfg
+		nsCfg.resultPrefix = n.ID + "/"
+
+		if err := importNamespace(n, nsCfg); err != nil {
+			return *cfg.result, err
+		}
+	}
+
+	return *cfg.result, nil
+}
+
+func validateExportedNamespaces(namespaces []exportedNamespace) error {
+	var err error
+
+	for _, n := range namespaces {
+		accounts := make([]Account, len(n.Accounts))
+
+		for i, a := range n.Accounts {
+			accounts[i] = Account(a)
+		}
+
+		if vErr := validateImportedAccounts(accounts); vErr != nil {
+			err = multierr.Append(err, fmt.Errorf("failed to import namespace %s: %w", n.ID, vErr))
+		}
+	}
+
+	return err
+}
+
+// importAccount stores the account and tells whether it was stored, it is not when it exists and the policy is
+// ConflictSkip.
+func importAccount(namespace string, account Account, policy ConflictPolicy) (bool, error) {
+	if policy == ConflictSkip {
+		_, err := getAccount(namespace, account.Name)
+		if err == nil {
+			return false, nil
+		}
+
+		if !errors.Is(err, ErrAccountNotFound) {
+			return false, err
+		}
+	}
+
+	if err := setAccount(namespace, account); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
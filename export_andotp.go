@@ -0,0 +1,79 @@
+package authenticator
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+type andOTPEntry struct {
+	Secret    string   `json:"secret"`
+	Issuer    string   `json:"issuer"`
+	Label     string   `json:"label"`
+	Digits    int      `json:"digits"`
+	Type      string   `json:"type"`
+	Algorithm string   `json:"algorithm"`
+	Period    uint     `json:"period,omitempty"`
+	Counter   *uint64  `json:"counter,omitempty"`
+	Thumbnail string   `json:"thumbnail"`
+	Tags      []string `json:"tags"`
+}
+
+// ExportAndOTP writes all the accounts of the namespace to the writer in the andOTP JSON format, an array of entries
+// sorted by label. The parameters that are not set on an account are written with their defaults.
+//
+// The document contains the TOTP secrets in plain text, it must be stored securely.
+func ExportAndOTP(namespace string, w io.Writer) error {
+	configMu.RLock()
+	defer configMu.RUnlock()
+
+	if _, err := getNamespace(namespace); err != nil {
+		return err
+	}
+
+	accounts, err := listAccounts(namespace)
+	if err != nil {
+		return err
+	}
+
+	entries := make([]andOTPEntry, 0, len(accounts))
+
+	for _, a := range accounts {
+		c := &generateTOTPConfig{}
+
+		if err := c.withDefaults(a); err != nil {
+			return fmt.Errorf("failed to export account %s: %w", a.Name, err)
+		}
+
+		e := andOTPEntry{
+			Secret:    normalizeSecret(a.TOTPSecret).String(),
+			Issuer:    a.Issuer,
+			Label:     a.Name,
+			Digits:    c.digits,
+			Type:      strings.ToUpper(TypeTOTP),
+			Algorithm: strings.ToUpper(c.algorithm),
+			Thumbnail: "Default",
+			Tags:      []string{},
+		}
+
+		if a.Type == TypeHOTP {
+			e.Type = strings.ToUpper(TypeHOTP)
+			e.Counter = &a.Counter
+		} else {
+			e.Period = uint(c.period / time.Second)
+		}
+
+		entries = append(entries, e)
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "    ")
+
+	if err := enc.Encode(entries); err != nil {
+		return fmt.Errorf("failed to export namespace %s: %w", namespace, err)
+	}
+
+	return nil
+}
@@ -0,0 +1,146 @@
+package authenticator_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.nhat.io/otp"
+	"go.nhat.io/secretstorage"
+	mockss "go.nhat.io/secretstorage/mock"
+
+	"go.nhat.io/authenticator"
+)
+
+func TestExportAndOTP_Success(t *testing.T) {
+	mockExportedNamespace(t)
+
+	buf := new(bytes.Buffer)
+
+	err := authenticator.ExportAndOTP("namespace", buf)
+	require.NoError(t, err)
+
+	var actual []map[string]any
+
+	err = json.Unmarshal(buf.Bytes(), &actual)
+	require.NoError(t, err)
+
+	expected := []map[string]any{
+		{
+			"secret":    "JBSWY3DP",
+			"issuer":    "example.com",
+			"label":     "jane.doe@example.com",
+			"digits":    float64(8),
+			"type":      "TOTP",
+			"algorithm": "SHA256",
+			"period":    float64(30),
+			"thumbnail": "Default",
+			"tags":      []any{},
+		},
+		{
+			"secret":    "NBSWY3DP",
+			"issuer":    "example.com",
+			"label":     "john.doe@example.com",
+			"digits":    float64(6),
+			"type":      "TOTP",
+			"algorithm": "SHA1",
+			"period":    float64(30),
+			"thumbnail": "Default",
+			"tags":      []any{},
+		},
+	}
+
+	assert.Equal(t, expected, actual)
+}
+
+func TestExportAndOTP_HOTP(t *testing.T) {
+	setNamespaceStorage(t, func(s *mockss.Storage[authenticator.Namespace]) {
+		s.On("Get", "go.nhat.io/authenticator", "namespace").
+			Return(authenticator.Namespace{Name: "namespace", Accounts: []string{"token"}}, nil)
+	})
+
+	setAccountStorage(t, func(s *mockss.Storage[authenticator.Account]) {
+		s.On("Get", "go.nhat.io/authenticator", "namespace/token").
+			Return(authenticator.Account{
+				Name:       "token",
+				TOTPSecret: "nbswy3dp",
+				Type:       authenticator.TypeHOTP,
+			}, nil)
+	})
+
+	buf := new(bytes.Buffer)
+
+	err := authenticator.ExportAndOTP("namespace", buf)
+	require.NoError(t, err)
+
+	expected := `[
+    {
+        "secret": "NBSWY3DP",
+        "issuer": "",
+        "label": "token",
+        "digits": 6,
+        "type": "HOTP",
+        "algorithm": "SHA1",
+        "counter": 0,
+        "thumbnail": "Default",
+        "tags": []
+    }
+]
+`
+
+	assert.Equal(t, expected, buf.String())
+}
+
+func TestExportAndOTP_NormalizeSecret(t *testing.T) {
+	testCases := []struct {
+		scenario string
+		secret   string
+		expected string
+	}{
+		{scenario: "spaces", secret: "nbsw y3dp ", expected: "NBSWY3DP"},
+		{scenario: "hyphens", secret: "NBSW-Y3DP", expected: "NBSWY3DP"},
+		{scenario: "padding", secret: "JBSWY3DPEE======", expected: "JBSWY3DPEE"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.scenario, func(t *testing.T) {
+			setNamespaceStorage(t, func(s *mockss.Storage[authenticator.Namespace]) {
+				s.On("Get", "go.nhat.io/authenticator", "namespace").
+					Return(authenticator.Namespace{Name: "namespace", Accounts: []string{"john.doe@example.com"}}, nil)
+			})
+
+			setAccountStorage(t, func(s *mockss.Storage[authenticator.Account]) {
+				s.On("Get", "go.nhat.io/authenticator", "namespace/john.doe@example.com").
+					Return(authenticator.Account{Name: "john.doe@example.com", TOTPSecret: otp.TOTPSecret(tc.secret)}, nil)
+			})
+
+			buf := new(bytes.Buffer)
+
+			err := authenticator.ExportAndOTP("namespace", buf)
+			require.NoError(t, err)
+
+			var actual []map[string]any
+
+			err = json.Unmarshal(buf.Bytes(), &actual)
+			require.NoError(t, err)
+			require.Len(t, actual, 1)
+
+			assert.Equal(t, tc.expected, actual[0]["secret"])
+		})
+	}
+}
+
+func TestExportAndOTP_NamespaceNotFound(t *testing.T) {
+	setNamespaceStorage(t, func(s *mockss.Storage[authenticator.Namespace]) {
+		s.On("Get", "go.nhat.io/authenticator", "namespace").
+			Return(authenticator.Namespace{}, secretstorage.ErrNotFound)
+	})
+
+	buf := new(bytes.Buffer)
+
+	err := authenticator.ExportAndOTP("namespace", buf)
+	require.ErrorIs(t, err, authenticator.ErrNamespaceNotFound)
+	assert.Empty(t, buf.String())
+}
@@ -0,0 +1,515 @@
+package authenticator_test
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.nhat.io/otp"
+	"go.nhat.io/secretstorage"
+	mockss "go.nhat.io/secretstorage/mock"
+
+	"go.nhat.io/authenticator"
+)
+
+var (
+	exportedCreatedAt = time.Date(2023, 12, 1, 0, 0, 0, 0, time.UTC)
+	exportedUpdatedAt = time.Date(2023, 12, 15, 0, 0, 0, 0, time.UTC)
+)
+
+const exportedNamespace = `{
+    "id": "namespace",
+    "name": "My Namespace",
+    "accounts": [
+        {
+            "name": "jane.doe@example.com",
+            "totp_secret": "JBSWY3DP",
+            "issuer": "example.com",
+            "type": "",
+            "counter": 0,
+            "digits": 8,
+            "algorithm": "SHA256",
+            "period": 0,
+            "metadata": {
+                "env": "prod"
+            },
+            "created_at": "2023-12-01T00:00:00Z",
+            "updated_at": "2023-12-15T00:00:00Z"
+        },
+        {
+            "name": "john.doe@example.com",
+            "totp_secret": "NBSWY3DP",
+            "issuer": "example.com",
+            "type": "",
+            "counter": 0,
+            "digits": 0,
+            "algorithm": "",
+            "period": 0,
+            "metadata": null,
+            "created_at": "2023-12-01T00:00:00Z",
+            "updated_at": "2023-12-01T00:00:00Z"
+        }
+    ]
+}
+`
+
+func mockExportedNamespace(t *testing.T) {
+	t.Helper()
+
+	setNamespaceStorage(t, func(s *mockss.Storage[authenticator.Namespace]) {
+		s.On("Get", "go.nhat.io/authenticator", "namespace").
+			Return(authenticator.Namespace{
+				Name:     "My Namespace",
+				Accounts: []string{"john.doe@example.com", "jane.doe@example.com"},
+			}, nil)
+	})
+
+	setAccountStorage(t, func(s *mockss.Storage[authenticator.Account]) {
+		s.On("Get", "go.nhat.io/authenticator", "namespace/john.doe@example.com").
+			Return(authenticator.Account{
+				Name:       "john.doe@example.com",
+				TOTPSecret: "NBSWY3DP",
+				Issuer:     "example.com",
+				CreatedAt:  exportedCreatedAt,
+				UpdatedAt:  exportedCreatedAt,
+			}, nil)
+
+		s.On("Get", "go.nhat.io/authenticator", "namespace/jane.doe@example.com").
+			Return(authenticator.Account{
+				Name:       "jane.doe@example.com",
+				TOTPSecret: "JBSWY3DP",
+				Issuer:     "example.com",
+				Digits:     8,
+				Algorithm:  authenticator.AlgorithmSHA256,
+				Metadata:   map[string]any{"env": "prod"},
+				CreatedAt:  exportedCreatedAt,
+				UpdatedAt:  exportedUpdatedAt,
+			}, nil)
+	})
+}
+
+func TestExportNamespace_Success(t *testing.T) {
+	mockExportedNamespace(t)
+
+	buf := new(bytes.Buffer)
+
+	err := authenticator.ExportNamespace("namespace", buf)
+	require.NoError(t, err)
+
+	assert.Equal(t, exportedNamespace, buf.String())
+}
+
+func TestExportNamespace_NamespaceNotFound(t *testing.T) {
+	setNamespaceStorage(t, func(s *mockss.Storage[authenticator.Namespace]) {
+		s.On("Get", "go.nhat.io/authenticator", "namespace").
+			Return(authenticator.Namespace{}, secretstorage.ErrNotFound)
+	})
+
+	buf := new(bytes.Buffer)
+
+	err := authenticator.ExportNamespace("namespace", buf)
+	require.ErrorIs(t, err, authenticator.ErrNamespaceNotFound)
+	assert.Empty(t, buf.String())
+}
+
+func TestExportNamespace_FailedToWrite(t *testing.T) {
+	mockExportedNamespace(t)
+
+	w := writerFunc(func([]byte) (int, error) {
+		return 0, assert.AnError
+	})
+
+	err := authenticator.ExportNamespace("namespace", w)
+	require.EqualError(t, err, `failed to export namespace namespace: assert.AnError general error for testing`)
+}
+
+func TestExportImportNamespace_RoundTrip(t *testing.T) {
+	setConfigFile(t)
+	setAccountClock(t)
+	mockExportedNamespace(t)
+
+	buf := new(bytes.Buffer)
+
+	err := authenticator.ExportNamespace("namespace", buf)
+	require.NoError(t, err)
+
+	setNamespaceStorage(t, func(s *mockss.Storage[authenticator.Namespace]) {
+		s.On("Get", "go.nhat.io/authenticator", "namespace").Once().
+			Return(authenticator.Namespace{}, secretstorage.ErrNotFound)
+
+		s.On("Set", "go.nhat.io/authenticator", "namespace", authenticator.Namespace{Name: "My Namespace"}).Once().
+			Return(nil)
+
+		s.On("Get", "go.nhat.io/authenticator", "namespace").Once().
+			Return(authenticator.Namespace{Name: "My Namespace"}, nil)
+
+		s.On("Set", "go.nhat.io/authenticator", "namespace", authenticator.Namespace{
+			Name:     "My Namespace",
+			Accounts: []string{"jane.doe@example.com", "john.doe@example.com"},
+			Issuers:  map[string][]string{"example.com": {"jane.doe@example.com", "john.doe@example.com"}},
+		}).Once().
+			Return(nil)
+	})
+
+	setAccountStorage(t, func(s *mockss.Storage[authenticator.Account]) {
+		s.On("Get", "go.nhat.io/authenticator", "namespace/jane.doe@example.com").
+			Return(authenticator.Account{}, secretstorage.ErrNotFound)
+
+		s.On("Get", "go.nhat.io/authenticator", "namespace/john.doe@example.com").
+			Return(authenticator.Account{}, secretstorage.ErrNotFound)
+
+		s.On("Set", "go.nhat.io/authenticator", "namespace/jane.doe@example.com", authenticator.Account{
+			Name:       "jane.doe@example.com",
+			TOTPSecret: "JBSWY3DP",
+			Issuer:     "example.com",
+			Digits:     8,
+			Algorithm:  authenticator.AlgorithmSHA256,
+			Metadata:   map[string]any{"env": "prod"},
+			CreatedAt:  exportedCreatedAt,
+			UpdatedAt:  accountTime,
+		}).
+			Return(nil)
+
+		s.On("Set", "go.nhat.io/authenticator", "namespace/john.doe@example.com", authenticator.Account{
+			Name:       "john.doe@example.com",
+			TOTPSecret: "NBSWY3DP",
+			Issuer:     "example.com",
+			CreatedAt:  exportedCreatedAt,
+			UpdatedAt:  accountTime,
+		}).
+			Return(nil)
+	})
+
+	_, err = authenticator.ImportNamespace(buf)
+	require.NoError(t, err)
+}
+
+func TestImportNamespace_SkipExisting(t *testing.T) {
+	setConfigFileWithContent(t, `namespaces = ["namespace"]`)
+
+	setNamespaceStorage(t, func(s *mockss.Storage[authenticator.Namespace]) {
+		s.On("Get", "go.nhat.io/authenticator", "namespace").
+			Return(authenticator.Namespace{Name: "My Namespace", Accounts: []string{"john.doe@example.com"}}, nil)
+
+		s.On("Set", "go.nhat.io/authenticator", "namespace", authenticator.Namespace{
+			Name:     "My Namespace",
+			Accounts: []string{"jane.doe@example.com", "john.doe@example.com"},
+		}).Once().
+			Return(nil)
+	})
+
+	setAccountStorage(t, func(s *mockss.Storage[authenticator.Account]) {
+		s.On("Get", "go.nhat.io/authenticator", "namespace/jane.doe@example.com").
+			Return(authenticator.Account{}, secretstorage.ErrNotFound)
+
+		s.On("Get", "go.nhat.io/authenticator", "namespace/john.doe@example.com").
+			Return(authenticator.Account{Name: "john.doe@example.com", TOTPSecret: "KBSWY3DP"}, nil)
+
+		s.On("Set", "go.nhat.io/authenticator", "namespace/jane.doe@example.com", mockss.Anything).
+			Return(nil)
+	})
+
+	_, err := authenticator.ImportNamespace(strings.NewReader(exportedNamespace))
+	require.NoError(t, err)
+}
+
+func TestImportNamespace_Result(t *testing.T) {
+	setConfigFileWithContent(t, `namespaces = ["namespace"]`)
+
+	setNamespaceStorage(t, func(s *mockss.Storage[authenticator.Namespace]) {
+		s.On("Get", "go.nhat.io/authenticator", "namespace").
+			Return(authenticator.Namespace{Name: "My Namespace", Accounts: []string{"john.doe@example.com"}}, nil)
+
+		s.On("Set", "go.nhat.io/authenticator", "namespace", mockss.Anything).Once().
+			Return(nil)
+	})
+
+	setAccountStorage(t, func(s *mockss.Storage[authenticator.Account]) {
+		s.On("Get", "go.nhat.io/authenticator", "namespace/jane.doe@example.com").
+			Return(authenticator.Account{}, secretstorage.ErrNotFound)
+
+		s.On("Get", "go.nhat.io/authenticator", "namespace/john.doe@example.com").
+			Return(authenticator.Account{Name: "john.doe@example.com", TOTPSecret: "KBSWY3DP"}, nil)
+
+		s.On("Set", "go.nhat.io/authenticator", "namespace/jane.doe@example.com", mockss.Anything).
+			Return(nil)
+	})
+
+	result, err := authenticator.ImportNamespace(strings.NewReader(exportedNamespace))
+	require.NoError(t, err)
+
+	expected := authenticator.ImportResult{
+		Imported: []string{"jane.doe@example.com"},
+		Skipped:  []authenticator.SkippedEntry{{Name: "john.doe@example.com", Reason: "account already exists"}},
+	}
+
+	assert.Equal(t, expected, result)
+}
+
+func TestImportNamespace_OverwriteExisting(t *testing.T) {
+	setConfigFileWithContent(t, `namespaces = ["namespace"]`)
+	setAccountClock(t)
+
+	setNamespaceStorage(t, func(s *mockss.Storage[authenticator.Namespace]) {
+		s.On("Get", "go.nhat.io/authenticator", "namespace").
+			Return(authenticator.Namespace{Name: "My Namespace", Accounts: []string{"john.doe@example.com"}}, nil)
+
+		s.On("Set", "go.nhat.io/authenticator", "namespace", authenticator.Namespace{
+			Name:     "My Namespace",
+			Accounts: []string{"jane.doe@example.com", "john.doe@example.com"},
+		}).Once().
+			Return(nil)
+	})
+
+	setAccountStorage(t, func(s *mockss.Storage[authenticator.Account]) {
+		s.On("Set", "go.nhat.io/authenticator", "namespace/jane.doe@example.com", mockss.Anything).
+			Return(nil)
+
+		s.On("Set", "go.nhat.io/authenticator", "namespace/john.doe@example.com", authenticator.Account{
+			Name:       "john.doe@example.com",
+			TOTPSecret: "NBSWY3DP",
+			Issuer:     "example.com",
+			CreatedAt:  exportedCreatedAt,
+			UpdatedAt:  accountTime,
+		}).
+			Return(nil)
+	})
+
+	_, err := authenticator.ImportNamespace(strings.NewReader(exportedNamespace),
+		authenticator.WithConflictPolicy(authenticator.ConflictOverwrite),
+	)
+	require.NoError(t, err)
+}
+
+func TestImportNamespace_DryRun(t *testing.T) {
+	testCases := []struct {
+		scenario       string
+		policy         authenticator.ConflictPolicy
+		expectedReport authenticator.ImportReport
+	}{
+		{
+			scenario: "skip",
+			policy:   authenticator.ConflictSkip,
+			expectedReport: authenticator.ImportReport{
+				Created: []string{"jane.doe@example.com"},
+				Skipped: []string{"john.doe@example.com"},
+			},
+		},
+		{
+			scenario: "overwrite",
+			policy:   authenticator.ConflictOverwrite,
+			expectedReport: authenticator.ImportReport{
+				Created:     []string{"jane.doe@example.com"},
+				Overwritten: []string{"john.doe@example.com"},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.scenario, func(t *testing.T) {
+			setConfigFileWithContent(t, `namespaces = ["namespace"]`)
+
+			// No write is expected.
+			setNamespaceStorage(t, func(*mockss.Storage[authenticator.Namespace]) {})
+
+			setAccountStorage(t, func(s *mockss.Storage[authenticator.Account]) {
+				s.On("Get", "go.nhat.io/authenticator", "namespace/jane.doe@example.com").
+					Return(authenticator.Account{}, secretstorage.ErrNotFound)
+
+				s.On("Get", "go.nhat.io/authenticator", "namespace/john.doe@example.com").
+					Return(authenticator.Account{Name: "john.doe@example.com", TOTPSecret: "KBSWY3DP"}, nil)
+			})
+
+			var report authenticator.ImportReport
+
+			_, err := authenticator.ImportNamespace(strings.NewReader(exportedNamespace),
+				authenticator.WithConflictPolicy(tc.policy),
+				authenticator.WithDryRun(&report),
+			)
+			require.NoError(t, err)
+
+			assert.Equal(t, tc.expectedReport, report)
+		})
+	}
+}
+
+func TestImportNamespace_FailedToSetAccount(t *testing.T) {
+	setConfigFileWithContent(t, `namespaces = ["namespace"]`)
+
+	setNamespaceStorage(t, func(s *mockss.Storage[authenticator.Namespace]) {
+		s.On("Get", "go.nhat.io/authenticator", "namespace").
+			Return(authenticator.Namespace{Name: "My Namespace"}, nil)
+
+		s.On("Set", "go.nhat.io/authenticator", "namespace", authenticator.Namespace{
+			Name:     "My Namespace",
+			Accounts: []string{"jane.doe@example.com"},
+			Issuers:  map[string][]string{"example.com": {"jane.doe@example.com"}},
+		}).Once().
+			Return(nil)
+	})
+
+	setAccountStorage(t, func(s *mockss.Storage[authenticator.Account]) {
+		s.On("Set", "go.nhat.io/authenticator", "namespace/jane.doe@example.com", mockss.Anything).
+			Return(nil)
+
+		s.On("Set", "go.nhat.io/authenticator", "namespace/john.doe@example.com", mockss.Anything).
+			Return(assert.AnError)
+	})
+
+	_, err := authenticator.ImportNamespace(strings.NewReader(exportedNamespace),
+		authenticator.WithConflictPolicy(authenticator.ConflictOverwrite),
+	)
+	require.EqualError(t, err, `failed to store account john.doe@example.com in namespace namespace: assert.AnError general error for testing`)
+}
+
+func TestImportNamespace_InvalidDocument(t *testing.T) {
+	t.Parallel()
+
+	_, err := authenticator.ImportNamespace(strings.NewReader(`{`))
+	require.EqualError(t, err, `failed to decode namespace: unexpected EOF`)
+}
+
+func TestExportImportAll_RoundTrip(t *testing.T) {
+	setConfigFile(t)
+	setAccountClock(t)
+
+	useInMemoryStorage(t)
+
+	err := authenticator.CreateNamespace("personal", "Personal")
+	require.NoError(t, err)
+
+	err = authenticator.CreateNamespace("work", "Work")
+	require.NoError(t, err)
+
+	err = authenticator.SetAccount("personal", authenticator.Account{Name: "john.doe@example.com", TOTPSecret: "NBSWY3DP", Issuer: "example.com"})
+	require.NoError(t, err)
+
+	err = authenticator.SetAccount("work", authenticator.Account{Name: "jane.doe@example.com", TOTPSecret: "JBSWY3DP", Digits: 8})
+	require.NoError(t, err)
+
+	expectedPersonal, err := authenticator.ListAccounts("personal")
+	require.NoError(t, err)
+
+	expectedWork, err := authenticator.ListAccounts("work")
+	require.NoError(t, err)
+
+	buf := new(bytes.Buffer)
+
+	err = authenticator.ExportAll(buf)
+	require.NoError(t, err)
+
+	err = authenticator.DeleteAllNamespaces()
+	require.NoError(t, err)
+
+	_, err = authenticator.ImportAll(buf, authenticator.ConflictSkip)
+	require.NoError(t, err)
+
+	ids, err := authenticator.GetAllNamespaceIDs()
+	require.NoError(t, err)
+
+	assert.Equal(t, []string{"personal", "work"}, ids)
+
+	n, err := authenticator.GetNamespace("work")
+	require.NoError(t, err)
+
+	assert.Equal(t, "Work", n.Name)
+
+	actual, err := authenticator.ListAccounts("personal")
+	require.NoError(t, err)
+
+	assert.Equal(t, expectedPersonal, actual)
+
+	actual, err = authenticator.ListAccounts("work")
+	require.NoError(t, err)
+
+	assert.Equal(t, expectedWork, actual)
+}
+
+func TestImportAll_WithSkipInvalid(t *testing.T) {
+	setConfigFile(t)
+	setAccountClock(t)
+
+	useInMemoryStorage(t)
+
+	err := authenticator.CreateNamespace("personal", "Personal")
+	require.NoError(t, err)
+
+	err = authenticator.SetAccount("personal", authenticator.Account{Name: "john.doe@example.com", TOTPSecret: "NBSWY3DP"})
+	require.NoError(t, err)
+
+	// The invalid account is skipped instead of failing the whole import.
+	document := `{"version": 1, "namespaces": [
+		{"id": "personal", "name": "Personal", "accounts": [{"name": "john.doe@example.com", "totp_secret": "JBSWY3DP"}]},
+		{"id": "work", "name": "Work", "accounts": [
+			{"name": "jane.doe@example.com", "totp_secret": "secret!"},
+			{"name": "john.doe@example.com", "totp_secret": "JBSWY3DP"}
+		]}
+	]}`
+
+	result, err := authenticator.ImportAll(strings.NewReader(document), authenticator.ConflictSkip, authenticator.WithSkipInvalid())
+	require.NoError(t, err)
+
+	expected := authenticator.ImportResult{
+		Imported: []string{"work/john.doe@example.com"},
+		Skipped: []authenticator.SkippedEntry{
+			{Name: "personal/john.doe@example.com", Reason: "account already exists"},
+			{Name: "work/jane.doe@example.com", Reason: "invalid totp secret: illegal base32 data at input byte 6"},
+		},
+	}
+
+	assert.Equal(t, expected, result)
+
+	actual, err := authenticator.GetAccount("personal", "john.doe@example.com")
+	require.NoError(t, err)
+	assert.Equal(t, otp.TOTPSecret("NBSWY3DP"), actual.TOTPSecret)
+
+	accounts, err := authenticator.ListAccounts("work")
+	require.NoError(t, err)
+	require.Len(t, accounts, 1)
+	assert.Equal(t, "john.doe@example.com", accounts[0].Name)
+}
+
+func TestImportAll_UnsupportedVersion(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		scenario string
+		document string
+	}{
+		{
+			scenario: "missing",
+			document: `{"namespaces": []}`,
+		},
+		{
+			scenario: "newer",
+			document: `{"version": 2, "namespaces": []}`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.scenario, func(t *testing.T) {
+			t.Parallel()
+
+			_, err := authenticator.ImportAll(strings.NewReader(tc.document), authenticator.ConflictSkip)
+			require.ErrorIs(t, err, authenticator.ErrUnsupportedExportVersion)
+		})
+	}
+}
+
+func TestImportAll_InvalidAccounts(t *testing.T) {
+	// Nothing is imported when any account is invalid, even in another namespace.
+	setNamespaceStorage(t, func(*mockss.Storage[authenticator.Namespace]) {})
+	setAccountStorage(t, func(*mockss.Storage[authenticator.Account]) {})
+
+	document := `{"version": 1, "namespaces": [
+		{"id": "valid", "name": "Valid", "accounts": [{"name": "john.doe@example.com", "totp_secret": "NBSWY3DP"}]},
+		{"id": "invalid", "name": "Invalid", "accounts": [{"name": "jane.doe@example.com", "totp_secret": "secret!"}]}
+	]}`
+
+	_, err := authenticator.ImportAll(strings.NewReader(document), authenticator.ConflictOverwrite)
+	require.ErrorIs(t, err, authenticator.ErrInvalidSecret)
+	require.EqualError(t, err, `failed to import namespace invalid: failed to import account #0 "jane.doe@example.com": invalid totp secret: illegal base32 data at input byte 6`)
+}
@@ -1,16 +1,23 @@
 module go.nhat.io/authenticator
 
-go 1.22
+go 1.22.3
 
 require (
+	github.com/HugoSmits86/nativewebp v1.3.0
 	github.com/bool64/ctxd v1.2.1
+	github.com/jdeng/goheif v0.1.2
 	github.com/makiuchi-d/gozxing v0.1.1
 	github.com/pelletier/go-toml/v2 v2.2.3
+	github.com/pquerna/otp v1.4.0
 	github.com/stretchr/testify v1.10.0
 	go.nhat.io/clock v0.7.0
 	go.nhat.io/otp v0.10.0
 	go.nhat.io/secretstorage v0.5.0
 	go.uber.org/multierr v1.11.0
+	golang.org/x/crypto v0.31.0
+	golang.org/x/image v0.24.0
+	golang.org/x/sys v0.28.0
+	google.golang.org/protobuf v1.34.2
 )
 
 require (
@@ -20,11 +27,9 @@ require (
 	github.com/davecgh/go-spew v1.1.1 // indirect
 	github.com/godbus/dbus/v5 v5.1.0 // indirect
 	github.com/pmezard/go-difflib v1.0.0 // indirect
-	github.com/pquerna/otp v1.4.0 // indirect
 	github.com/stretchr/objx v0.5.2 // indirect
 	github.com/zalando/go-keyring v0.2.6 // indirect
-	golang.org/x/sys v0.26.0 // indirect
-	golang.org/x/text v0.19.0 // indirect
+	golang.org/x/text v0.22.0 // indirect
 	golang.org/x/xerrors v0.0.0-20240903120638-7835f813f4da // indirect
 	gopkg.in/yaml.v3 v3.0.1 // indirect
 )
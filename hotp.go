@@ -0,0 +1,104 @@
+package authenticator
+
+import (
+	"context"
+	"crypto/subtle"
+	"fmt"
+
+	"github.com/pquerna/otp/hotp"
+	"go.nhat.io/otp"
+)
+
+// GenerateHOTP generates a HOTP code for the given account and counter.
+func GenerateHOTP(ctx context.Context, namespace, account string, counter uint64, opts ...GenerateTOTPOption) (otp.OTP, error) {
+	c, err := newGenerateTOTPConfig(opts...)
+	if err != nil {
+		return "", err
+	}
+
+	secret, err := c.totpSecret(ctx, namespace, account)
+	if err != nil {
+		return "", err
+	}
+
+	return generateHOTP(secret, counter, c.hotpOpts())
+}
+
+// GenerateHOTPNext generates a HOTP code using the counter stored in the account, then increments and persists the
+// counter.
+func GenerateHOTPNext(_ context.Context, namespace, account string) (otp.OTP, error) {
+	configMu.Lock()
+	defer configMu.Unlock()
+
+	a, err := getAccount(namespace, account)
+	if err != nil {
+		return "", err
+	}
+
+	c := &generateTOTPConfig{}
+
+	if err := c.withDefaults(a); err != nil {
+		return "", err
+	}
+
+	code, err := generateHOTP(a.TOTPSecret, a.Counter, c.hotpOpts())
+	if err != nil {
+		return "", err
+	}
+
+	a.Counter++
+
+	if err := setAccount(namespace, a); err != nil {
+		return "", err
+	}
+
+	return code, nil
+}
+
+// ResyncHOTP resynchronizes the counter of a HOTP account that drifted, e.g. when the button of a hardware token is
+// pressed without logging in. It looks for the code from the stored counter up to lookAhead counters ahead. When the
+// code matches, the counter is set to the one after the match. It returns false without an error if there is no match.
+func ResyncHOTP(_ context.Context, namespace, account string, code string, lookAhead int) (bool, error) {
+	if lookAhead < 0 {
+		return false, fmt.Errorf("%w: %d ahead, must not be negative", ErrInvalidWindow, lookAhead)
+	}
+
+	configMu.Lock()
+	defer configMu.Unlock()
+
+	a, err := getAccount(namespace, account)
+	if err != nil {
+		return false, err
+	}
+
+	c := &generateTOTPConfig{}
+
+	if err := c.withDefaults(a); err != nil {
+		return false, err
+	}
+
+	for i := range uint64(lookAhead) + 1 {
+		expected, err := generateHOTP(a.TOTPSecret, a.Counter+i, c.hotpOpts())
+		if err != nil {
+			return false, err
+		}
+
+		if subtle.ConstantTimeCompare([]byte(expected), []byte(code)) != 1 {
+			continue
+		}
+
+		a.Counter += i + 1
+
+		if err := setAccount(namespace, a); err != nil {
+			return false, err
+		}
+
+		return true, nil
+	}
+
+	return false, nil
+}
+
+func generateHOTP(secret otp.TOTPSecret, counter uint64, opts hotp.ValidateOpts) (otp.OTP, error) {
+	return generateCode(normalizeSecret(secret), counter, opts)
+}
@@ -0,0 +1,273 @@
+package authenticator_test
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.nhat.io/otp"
+	"go.nhat.io/secretstorage"
+	mockss "go.nhat.io/secretstorage/mock"
+
+	"go.nhat.io/authenticator"
+)
+
+// rfc4226Secret is the base32 encoded secret from the RFC 4226 test vectors.
+const rfc4226Secret = otp.TOTPSecret("GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ")
+
+func TestGenerateHOTP_Success_FromTOTPSecret(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		counter  uint64
+		expected otp.OTP
+	}{
+		{counter: 0, expected: "755224"},
+		{counter: 1, expected: "287082"},
+		{counter: 5, expected: "254676"},
+		{counter: 9, expected: "520489"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(fmt.Sprintf("counter %d", tc.counter), func(t *testing.T) {
+			t.Parallel()
+
+			actual, err := authenticator.GenerateHOTP(context.Background(), t.Name(), "john.doe@example.com", tc.counter,
+				authenticator.WithTOTPSecret(rfc4226Secret),
+			)
+			require.NoError(t, err)
+
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+}
+
+func TestGenerateHOTP_Success_WithDigits(t *testing.T) {
+	t.Parallel()
+
+	actual, err := authenticator.GenerateHOTP(context.Background(), t.Name(), "john.doe@example.com", 0,
+		authenticator.WithTOTPSecret(rfc4226Secret),
+		authenticator.WithDigits(8),
+	)
+	require.NoError(t, err)
+
+	assert.Equal(t, otp.OTP("84755224"), actual)
+}
+
+func TestGenerateHOTP_Failure_InvalidDigits(t *testing.T) {
+	t.Parallel()
+
+	actual, err := authenticator.GenerateHOTP(context.Background(), t.Name(), "john.doe@example.com", 0,
+		authenticator.WithTOTPSecret(rfc4226Secret),
+		authenticator.WithDigits(4),
+	)
+	require.ErrorIs(t, err, authenticator.ErrInvalidDigits)
+	assert.Empty(t, actual)
+}
+
+func TestGenerateHOTPNext_Success_WithAccountParameters(t *testing.T) {
+	setAccountStorage(t, func(s *mockss.Storage[authenticator.Account]) {
+		s.On("Get", "go.nhat.io/authenticator", fmt.Sprintf("%s/%s", t.Name(), "john.doe@example.com")).
+			Return(authenticator.Account{
+				Name:       "john.doe@example.com",
+				TOTPSecret: rfc4226Secret,
+				Type:       authenticator.TypeHOTP,
+				Digits:     8,
+				Algorithm:  authenticator.AlgorithmSHA1,
+			}, nil)
+
+		s.On("Set", "go.nhat.io/authenticator", fmt.Sprintf("%s/%s", t.Name(), "john.doe@example.com"), mockss.Anything).
+			Return(nil)
+	})
+
+	actual, err := authenticator.GenerateHOTPNext(context.Background(), t.Name(), "john.doe@example.com")
+	require.NoError(t, err)
+
+	assert.Equal(t, otp.OTP("84755224"), actual)
+}
+
+func TestGenerateHOTPNext_UnsupportedAlgorithm(t *testing.T) {
+	setAccountStorage(t, func(s *mockss.Storage[authenticator.Account]) {
+		s.On("Get", "go.nhat.io/authenticator", fmt.Sprintf("%s/%s", t.Name(), "john.doe@example.com")).
+			Return(authenticator.Account{
+				Name:       "john.doe@example.com",
+				TOTPSecret: rfc4226Secret,
+				Algorithm:  "MD5",
+			}, nil)
+	})
+
+	actual, err := authenticator.GenerateHOTPNext(context.Background(), t.Name(), "john.doe@example.com")
+	require.ErrorIs(t, err, authenticator.ErrUnsupportedAlgorithm)
+	assert.Empty(t, actual)
+}
+
+func TestGenerateHOTP_Failure_NoSecret(t *testing.T) {
+	setAccountStorage(t, func(s *mockss.Storage[authenticator.Account]) {
+		s.On("Get", "go.nhat.io/authenticator", fmt.Sprintf("%s/%s", t.Name(), "john.doe@example.com")).
+			Return(authenticator.Account{}, secretstorage.ErrNotFound)
+	})
+
+	actual, err := authenticator.GenerateHOTP(context.Background(), t.Name(), "john.doe@example.com", 0)
+	require.EqualError(t, err, `could not generate otp: no totp secret`)
+	assert.Empty(t, actual)
+}
+
+func TestGenerateHOTP_Failure_InvalidSecret(t *testing.T) {
+	t.Parallel()
+
+	actual, err := authenticator.GenerateHOTP(context.Background(), t.Name(), "john.doe@example.com", 0,
+		authenticator.WithTOTPSecret("secret"),
+	)
+	require.EqualError(t, err, `could not generate otp: Decoding of secret as base32 failed.`)
+	assert.Empty(t, actual)
+}
+
+func TestGenerateHOTPNext_Success(t *testing.T) {
+	setAccountClock(t)
+
+	createdAt := accountTime.Add(-time.Hour)
+
+	setAccountStorage(t, func(s *mockss.Storage[authenticator.Account]) {
+		s.On("Get", "go.nhat.io/authenticator", fmt.Sprintf("%s/%s", t.Name(), "john.doe@example.com")).
+			Return(authenticator.Account{
+				Name:       "john.doe@example.com",
+				TOTPSecret: rfc4226Secret,
+				Type:       authenticator.TypeHOTP,
+				Counter:    5,
+				CreatedAt:  createdAt,
+				UpdatedAt:  createdAt,
+			}, nil)
+
+		s.On("Set", "go.nhat.io/authenticator", fmt.Sprintf("%s/%s", t.Name(), "john.doe@example.com"),
+			authenticator.Account{
+				Name:       "john.doe@example.com",
+				TOTPSecret: rfc4226Secret,
+				Type:       authenticator.TypeHOTP,
+				Counter:    6,
+				CreatedAt:  createdAt,
+				UpdatedAt:  accountTime,
+			}).
+			Return(nil)
+	})
+
+	actual, err := authenticator.GenerateHOTPNext(context.Background(), t.Name(), "john.doe@example.com")
+	require.NoError(t, err)
+
+	assert.Equal(t, otp.OTP("254676"), actual)
+}
+
+func TestGenerateHOTPNext_AccountNotFound(t *testing.T) {
+	setAccountStorage(t, func(s *mockss.Storage[authenticator.Account]) {
+		s.On("Get", "go.nhat.io/authenticator", fmt.Sprintf("%s/%s", t.Name(), "john.doe@example.com")).
+			Return(authenticator.Account{}, secretstorage.ErrNotFound)
+	})
+
+	actual, err := authenticator.GenerateHOTPNext(context.Background(), t.Name(), "john.doe@example.com")
+	require.ErrorIs(t, err, authenticator.ErrAccountNotFound)
+	assert.Empty(t, actual)
+}
+
+func TestGenerateHOTPNext_InvalidSecret(t *testing.T) {
+	setAccountStorage(t, func(s *mockss.Storage[authenticator.Account]) {
+		s.On("Get", "go.nhat.io/authenticator", fmt.Sprintf("%s/%s", t.Name(), "john.doe@example.com")).
+			Return(authenticator.Account{
+				Name:       "john.doe@example.com",
+				TOTPSecret: "secret",
+			}, nil)
+	})
+
+	actual, err := authenticator.GenerateHOTPNext(context.Background(), t.Name(), "john.doe@example.com")
+	require.EqualError(t, err, `could not generate otp: Decoding of secret as base32 failed.`)
+	assert.Empty(t, actual)
+}
+
+func TestGenerateHOTPNext_FailedToSet(t *testing.T) {
+	setAccountStorage(t, func(s *mockss.Storage[authenticator.Account]) {
+		s.On("Get", "go.nhat.io/authenticator", fmt.Sprintf("%s/%s", t.Name(), "john.doe@example.com")).
+			Return(authenticator.Account{
+				Name:       "john.doe@example.com",
+				TOTPSecret: rfc4226Secret,
+			}, nil)
+
+		s.On("Set", "go.nhat.io/authenticator", fmt.Sprintf("%s/%s", t.Name(), "john.doe@example.com"), mockss.Anything).
+			Return(assert.AnError)
+	})
+
+	actual, err := authenticator.GenerateHOTPNext(context.Background(), t.Name(), "john.doe@example.com")
+	require.EqualError(t, err, `failed to store account john.doe@example.com in namespace TestGenerateHOTPNext_FailedToSet: assert.AnError general error for testing`)
+	assert.Empty(t, actual)
+}
+
+func TestResyncHOTP(t *testing.T) {
+	setAccountClock(t)
+
+	testCases := []struct {
+		scenario        string
+		code            string
+		lookAhead       int
+		expectedCounter uint64
+		expectedResult  bool
+	}{
+		{
+			scenario:        "match ahead",
+			code:            "254676",
+			lookAhead:       5,
+			expectedCounter: 6,
+			expectedResult:  true,
+		},
+		{
+			scenario:        "match at counter",
+			code:            "359152",
+			lookAhead:       5,
+			expectedCounter: 3,
+			expectedResult:  true,
+		},
+		{
+			scenario:  "out of window",
+			code:      "254676",
+			lookAhead: 2,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.scenario, func(t *testing.T) {
+			setAccountStorage(t, func(s *mockss.Storage[authenticator.Account]) {
+				s.On("Get", "go.nhat.io/authenticator", "namespace/token").
+					Return(authenticator.Account{
+						Name:       "token",
+						TOTPSecret: rfc4226Secret,
+						Type:       authenticator.TypeHOTP,
+						Counter:    2,
+					}, nil)
+
+				if tc.expectedResult {
+					s.On("Set", "go.nhat.io/authenticator", "namespace/token", authenticator.Account{
+						Name:       "token",
+						TOTPSecret: rfc4226Secret,
+						Type:       authenticator.TypeHOTP,
+						Counter:    tc.expectedCounter,
+						CreatedAt:  accountTime,
+						UpdatedAt:  accountTime,
+					}).Once().
+						Return(nil)
+				}
+			})
+
+			actual, err := authenticator.ResyncHOTP(context.Background(), "namespace", "token", tc.code, tc.lookAhead)
+			require.NoError(t, err)
+
+			assert.Equal(t, tc.expectedResult, actual)
+		})
+	}
+}
+
+func TestResyncHOTP_InvalidWindow(t *testing.T) {
+	t.Parallel()
+
+	actual, err := authenticator.ResyncHOTP(context.Background(), "namespace", "token", "254676", -1)
+	require.ErrorIs(t, err, authenticator.ErrInvalidWindow)
+	assert.False(t, actual)
+}
@@ -0,0 +1,153 @@
+package authenticator
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+
+	"github.com/pelletier/go-toml/v2"
+	"go.uber.org/multierr"
+)
+
+var (
+	// ErrMissingAccountName indicates that the account has no name.
+	ErrMissingAccountName = errors.New("missing account name")
+	// ErrMissingTOTPSecret indicates that the account has no TOTP secret.
+	ErrMissingTOTPSecret = errors.New("missing totp secret")
+)
+
+type importedAccounts struct {
+	Accounts []exportedAccount `json:"accounts" toml:"accounts" yaml:"accounts"`
+}
+
+// ImportAccounts reads a list of accounts in json or toml format and stores them in the namespace. The namespace is
+// created if it does not exist.
+//
+// The document has a top-level "accounts" list, each entry is an Account. The entries are validated up front: if any is
+// invalid, nothing is imported and the error lists every invalid entry. With WithSkipInvalid, the invalid entries are
+// skipped instead. The result tells which accounts were stored and which entries were skipped, with the reason.
+func ImportAccounts(namespace string, r io.Reader, format string, opts ...ImportOption) (ImportResult, error) {
+	doc, err := decodeImportedAccounts(r, format)
+	if err != nil {
+		return ImportResult{}, err
+	}
+
+	accounts := make([]Account, len(doc.Accounts))
+
+	for i, a := range doc.Accounts {
+		accounts[i] = Account(a)
+	}
+
+	configMu.Lock()
+	defer configMu.Unlock()
+
+	cfg := newImportConfig(opts...)
+	err = importAccounts(namespace, accounts, cfg)
+
+	return *cfg.result, err
+}
+
+// importAccounts validates and stores the accounts in the namespace, creating it if needed. Nothing is stored if any
+// account is invalid, unless they are skipped.
+func importAccounts(namespace string, accounts []Account, cfg importConfig) error {
+	for i := range accounts {
+		if accounts[i].Issuer == "" {
+			accounts[i].Issuer = cfg.defaultIssuer
+		}
+	}
+
+	accounts, err := cfg.validAccounts(accounts)
+	if err != nil {
+		return err
+	}
+
+	if cfg.dryRun {
+		return previewImportAccounts(namespace, accounts, cfg)
+	}
+
+	if err := createNamespace(newConfigOptions(), namespace, namespace); err != nil && !errors.Is(err, ErrNamespaceExists) {
+		return err
+	}
+
+	n, err := getNamespace(namespace)
+	if err != nil {
+		return fmt.Errorf("failed to get namespace %s for importing: %w", namespace, errors.Unwrap(err))
+	}
+
+	var imported int
+
+	for _, a := range accounts {
+		if err = setAccount(namespace, a); err != nil {
+			break
+		}
+
+		imported++
+
+		cfg.imported(a.Name)
+
+		n.indexAccount(a.Name, a.Issuer)
+
+		if !slices.Contains(n.Accounts, a.Name) {
+			n.Accounts = append(n.Accounts, a.Name)
+		}
+	}
+
+	if imported == 0 {
+		return err
+	}
+
+	slices.Sort(n.Accounts)
+
+	return multierr.Combine(err, updateNamespace(namespace, n))
+}
+
+// previewImportAccounts reports what importAccounts would store, without writing anything. The existing accounts would
+// be overwritten.
+func previewImportAccounts(namespace string, accounts []Account, cfg importConfig) error {
+	for _, a := range accounts {
+		if err := cfg.preview(namespace, a, ConflictOverwrite); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func decodeImportedAccounts(r io.Reader, format string) (importedAccounts, error) {
+	var (
+		doc importedAccounts
+		err error
+	)
+
+	switch format {
+	case "json":
+		err = json.NewDecoder(r).Decode(&doc)
+
+	case "toml":
+		err = toml.NewDecoder(r).Decode(&doc)
+
+	default:
+		return importedAccounts{}, fmt.Errorf("failed to import accounts: %w %s", ErrUnsupportedFormat, format)
+	}
+
+	if err != nil {
+		return importedAccounts{}, fmt.Errorf("failed to decode accounts: %w", err)
+	}
+
+	return doc, nil
+}
+
+// validateImportedAccounts validates all the accounts and combines the errors of the invalid ones.
+func validateImportedAccounts(accounts []Account) error {
+	var err error
+
+	for i, a := range accounts {
+		if vErr := a.Validate(); vErr != nil {
+			err = multierr.Append(err, fmt.Errorf("failed to import account #%d %q: %w", i, a.Name, vErr))
+		}
+	}
+
+	return err
+}
@@ -0,0 +1,110 @@
+package authenticator
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"go.nhat.io/otp"
+	"go.uber.org/multierr"
+)
+
+var (
+	// ErrEncrypted2FASBackup indicates that the 2FAS backup is encrypted.
+	ErrEncrypted2FASBackup = errors.New("encrypted 2fas backup is not supported, export it again without a password")
+	// ErrSkippedEntries indicates that some entries of a backup are not supported and have not been imported.
+	ErrSkippedEntries = errors.New("skipped unsupported entries")
+)
+
+type twoFASBackup struct {
+	Services          []twoFASService `json:"services"`
+	ServicesEncrypted string          `json:"servicesEncrypted"`
+}
+
+type twoFASService struct {
+	Name   string `json:"name"`
+	Secret string `json:"secret"`
+	OTP    struct {
+		Account   string `json:"account"`
+		Issuer    string `json:"issuer"`
+		Digits    int    `json:"digits"`
+		Period    uint   `json:"period"`
+		Algorithm string `json:"algorithm"`
+		Counter   uint64 `json:"counter"`
+		TokenType string `json:"tokenType"`
+	} `json:"otp"`
+}
+
+// Import2FAS reads an unencrypted 2FAS JSON backup and stores the TOTP and HOTP services in the namespace. The namespace
+// is created if it does not exist.
+//
+// The other services, such as Steam, are skipped and reported by name in an error wrapping ErrSkippedEntries, along with
+// the result of the import.
+func Import2FAS(namespace string, r io.Reader, opts ...ImportOption) (result ImportResult, err error) {
+	var doc twoFASBackup
+
+	if err := json.NewDecoder(r).Decode(&doc); err != nil {
+		return ImportResult{}, fmt.Errorf("failed to decode 2fas backup: %w", err)
+	}
+
+	if doc.ServicesEncrypted != "" {
+		return ImportResult{}, fmt.Errorf("failed to decode 2fas backup: %w", ErrEncrypted2FASBackup)
+	}
+
+	var (
+		cfg      = newImportConfig(opts...)
+		accounts = make([]Account, 0, len(doc.Services))
+		skipped  []string
+	)
+
+	for _, s := range doc.Services {
+		typ := strings.ToLower(s.OTP.TokenType)
+
+		if typ != "" && typ != TypeTOTP && typ != TypeHOTP {
+			skipped = append(skipped, fmt.Sprintf("%s (%s)", s.Name, s.OTP.TokenType))
+			cfg.skipped(s.Name, "unsupported type "+s.OTP.TokenType)
+
+			continue
+		}
+
+		a := Account{
+			Name:       s.OTP.Account,
+			TOTPSecret: otp.TOTPSecret(s.Secret),
+			Issuer:     s.OTP.Issuer,
+			Digits:     s.OTP.Digits,
+			Algorithm:  strings.ToUpper(s.OTP.Algorithm),
+		}
+
+		if a.Name == "" {
+			a.Name = s.Name
+		}
+
+		if a.Issuer == "" {
+			a.Issuer = s.Name
+		}
+
+		if typ == TypeHOTP {
+			a.Type = TypeHOTP
+			a.Counter = s.OTP.Counter
+		} else {
+			a.Period = s.OTP.Period
+		}
+
+		accounts = append(accounts, a)
+	}
+
+	if len(accounts) > 0 {
+		configMu.Lock()
+		defer configMu.Unlock()
+
+		err = importAccounts(namespace, accounts, cfg)
+	}
+
+	if len(skipped) > 0 {
+		err = multierr.Combine(err, fmt.Errorf("%w: %s", ErrSkippedEntries, strings.Join(skipped, ", ")))
+	}
+
+	return *cfg.result, err
+}
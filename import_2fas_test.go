@@ -0,0 +1,98 @@
+package authenticator_test
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go.nhat.io/authenticator"
+)
+
+func TestImport2FAS_Success(t *testing.T) {
+	setConfigFile(t)
+	setAccountClock(t)
+
+	useInMemoryStorage(t)
+
+	f, err := os.Open("resources/fixtures/2fas.json")
+	require.NoError(t, err)
+
+	defer f.Close() //nolint: errcheck
+
+	result, err := authenticator.Import2FAS("namespace", f)
+	require.ErrorIs(t, err, authenticator.ErrSkippedEntries)
+	require.EqualError(t, err, `skipped unsupported entries: Steam (STEAM)`)
+
+	assert.Len(t, result.Imported, 2)
+
+	actual, err := authenticator.ListAccounts("namespace")
+	require.NoError(t, err)
+
+	expected := []authenticator.Account{
+		{
+			Name:       "jane.doe@example.com",
+			TOTPSecret: "NBSWY3DP",
+			Issuer:     "Other",
+			Digits:     8,
+			Algorithm:  authenticator.AlgorithmSHA512,
+			Period:     60,
+			CreatedAt:  accountTime,
+			UpdatedAt:  accountTime,
+		},
+		{
+			Name:       "john.doe@example.com",
+			TOTPSecret: "JBSWY3DPEHPK3PXP",
+			Issuer:     "Example",
+			Digits:     6,
+			Algorithm:  authenticator.AlgorithmSHA1,
+			Period:     30,
+			CreatedAt:  accountTime,
+			UpdatedAt:  accountTime,
+		},
+	}
+
+	assert.Equal(t, expected, actual)
+}
+
+func TestImport2FAS_Encrypted(t *testing.T) {
+	t.Parallel()
+
+	result, err := authenticator.Import2FAS("namespace", strings.NewReader(`{"services": [], "servicesEncrypted": "abc:def:ghi", "schemaVersion": 4}`))
+	require.ErrorIs(t, err, authenticator.ErrEncrypted2FASBackup)
+
+	assert.Empty(t, result.Imported)
+}
+
+func TestImport2FAS_InvalidDocument(t *testing.T) {
+	t.Parallel()
+
+	result, err := authenticator.Import2FAS("namespace", strings.NewReader(`{`))
+	require.EqualError(t, err, `failed to decode 2fas backup: unexpected EOF`)
+
+	assert.Empty(t, result.Imported)
+}
+
+func TestImport2FAS_Result(t *testing.T) {
+	setConfigFile(t)
+
+	useInMemoryStorage(t)
+
+	f, err := os.Open("resources/fixtures/2fas.json")
+	require.NoError(t, err)
+
+	defer f.Close() //nolint: errcheck
+
+	result, err := authenticator.Import2FAS("namespace", f)
+	require.ErrorIs(t, err, authenticator.ErrSkippedEntries)
+	assert.Len(t, result.Imported, 2)
+
+	expected := authenticator.ImportResult{
+		Imported: []string{"john.doe@example.com", "jane.doe@example.com"},
+		Skipped:  []authenticator.SkippedEntry{{Name: "Steam", Reason: "unsupported type STEAM"}},
+	}
+
+	assert.Equal(t, expected, result)
+}
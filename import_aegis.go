@@ -0,0 +1,98 @@
+package authenticator
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"go.nhat.io/otp"
+)
+
+// ErrEncryptedAegisExport indicates that the Aegis export is encrypted.
+var ErrEncryptedAegisExport = errors.New("encrypted aegis export is not supported")
+
+type aegisExport struct {
+	DB json.RawMessage `json:"db"`
+}
+
+type aegisDB struct {
+	Entries []aegisEntry `json:"entries"`
+}
+
+type aegisEntry struct {
+	Type   string `json:"type"`
+	Name   string `json:"name"`
+	Issuer string `json:"issuer"`
+	Info   struct {
+		Secret  string `json:"secret"`
+		Algo    string `json:"algo"`
+		Digits  int    `json:"digits"`
+		Period  uint   `json:"period"`
+		Counter uint64 `json:"counter"`
+	} `json:"info"`
+}
+
+// ImportAegis reads a decrypted Aegis JSON export and stores the TOTP and HOTP entries in the namespace. The other
+// entries, such as Steam or mOTP, are skipped. The namespace is created if it does not exist.
+//
+// The entries are validated up front like ImportAccounts. The result reports the skipped entries with the reason.
+func ImportAegis(namespace string, r io.Reader, opts ...ImportOption) (ImportResult, error) {
+	var doc aegisExport
+
+	if err := json.NewDecoder(r).Decode(&doc); err != nil {
+		return ImportResult{}, fmt.Errorf("failed to decode aegis export: %w", err)
+	}
+
+	if strings.HasPrefix(strings.TrimSpace(string(doc.DB)), `"`) {
+		return ImportResult{}, fmt.Errorf("failed to decode aegis export: %w", ErrEncryptedAegisExport)
+	}
+
+	var db aegisDB
+
+	if err := json.Unmarshal(doc.DB, &db); err != nil {
+		return ImportResult{}, fmt.Errorf("failed to decode aegis export: %w", err)
+	}
+
+	cfg := newImportConfig(opts...)
+	accounts := make([]Account, 0, len(db.Entries))
+
+	for _, e := range db.Entries {
+		typ := strings.ToLower(e.Type)
+
+		if typ != TypeTOTP && typ != TypeHOTP {
+			cfg.skipped(e.Name, "unsupported type "+e.Type)
+
+			continue
+		}
+
+		a := Account{
+			Name:       e.Name,
+			TOTPSecret: otp.TOTPSecret(e.Info.Secret),
+			Issuer:     e.Issuer,
+			Digits:     e.Info.Digits,
+			Algorithm:  strings.ToUpper(e.Info.Algo),
+		}
+
+		if typ == TypeHOTP {
+			a.Type = TypeHOTP
+			a.Counter = e.Info.Counter
+		} else {
+			a.Period = e.Info.Period
+		}
+
+		accounts = append(accounts, a)
+	}
+
+	if len(accounts) == 0 {
+		return *cfg.result, nil
+	}
+
+	configMu.Lock()
+	defer configMu.Unlock()
+
+	err := importAccounts(namespace, accounts, cfg)
+
+	return *cfg.result, err
+}
@@ -0,0 +1,117 @@
+package authenticator_test
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go.nhat.io/authenticator"
+)
+
+func TestImportAegis_Success(t *testing.T) {
+	setConfigFile(t)
+	setAccountClock(t)
+
+	useInMemoryStorage(t)
+
+	f, err := os.Open("resources/fixtures/aegis.json")
+	require.NoError(t, err)
+
+	defer f.Close() //nolint: errcheck
+
+	result, err := authenticator.ImportAegis("namespace", f)
+	require.NoError(t, err)
+
+	assert.Len(t, result.Imported, 3)
+
+	actual, err := authenticator.ListAccounts("namespace")
+	require.NoError(t, err)
+
+	expected := []authenticator.Account{
+		{
+			Name:       "jane.doe@example.com",
+			TOTPSecret: "NBSWY3DP",
+			Issuer:     "Other",
+			Digits:     8,
+			Algorithm:  authenticator.AlgorithmSHA256,
+			Period:     60,
+			CreatedAt:  accountTime,
+			UpdatedAt:  accountTime,
+		},
+		{
+			Name:       "john.doe@example.com",
+			TOTPSecret: "JBSWY3DPEHPK3PXP",
+			Issuer:     "Example",
+			Digits:     6,
+			Algorithm:  authenticator.AlgorithmSHA1,
+			Period:     30,
+			CreatedAt:  accountTime,
+			UpdatedAt:  accountTime,
+		},
+		{
+			Name:       "token",
+			TOTPSecret: "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ",
+			Issuer:     "Bank",
+			Type:       authenticator.TypeHOTP,
+			Counter:    7,
+			Digits:     6,
+			Algorithm:  authenticator.AlgorithmSHA1,
+			CreatedAt:  accountTime,
+			UpdatedAt:  accountTime,
+		},
+	}
+
+	assert.Equal(t, expected, actual)
+}
+
+func TestImportAegis_Encrypted(t *testing.T) {
+	t.Parallel()
+
+	result, err := authenticator.ImportAegis("namespace", strings.NewReader(`{"version": 1, "header": {}, "db": "c2VjcmV0"}`))
+	require.ErrorIs(t, err, authenticator.ErrEncryptedAegisExport)
+
+	assert.Empty(t, result.Imported)
+}
+
+func TestImportAegis_InvalidDocument(t *testing.T) {
+	t.Parallel()
+
+	result, err := authenticator.ImportAegis("namespace", strings.NewReader(`{`))
+	require.EqualError(t, err, `failed to decode aegis export: unexpected EOF`)
+
+	assert.Empty(t, result.Imported)
+}
+
+func TestImportAegis_NoSupportedEntries(t *testing.T) {
+	t.Parallel()
+
+	result, err := authenticator.ImportAegis("namespace", strings.NewReader(`{"db": {"entries": [{"type": "steam", "name": "gamer"}]}}`))
+	require.NoError(t, err)
+
+	assert.Empty(t, result.Imported)
+}
+
+func TestImportAegis_Result(t *testing.T) {
+	setConfigFile(t)
+
+	useInMemoryStorage(t)
+
+	f, err := os.Open("resources/fixtures/aegis.json")
+	require.NoError(t, err)
+
+	defer f.Close() //nolint: errcheck
+
+	result, err := authenticator.ImportAegis("namespace", f)
+	require.NoError(t, err)
+	assert.Len(t, result.Imported, 3)
+
+	expected := authenticator.ImportResult{
+		Imported: []string{"john.doe@example.com", "jane.doe@example.com", "token"},
+		Skipped:  []authenticator.SkippedEntry{{Name: "gamer", Reason: "unsupported type steam"}},
+	}
+
+	assert.Equal(t, expected, result)
+}
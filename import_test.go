@@ -0,0 +1,272 @@
+package authenticator_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.nhat.io/secretstorage"
+	mockss "go.nhat.io/secretstorage/mock"
+
+	"go.nhat.io/authenticator"
+)
+
+func TestImportAccounts_Success(t *testing.T) {
+	testCases := []struct {
+		scenario string
+		format   string
+		document string
+	}{
+		{
+			scenario: "json",
+			format:   "json",
+			document: `{"accounts": [
+				{"name": "john.doe@example.com", "totp_secret": "NBSWY3DP", "issuer": "example.com"},
+				{"name": "jane.doe@example.com", "totp_secret": "JBSWY3DP", "issuer": "example.com", "digits": 8}
+			]}`,
+		},
+		{
+			scenario: "toml",
+			format:   "toml",
+			document: `
+[[accounts]]
+name = "john.doe@example.com"
+totp_secret = "NBSWY3DP"
+issuer = "example.com"
+
+[[accounts]]
+name = "jane.doe@example.com"
+totp_secret = "JBSWY3DP"
+issuer = "example.com"
+digits = 8
+`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.scenario, func(t *testing.T) {
+			setConfigFile(t)
+			setAccountClock(t)
+
+			setNamespaceStorage(t, func(s *mockss.Storage[authenticator.Namespace]) {
+				s.On("Get", "go.nhat.io/authenticator", "namespace").Once().
+					Return(authenticator.Namespace{}, secretstorage.ErrNotFound)
+
+				s.On("Set", "go.nhat.io/authenticator", "namespace", authenticator.Namespace{Name: "namespace"}).Once().
+					Return(nil)
+
+				s.On("Get", "go.nhat.io/authenticator", "namespace").Once().
+					Return(authenticator.Namespace{Name: "namespace"}, nil)
+
+				s.On("Set", "go.nhat.io/authenticator", "namespace", authenticator.Namespace{
+					Name:     "namespace",
+					Accounts: []string{"jane.doe@example.com", "john.doe@example.com"},
+					Issuers:  map[string][]string{"example.com": {"jane.doe@example.com", "john.doe@example.com"}},
+				}).Once().
+					Return(nil)
+			})
+
+			setAccountStorage(t, func(s *mockss.Storage[authenticator.Account]) {
+				s.On("Set", "go.nhat.io/authenticator", "namespace/john.doe@example.com", authenticator.Account{
+					Name:       "john.doe@example.com",
+					TOTPSecret: "NBSWY3DP",
+					Issuer:     "example.com",
+					CreatedAt:  accountTime,
+					UpdatedAt:  accountTime,
+				}).
+					Return(nil)
+
+				s.On("Set", "go.nhat.io/authenticator", "namespace/jane.doe@example.com", authenticator.Account{
+					Name:       "jane.doe@example.com",
+					TOTPSecret: "JBSWY3DP",
+					Issuer:     "example.com",
+					Digits:     8,
+					CreatedAt:  accountTime,
+					UpdatedAt:  accountTime,
+				}).
+					Return(nil)
+			})
+
+			result, err := authenticator.ImportAccounts("namespace", strings.NewReader(tc.document), tc.format)
+			require.NoError(t, err)
+
+			assert.Len(t, result.Imported, 2)
+		})
+	}
+}
+
+func TestImportAccounts_WithDefaultIssuer(t *testing.T) {
+	setConfigFile(t)
+
+	useInMemoryStorage(t)
+
+	document := `{"accounts": [
+		{"name": "john.doe@example.com", "totp_secret": "NBSWY3DP"},
+		{"name": "jane.doe@example.com", "totp_secret": "JBSWY3DP", "issuer": "example.com"}
+	]}`
+
+	result, err := authenticator.ImportAccounts("namespace", strings.NewReader(document), "json", authenticator.WithDefaultIssuer("Acme"))
+	require.NoError(t, err)
+	assert.Len(t, result.Imported, 2)
+
+	actual, err := authenticator.GetAccount("namespace", "john.doe@example.com")
+	require.NoError(t, err)
+	assert.Equal(t, "Acme", actual.Issuer)
+
+	actual, err = authenticator.GetAccount("namespace", "jane.doe@example.com")
+	require.NoError(t, err)
+	assert.Equal(t, "example.com", actual.Issuer)
+}
+
+func TestImportAccounts_DryRun(t *testing.T) {
+	setConfigFile(t)
+
+	// No write is expected.
+	setNamespaceStorage(t, func(*mockss.Storage[authenticator.Namespace]) {})
+
+	setAccountStorage(t, func(s *mockss.Storage[authenticator.Account]) {
+		s.On("Get", "go.nhat.io/authenticator", "namespace/john.doe@example.com").
+			Return(authenticator.Account{Name: "john.doe@example.com", TOTPSecret: "KBSWY3DP"}, nil)
+
+		s.On("Get", "go.nhat.io/authenticator", "namespace/jane.doe@example.com").
+			Return(authenticator.Account{}, secretstorage.ErrNotFound)
+	})
+
+	var report authenticator.ImportReport
+
+	doc := `{"accounts": [
+		{"name": "john.doe@example.com", "totp_secret": "NBSWY3DP"},
+		{"name": "jane.doe@example.com", "totp_secret": "JBSWY3DP"}
+	]}`
+
+	result, err := authenticator.ImportAccounts("namespace", strings.NewReader(doc), "json", authenticator.WithDryRun(&report))
+	require.NoError(t, err)
+
+	expected := authenticator.ImportReport{
+		Created:     []string{"jane.doe@example.com"},
+		Overwritten: []string{"john.doe@example.com"},
+	}
+
+	assert.Len(t, result.Imported, 2)
+	assert.Equal(t, expected, report)
+}
+
+func TestImportAccounts_InvalidAccounts(t *testing.T) {
+	setConfigFileWithContent(t, `namespaces = ["namespace"]`)
+
+	// Nothing is imported when any account is invalid.
+	setNamespaceStorage(t, func(*mockss.Storage[authenticator.Namespace]) {})
+	setAccountStorage(t, func(*mockss.Storage[authenticator.Account]) {})
+
+	document := `{"accounts": [
+		{"name": "john.doe@example.com", "totp_secret": "NBSWY3DP"},
+		{"name": "jane.doe@example.com", "totp_secret": "secret!"},
+		{"totp_secret": "JBSWY3DP"},
+		{"name": "jim.doe@example.com"},
+		{"name": "joe.doe@example.com", "totp_secret": "JBSWY3DP", "digits": 4},
+		{"name": "jill.doe@example.com", "totp_secret": "JBSWY3DP", "algorithm": "MD5"}
+	]}`
+
+	for _, opts := range [][]authenticator.ImportOption{nil, {authenticator.WithDryRun(&authenticator.ImportReport{})}} {
+		result, err := authenticator.ImportAccounts("namespace", strings.NewReader(document), "json", opts...)
+		require.ErrorIs(t, err, authenticator.ErrInvalidSecret)
+		require.ErrorIs(t, err, authenticator.ErrMissingAccountName)
+		require.ErrorIs(t, err, authenticator.ErrMissingTOTPSecret)
+		require.ErrorIs(t, err, authenticator.ErrInvalidDigits)
+		require.ErrorIs(t, err, authenticator.ErrUnsupportedAlgorithm)
+
+		expected := `failed to import account #1 "jane.doe@example.com": invalid totp secret: illegal base32 data at input byte 6; ` +
+			`failed to import account #2 "": missing account name; ` +
+			`failed to import account #3 "jim.doe@example.com": missing totp secret; ` +
+			`failed to import account #4 "joe.doe@example.com": invalid number of digits: 4, must be between 6 and 10; ` +
+			`failed to import account #5 "jill.doe@example.com": unsupported algorithm: MD5`
+
+		require.EqualError(t, err, expected)
+		assert.Empty(t, result.Imported)
+	}
+}
+
+func TestImportAccounts_WithSkipInvalid(t *testing.T) {
+	setConfigFile(t)
+
+	useInMemoryStorage(t)
+
+	document := `{"accounts": [
+		{"name": "john.doe@example.com", "totp_secret": "NBSWY3DP"},
+		{"name": "jane.doe@example.com", "totp_secret": "secret!"},
+		{"totp_secret": "JBSWY3DP"},
+		{"name": "joe.doe@example.com", "totp_secret": "JBSWY3DP"},
+		{"name": "jill.doe@example.com", "totp_secret": "JBSWY3DP", "algorithm": "MD5"}
+	]}`
+
+	result, err := authenticator.ImportAccounts("namespace", strings.NewReader(document), "json",
+		authenticator.WithSkipInvalid(),
+	)
+	require.NoError(t, err)
+	assert.Len(t, result.Imported, 2)
+
+	expected := authenticator.ImportResult{
+		Imported: []string{"john.doe@example.com", "joe.doe@example.com"},
+		Skipped: []authenticator.SkippedEntry{
+			{Name: "jane.doe@example.com", Reason: "invalid totp secret: illegal base32 data at input byte 6"},
+			{Name: "", Reason: "missing account name"},
+			{Name: "jill.doe@example.com", Reason: "unsupported algorithm: MD5"},
+		},
+	}
+
+	assert.Equal(t, expected, result)
+
+	n, err := authenticator.GetNamespace("namespace")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"joe.doe@example.com", "john.doe@example.com"}, n.Accounts)
+}
+
+func TestImportAccounts_MissingName(t *testing.T) {
+	setConfigFileWithContent(t, `namespaces = ["namespace"]`)
+
+	setNamespaceStorage(t, func(*mockss.Storage[authenticator.Namespace]) {})
+
+	result, err := authenticator.ImportAccounts("namespace", strings.NewReader(`{"accounts": [{"totp_secret": "NBSWY3DP"}]}`), "json")
+	require.ErrorIs(t, err, authenticator.ErrMissingAccountName)
+
+	assert.Empty(t, result.Imported)
+}
+
+func TestImportAccounts_FailedToSetAccount(t *testing.T) {
+	setConfigFileWithContent(t, `namespaces = ["namespace"]`)
+
+	setNamespaceStorage(t, func(s *mockss.Storage[authenticator.Namespace]) {
+		s.On("Get", "go.nhat.io/authenticator", "namespace").
+			Return(authenticator.Namespace{Name: "namespace"}, nil)
+	})
+
+	setAccountStorage(t, func(s *mockss.Storage[authenticator.Account]) {
+		s.On("Set", "go.nhat.io/authenticator", "namespace/john.doe@example.com", mockss.Anything).
+			Return(assert.AnError)
+	})
+
+	result, err := authenticator.ImportAccounts("namespace", strings.NewReader(`{"accounts": [{"name": "john.doe@example.com", "totp_secret": "NBSWY3DP"}]}`), "json")
+	require.EqualError(t, err, `failed to store account john.doe@example.com in namespace namespace: assert.AnError general error for testing`)
+
+	assert.Empty(t, result.Imported)
+}
+
+func TestImportAccounts_UnsupportedFormat(t *testing.T) {
+	t.Parallel()
+
+	result, err := authenticator.ImportAccounts("namespace", strings.NewReader(``), "yaml")
+	require.ErrorIs(t, err, authenticator.ErrUnsupportedFormat)
+	require.EqualError(t, err, `failed to import accounts: unsupported format yaml`)
+
+	assert.Empty(t, result.Imported)
+}
+
+func TestImportAccounts_InvalidDocument(t *testing.T) {
+	t.Parallel()
+
+	result, err := authenticator.ImportAccounts("namespace", strings.NewReader(`{`), "json")
+	require.EqualError(t, err, `failed to decode accounts: unexpected EOF`)
+
+	assert.Empty(t, result.Imported)
+}
@@ -0,0 +1,127 @@
+package authenticator
+
+import (
+	"errors"
+	"maps"
+	"slices"
+)
+
+// FindAccountsByIssuer returns the accounts in the namespace that have the issuer, sorted by name. The empty issuer
+// finds the accounts without an issuer.
+//
+// The accounts are looked up in the issuer index of the namespace, so only the accounts of the issuer are read from the
+// storage. The namespaces stored before the index are scanned like FilterAccounts until RepairNamespace with WithFix
+// builds their index.
+func FindAccountsByIssuer(namespace, issuer string) ([]Account, error) {
+	configMu.RLock()
+	defer configMu.RUnlock()
+
+	n, err := getNamespace(namespace)
+	if err != nil {
+		return nil, err
+	}
+
+	if !n.indexed() {
+		accounts, err := getAccounts(namespace, n.Accounts)
+		if err != nil {
+			return nil, err
+		}
+
+		return slices.DeleteFunc(accounts, func(a Account) bool {
+			return a.Issuer != issuer
+		}), nil
+	}
+
+	return getAccounts(namespace, n.Issuers[issuer])
+}
+
+// indexed tells whether the namespace has an issuer index. The namespaces stored before the index have accounts but no
+// index.
+func (c Namespace) indexed() bool {
+	return c.Issuers != nil || len(c.Accounts) == 0
+}
+
+// indexAccount records the issuer of the account in the index, if the namespace has one, and tells whether the index
+// changed. It must be called before the account is added to the namespace, so that an empty namespace starts its index.
+//
+// The index is copied before it is changed because it may be shared with the storage, e.g. in memory.
+func (c *Namespace) indexAccount(account, issuer string) bool {
+	if !c.indexed() || slices.Contains(c.Issuers[issuer], account) {
+		return false
+	}
+
+	c.unindexAccount(account)
+
+	index := maps.Clone(c.Issuers)
+	if index == nil {
+		index = make(map[string][]string)
+	}
+
+	accounts := append(slices.Clone(index[issuer]), account)
+
+	slices.Sort(accounts)
+
+	index[issuer] = accounts
+	c.Issuers = index
+
+	return true
+}
+
+// unindexAccount removes the account from the issuer index.
+func (c *Namespace) unindexAccount(account string) {
+	for issuer, accounts := range c.Issuers {
+		i := slices.Index(accounts, account)
+		if i < 0 {
+			continue
+		}
+
+		c.Issuers = maps.Clone(c.Issuers)
+
+		if len(accounts) == 1 {
+			delete(c.Issuers, issuer)
+		} else {
+			c.Issuers[issuer] = slices.Delete(slices.Clone(accounts), i, i+1)
+		}
+
+		return
+	}
+}
+
+// issuerIndex builds the issuer index of the accounts. It is nil if there is no account.
+func issuerIndex(accounts []Account) map[string][]string {
+	if len(accounts) == 0 {
+		return nil
+	}
+
+	index := make(map[string][]string)
+
+	for _, a := range accounts {
+		index[a.Issuer] = append(index[a.Issuer], a.Name)
+	}
+
+	for _, names := range index {
+		slices.Sort(names)
+	}
+
+	return index
+}
+
+// getAccounts returns the accounts in the order of the names. The accounts that are missing in the storage are skipped.
+func getAccounts(namespace string, names []string) ([]Account, error) {
+	accounts := make([]Account, 0, len(names))
+
+	for _, name := range names {
+		a, err := getAccount(namespace, name)
+		if err != nil {
+			if errors.Is(err, ErrAccountNotFound) {
+				continue
+			}
+
+			return nil, err
+		}
+
+		accounts = append(accounts, a)
+	}
+
+	return accounts, nil
+}
@@ -0,0 +1,236 @@
+package authenticator_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go.nhat.io/authenticator"
+)
+
+func TestFindAccountsByIssuer_Consistency(t *testing.T) {
+	setConfigFile(t)
+	setAccountClock(t)
+	useInMemoryStorage(t)
+
+	err := authenticator.CreateNamespace("namespace", "Namespace")
+	require.NoError(t, err)
+
+	for _, a := range []authenticator.Account{
+		{Name: "alice", Issuer: "github.com", TOTPSecret: "NBSWY3DP"},
+		{Name: "bob", Issuer: "github.com", TOTPSecret: "NBSWY3DP"},
+		{Name: "carol", Issuer: "gitlab.com", TOTPSecret: "NBSWY3DP"},
+		{Name: "dave", TOTPSecret: "NBSWY3DP"},
+	} {
+		err := authenticator.SetAccount("namespace", a)
+		require.NoError(t, err)
+	}
+
+	assertIssuerIndex(t, "namespace", map[string][]string{
+		"":           {"dave"},
+		"github.com": {"alice", "bob"},
+		"gitlab.com": {"carol"},
+	})
+
+	// The issuer changes.
+	err = authenticator.SetAccount("namespace", authenticator.Account{Name: "bob", Issuer: "gitlab.com", TOTPSecret: "NBSWY3DP"})
+	require.NoError(t, err)
+
+	assertIssuerIndex(t, "namespace", map[string][]string{
+		"":           {"dave"},
+		"github.com": {"alice"},
+		"gitlab.com": {"bob", "carol"},
+	})
+
+	// Rename.
+	err = authenticator.CopyAccount("namespace", "alice", "alicia")
+	require.NoError(t, err)
+
+	err = authenticator.DeleteAccount("namespace", "alice")
+	require.NoError(t, err)
+
+	assertIssuerIndex(t, "namespace", map[string][]string{
+		"":           {"dave"},
+		"github.com": {"alicia"},
+		"gitlab.com": {"bob", "carol"},
+	})
+
+	err = authenticator.DeleteAccounts("namespace", []string{"alicia", "dave"})
+	require.NoError(t, err)
+
+	assertIssuerIndex(t, "namespace", map[string][]string{
+		"gitlab.com": {"bob", "carol"},
+	})
+
+	_, err = authenticator.ImportAccounts("namespace", strings.NewReader(`{"accounts": [
+		{"name": "bob", "totp_secret": "NBSWY3DP", "issuer": "github.com"},
+		{"name": "erin", "totp_secret": "NBSWY3DP", "issuer": "github.com"}
+	]}`), "json")
+	require.NoError(t, err)
+
+	assertIssuerIndex(t, "namespace", map[string][]string{
+		"github.com": {"bob", "erin"},
+		"gitlab.com": {"carol"},
+	})
+
+	actual, err := authenticator.FindAccountsByIssuer("namespace", "unknown")
+	require.NoError(t, err)
+	assert.Empty(t, actual)
+}
+
+func TestFindAccountsByIssuer_WithoutIndex(t *testing.T) {
+	setConfigFile(t)
+	setAccountClock(t)
+
+	accounts, namespaces := useInMemoryStorage(t)
+
+	// The namespace is stored before the index.
+	err := namespaces.Set("go.nhat.io/authenticator", "namespace", authenticator.Namespace{
+		Name:     "namespace",
+		Accounts: []string{"alice", "bob"},
+	})
+	require.NoError(t, err)
+
+	for _, a := range []authenticator.Account{
+		{Name: "alice", Issuer: "github.com"},
+		{Name: "bob", Issuer: "gitlab.com"},
+	} {
+		err := accounts.Set("go.nhat.io/authenticator", "namespace/"+a.Name, a)
+		require.NoError(t, err)
+	}
+
+	actual, err := authenticator.FindAccountsByIssuer("namespace", "github.com")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"alice"}, accountNames(actual))
+
+	// The index is not started for a part of the accounts.
+	err = authenticator.SetAccount("namespace", authenticator.Account{Name: "carol", Issuer: "github.com", TOTPSecret: "NBSWY3DP"})
+	require.NoError(t, err)
+
+	n, err := authenticator.GetNamespace("namespace")
+	require.NoError(t, err)
+	assert.Nil(t, n.Issuers)
+
+	actual, err = authenticator.FindAccountsByIssuer("namespace", "github.com")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"alice", "carol"}, accountNames(actual))
+
+	report, err := authenticator.RepairNamespace("namespace", authenticator.WithFix())
+	require.NoError(t, err)
+	assert.True(t, report.Repaired)
+
+	assertIssuerIndex(t, "namespace", map[string][]string{
+		"github.com": {"alice", "carol"},
+		"gitlab.com": {"bob"},
+	})
+}
+
+func TestRepairNamespace_StaleIndex(t *testing.T) {
+	accounts, namespaces := useInMemoryStorage(t)
+
+	err := namespaces.Set("go.nhat.io/authenticator", "namespace", authenticator.Namespace{
+		Name:     "namespace",
+		Accounts: []string{"alice"},
+		Issuers:  map[string][]string{"github.com": {"alice"}},
+	})
+	require.NoError(t, err)
+
+	// The issuer is changed out of band.
+	err = accounts.Set("go.nhat.io/authenticator", "namespace/alice", authenticator.Account{Name: "alice", Issuer: "gitlab.com"})
+	require.NoError(t, err)
+
+	actual, err := authenticator.RepairNamespace("namespace")
+	require.NoError(t, err)
+	assert.Equal(t, authenticator.RepairReport{StaleIndex: true}, actual)
+	assert.False(t, actual.Consistent())
+
+	actual, err = authenticator.RepairNamespace("namespace", authenticator.WithFix())
+	require.NoError(t, err)
+	assert.Equal(t, authenticator.RepairReport{StaleIndex: true, Repaired: true}, actual)
+
+	actual, err = authenticator.RepairNamespace("namespace")
+	require.NoError(t, err)
+	assert.True(t, actual.Consistent())
+
+	found, err := authenticator.FindAccountsByIssuer("namespace", "gitlab.com")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"alice"}, accountNames(found))
+}
+
+func BenchmarkFindAccountsByIssuer(b *testing.B) {
+	const (
+		numAccounts = 1000
+		numIssuers  = 100
+	)
+
+	accounts, namespaces := useInMemoryStorage(b)
+
+	n := authenticator.Namespace{Name: "namespace", Issuers: map[string][]string{}}
+
+	for i := range numAccounts {
+		a := authenticator.Account{
+			Name:       fmt.Sprintf("account-%04d", i),
+			Issuer:     fmt.Sprintf("issuer-%02d", i%numIssuers),
+			TOTPSecret: "NBSWY3DP",
+		}
+
+		err := accounts.Set("go.nhat.io/authenticator", "namespace/"+a.Name, a)
+		require.NoError(b, err)
+
+		n.Accounts = append(n.Accounts, a.Name)
+		n.Issuers[a.Issuer] = append(n.Issuers[a.Issuer], a.Name)
+	}
+
+	err := namespaces.Set("go.nhat.io/authenticator", "namespace", n)
+	require.NoError(b, err)
+
+	b.Run("index", func(b *testing.B) {
+		for range b.N {
+			_, _ = authenticator.FindAccountsByIssuer("namespace", "issuer-42") //nolint: errcheck
+		}
+	})
+
+	b.Run("scan", func(b *testing.B) {
+		for range b.N {
+			_, _ = authenticator.FilterAccounts("namespace", func(a authenticator.Account) bool { //nolint: errcheck
+				return a.Issuer == "issuer-42"
+			})
+		}
+	})
+}
+
+// assertIssuerIndex asserts that the index of the namespace is the expected one, and that FindAccountsByIssuer finds
+// the same accounts as a scan.
+func assertIssuerIndex(t *testing.T, namespace string, expected map[string][]string) {
+	t.Helper()
+
+	n, err := authenticator.GetNamespace(namespace)
+	require.NoError(t, err)
+	assert.Equal(t, expected, n.Issuers)
+
+	for issuer := range expected {
+		found, err := authenticator.FindAccountsByIssuer(namespace, issuer)
+		require.NoError(t, err)
+
+		scanned, err := authenticator.FilterAccounts(namespace, func(a authenticator.Account) bool {
+			return a.Issuer == issuer
+		})
+		require.NoError(t, err)
+
+		assert.Equal(t, scanned, found, "issuer %q", issuer)
+		assert.Equal(t, expected[issuer], accountNames(found), "issuer %q", issuer)
+	}
+}
+
+func accountNames(accounts []authenticator.Account) []string {
+	names := make([]string, len(accounts))
+
+	for i, a := range accounts {
+		names[i] = a.Name
+	}
+
+	return names
+}
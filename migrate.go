@@ -0,0 +1,102 @@
+package authenticator
+
+import (
+	"errors"
+	"fmt"
+
+	"go.nhat.io/secretstorage"
+)
+
+// MigrateAccountKeys moves the accounts of the namespace that are still stored under their legacy key, from before the
+// namespace and the account name were escaped in the storage key, to their current key. It returns the migrated
+// accounts.
+//
+// The accounts are only read from their current key, so the namespaces with "/" or "%" in their id or in the name of
+// their accounts must be migrated once after upgrading. RepairNamespace reports the accounts to migrate.
+func MigrateAccountKeys(namespace string) ([]string, error) {
+	configMu.Lock()
+	defer configMu.Unlock()
+
+	n, err := getNamespace(namespace)
+	if err != nil {
+		return nil, fmt.Errorf("failed to migrate account keys of namespace %s: %w", namespace, errors.Unwrap(err))
+	}
+
+	var migrated []string
+
+	for _, account := range n.Accounts {
+		ok, err := migrateAccountKey(namespace, account)
+		if err != nil {
+			return migrated, fmt.Errorf("failed to migrate account keys of namespace %s: %w", namespace, err)
+		}
+
+		if ok {
+			migrated = append(migrated, account)
+		}
+	}
+
+	return migrated, nil
+}
+
+// migrateAccountKey moves the account from its legacy key to its current key, and tells whether it was moved. If the
+// account is stored under both keys, the current one is kept and the legacy one is deleted.
+func migrateAccountKey(namespace, account string) (bool, error) {
+	a, err := getLegacyAccount(namespace, account)
+	if err != nil {
+		if errors.Is(err, secretstorage.ErrNotFound) {
+			return false, nil
+		}
+
+		return false, fmt.Errorf("failed to get account %s: %w", account, err)
+	}
+
+	var moved bool
+
+	_, err = accountStorage.Get(serviceName, formatAccount(namespace, account))
+
+	switch {
+	case errors.Is(err, secretstorage.ErrNotFound):
+		if err := accountStorage.Set(serviceName, formatAccount(namespace, account), a); err != nil {
+			return false, fmt.Errorf("failed to store account %s: %w", account, err)
+		}
+
+		moved = true
+
+	case err != nil:
+		return false, fmt.Errorf("failed to get account %s: %w", account, err)
+	}
+
+	if err := accountStorage.Delete(serviceName, formatLegacyAccount(namespace, account)); err != nil {
+		return moved, fmt.Errorf("failed to delete account %s: %w", account, err)
+	}
+
+	codeCache.invalidate(namespace, account)
+
+	return moved, nil
+}
+
+// getLegacyAccount reads the account from its legacy key. It returns secretstorage.ErrNotFound if the legacy key is the
+// current one, or if the legacy key holds another account, e.g. b/c in namespace a and c in namespace a/b share the
+// legacy key a/b/c.
+func getLegacyAccount(namespace, account string) (Account, error) {
+	legacy := formatLegacyAccount(namespace, account)
+	if legacy == formatAccount(namespace, account) {
+		return Account{}, secretstorage.ErrNotFound
+	}
+
+	a, err := accountStorage.Get(serviceName, legacy)
+	if err != nil {
+		return Account{}, err //nolint: wrapcheck
+	}
+
+	if a.Name != account {
+		return Account{}, secretstorage.ErrNotFound
+	}
+
+	return a, nil
+}
+
+// formatLegacyAccount returns the storage key of the account before the namespace and the account name were escaped.
+func formatLegacyAccount(namespace, account string) string {
+	return fmt.Sprintf("%s/%s", namespace, account)
+}
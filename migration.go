@@ -0,0 +1,409 @@
+package authenticator
+
+import (
+	"bytes"
+	"encoding/base32"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"go.nhat.io/otp"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+const (
+	migrationProtocol   = "otpauth-migration://"
+	migrationOfflineURI = "otpauth-migration://offline"
+	migrationVersion    = 1
+
+	// maxMigrationURILength is the longest uri that still renders to a QR code that phones scan reliably.
+	maxMigrationURILength = 1024
+)
+
+var (
+	// ErrInvalidMigrationPayload indicates that the otpauth-migration payload could not be decoded.
+	ErrInvalidMigrationPayload = errors.New("invalid migration payload")
+	// ErrMigrationPayloadTooLarge indicates that the accounts do not fit in a single QR code.
+	ErrMigrationPayloadTooLarge = errors.New("migration payload is too large for a single qr code, use EncodeMigrationQRCodeBatch instead")
+)
+
+// Field numbers of the MigrationPayload and OtpParameters messages in the Google Authenticator export.
+const (
+	migrationPayloadOTPParameters protowire.Number = 1
+	migrationPayloadVersion       protowire.Number = 2
+	migrationPayloadBatchSize     protowire.Number = 3
+	migrationPayloadBatchIndex    protowire.Number = 4
+	migrationPayloadBatchID       protowire.Number = 5
+
+	migrationOTPSecret    protowire.Number = 1
+	migrationOTPName      protowire.Number = 2
+	migrationOTPIssuer    protowire.Number = 3
+	migrationOTPAlgorithm protowire.Number = 4
+	migrationOTPDigits    protowire.Number = 5
+	migrationOTPType      protowire.Number = 6
+	migrationOTPCounter   protowire.Number = 7
+)
+
+// Values of the Algorithm, DigitCount and OtpType enums in the Google Authenticator export.
+const (
+	migrationAlgorithmSHA1   = 1
+	migrationAlgorithmSHA256 = 2
+	migrationAlgorithmSHA512 = 3
+	migrationAlgorithmMD5    = 4
+
+	migrationDigitsSix   = 1
+	migrationDigitsEight = 2
+
+	migrationTypeHOTP = 1
+	migrationTypeTOTP = 2
+)
+
+// ParseMigrationQRCode parses a Google Authenticator export QR code from the given file path.
+func ParseMigrationQRCode(path string) ([]Account, error) {
+	f, err := os.Open(filepath.Clean(path))
+	if err != nil {
+		return nil, fmt.Errorf("failed to qr code file: %w", err)
+	}
+
+	defer f.Close() //nolint: errcheck,gosec
+
+	return DecodeMigrationQRCode(f)
+}
+
+// DecodeMigrationQRCode decodes a Google Authenticator export QR code, the otpauth-migration://offline?data=... uri, and
+// returns all the accounts in it.
+func DecodeMigrationQRCode(r io.Reader) ([]Account, error) {
+	content, err := decodeQRCode(r)
+	if err != nil {
+		return nil, err
+	}
+
+	if !strings.HasPrefix(content, migrationProtocol) {
+		return nil, fmt.Errorf("invalid otpauth-migration uri: %s", content) //nolint: goerr113
+	}
+
+	u, err := url.Parse(content)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse otpauth-migration uri: %w", err)
+	}
+
+	data, err := base64.StdEncoding.DecodeString(u.Query().Get("data"))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode migration payload: %w: %w", ErrInvalidMigrationPayload, err)
+	}
+
+	accounts, err := parseMigrationPayload(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to decode migration payload: %w", err)
+	}
+
+	return accounts, nil
+}
+
+func parseMigrationPayload(data []byte) ([]Account, error) {
+	var accounts []Account
+
+	err := consumeProtoFields(data, func(num protowire.Number, typ protowire.Type, b []byte, _ uint64) error {
+		if num != migrationPayloadOTPParameters || typ != protowire.BytesType {
+			return nil
+		}
+
+		account, err := parseMigrationOTPParameters(b)
+		if err != nil {
+			return err
+		}
+
+		accounts = append(accounts, account)
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return accounts, nil
+}
+
+func parseMigrationOTPParameters(data []byte) (Account, error) {
+	var account Account
+
+	err := consumeProtoFields(data, func(num protowire.Number, typ protowire.Type, b []byte, v uint64) error {
+		switch {
+		case num == migrationOTPSecret && typ == protowire.BytesType:
+			account.TOTPSecret = otp.TOTPSecret(base32.StdEncoding.WithPadding(base32.NoPadding).EncodeToString(b))
+
+		case num == migrationOTPName && typ == protowire.BytesType:
+			account.Name = string(b)
+
+		case num == migrationOTPIssuer && typ == protowire.BytesType:
+			account.Issuer = string(b)
+
+		case num == migrationOTPAlgorithm && typ == protowire.VarintType:
+			switch v {
+			case migrationAlgorithmSHA1:
+				account.Algorithm = AlgorithmSHA1
+
+			case migrationAlgorithmSHA256:
+				account.Algorithm = AlgorithmSHA256
+
+			case migrationAlgorithmSHA512:
+				account.Algorithm = AlgorithmSHA512
+
+			case migrationAlgorithmMD5:
+				return fmt.Errorf("%w: MD5", ErrUnsupportedAlgorithm)
+			}
+
+		case num == migrationOTPDigits && typ == protowire.VarintType:
+			switch v {
+			case migrationDigitsSix:
+				account.Digits = 6 //nolint: gomnd
+
+			case migrationDigitsEight:
+				account.Digits = 8 //nolint: gomnd
+			}
+
+		case num == migrationOTPType && typ == protowire.VarintType:
+			if v == migrationTypeHOTP {
+				account.Type = TypeHOTP
+			}
+
+		case num == migrationOTPCounter && typ == protowire.VarintType:
+			account.Counter = v
+		}
+
+		return nil
+	})
+	if err != nil {
+		return Account{}, err
+	}
+
+	// The name is usually prefixed with the issuer, e.g. "Example:john.doe@example.com".
+	if account.Issuer != "" {
+		account.Name = strings.TrimPrefix(account.Name, account.Issuer+":")
+	}
+
+	if account.Type != TypeHOTP {
+		account.Counter = 0
+	}
+
+	return account, nil
+}
+
+// consumeProtoFields walks through the fields of a protobuf message. Only varint and length-delimited values are passed
+// to fn, the other types are skipped.
+func consumeProtoFields(data []byte, fn func(num protowire.Number, typ protowire.Type, b []byte, v uint64) error) error {
+	for len(data) > 0 {
+		num, typ, n := protowire.ConsumeTag(data)
+		if n < 0 {
+			return fmt.Errorf("%w: %w", ErrInvalidMigrationPayload, protowire.ParseError(n))
+		}
+
+		data = data[n:]
+
+		var (
+			b []byte
+			v uint64
+		)
+
+		switch typ {
+		case protowire.VarintType:
+			v, n = protowire.ConsumeVarint(data)
+
+		case protowire.BytesType:
+			b, n = protowire.ConsumeBytes(data)
+
+		default:
+			n = protowire.ConsumeFieldValue(num, typ, data)
+		}
+
+		if n < 0 {
+			return fmt.Errorf("%w: %w", ErrInvalidMigrationPayload, protowire.ParseError(n))
+		}
+
+		data = data[n:]
+
+		if err := fn(num, typ, b, v); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// EncodeMigrationQRCode produces a single Google Authenticator export QR code for the given accounts. If the accounts
+// do not fit in one QR code, ErrMigrationPayloadTooLarge is returned and EncodeMigrationQRCodeBatch should be used.
+func EncodeMigrationQRCode(w io.Writer, accounts []Account, format string, width, height int) error {
+	uris, err := migrationURIs(accounts)
+	if err != nil {
+		return fmt.Errorf("failed to encode migration qr code: %w", err)
+	}
+
+	if len(uris) > 1 {
+		return fmt.Errorf("failed to encode migration qr code: %w", ErrMigrationPayloadTooLarge)
+	}
+
+	return encodeQRCode(w, uris[0], format, width, height)
+}
+
+// EncodeMigrationQRCodeBatch produces as many Google Authenticator export QR codes as needed to hold all the accounts.
+// The QR codes are returned in order and are meant to be scanned one after another.
+func EncodeMigrationQRCodeBatch(accounts []Account, format string, width, height int) ([][]byte, error) {
+	uris, err := migrationURIs(accounts)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode migration qr code: %w", err)
+	}
+
+	result := make([][]byte, 0, len(uris))
+
+	for _, uri := range uris {
+		var buf bytes.Buffer
+
+		if err := encodeQRCode(&buf, uri, format, width, height); err != nil {
+			return nil, err
+		}
+
+		result = append(result, buf.Bytes())
+	}
+
+	return result, nil
+}
+
+// migrationURIs packs the accounts into as few otpauth-migration uris as possible without exceeding
+// maxMigrationURILength.
+func migrationURIs(accounts []Account) ([]string, error) {
+	params := make([][]byte, 0, len(accounts))
+	h := fnv.New32a()
+
+	for i, a := range accounts {
+		b, err := appendMigrationOTPParameters(nil, a)
+		if err != nil {
+			return nil, fmt.Errorf("account #%d %q: %w", i, a.Name, err)
+		}
+
+		_, _ = h.Write(b)
+
+		params = append(params, b)
+	}
+
+	batchID := uint64(h.Sum32() & 0x7fffffff) //nolint: gomnd
+
+	var (
+		batches [][][]byte
+		current [][]byte
+	)
+
+	for _, p := range params {
+		if len(current) > 0 && len(buildMigrationURI(append(current, p), 0, 1, batchID)) > maxMigrationURILength {
+			batches = append(batches, current)
+			current = nil
+		}
+
+		current = append(current, p)
+	}
+
+	batches = append(batches, current)
+	uris := make([]string, 0, len(batches))
+
+	for i, batch := range batches {
+		uris = append(uris, buildMigrationURI(batch, i, len(batches), batchID))
+	}
+
+	return uris, nil
+}
+
+func buildMigrationURI(params [][]byte, batchIndex, batchSize int, batchID uint64) string {
+	var payload []byte
+
+	for _, p := range params {
+		payload = protowire.AppendTag(payload, migrationPayloadOTPParameters, protowire.BytesType)
+		payload = protowire.AppendBytes(payload, p)
+	}
+
+	payload = protowire.AppendTag(payload, migrationPayloadVersion, protowire.VarintType)
+	payload = protowire.AppendVarint(payload, migrationVersion)
+	payload = protowire.AppendTag(payload, migrationPayloadBatchSize, protowire.VarintType)
+	payload = protowire.AppendVarint(payload, uint64(batchSize))
+	payload = protowire.AppendTag(payload, migrationPayloadBatchIndex, protowire.VarintType)
+	payload = protowire.AppendVarint(payload, uint64(batchIndex))
+	payload = protowire.AppendTag(payload, migrationPayloadBatchID, protowire.VarintType)
+	payload = protowire.AppendVarint(payload, batchID)
+
+	return migrationOfflineURI + "?data=" + url.QueryEscape(base64.StdEncoding.EncodeToString(payload))
+}
+
+func appendMigrationOTPParameters(b []byte, account Account) ([]byte, error) {
+	secret, err := decodeTOTPSecret(account.TOTPSecret)
+	if err != nil {
+		return nil, err
+	}
+
+	var algorithm, digits, typ uint64
+
+	switch strings.ToUpper(account.Algorithm) {
+	case "", AlgorithmSHA1:
+		algorithm = migrationAlgorithmSHA1
+
+	case AlgorithmSHA256:
+		algorithm = migrationAlgorithmSHA256
+
+	case AlgorithmSHA512:
+		algorithm = migrationAlgorithmSHA512
+
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrUnsupportedAlgorithm, account.Algorithm)
+	}
+
+	switch account.Digits {
+	case 0, 6: //nolint: gomnd
+		digits = migrationDigitsSix
+
+	case 8: //nolint: gomnd
+		digits = migrationDigitsEight
+
+	default:
+		return nil, fmt.Errorf("%w: %d, must be 6 or 8", ErrInvalidDigits, account.Digits)
+	}
+
+	if account.Period != 0 && time.Duration(account.Period)*time.Second != defaultPeriod { //nolint: gosec
+		return nil, fmt.Errorf("%w: %ds, must be %s", ErrInvalidPeriod, account.Period, defaultPeriod)
+	}
+
+	typ = migrationTypeTOTP
+
+	if account.Type == TypeHOTP {
+		typ = migrationTypeHOTP
+	}
+
+	name := account.Name
+
+	if account.Issuer != "" {
+		name = account.Issuer + ":" + name
+	}
+
+	b = protowire.AppendTag(b, migrationOTPSecret, protowire.BytesType)
+	b = protowire.AppendBytes(b, secret)
+	b = protowire.AppendTag(b, migrationOTPName, protowire.BytesType)
+	b = protowire.AppendString(b, name)
+	b = protowire.AppendTag(b, migrationOTPIssuer, protowire.BytesType)
+	b = protowire.AppendString(b, account.Issuer)
+	b = protowire.AppendTag(b, migrationOTPAlgorithm, protowire.VarintType)
+	b = protowire.AppendVarint(b, algorithm)
+	b = protowire.AppendTag(b, migrationOTPDigits, protowire.VarintType)
+	b = protowire.AppendVarint(b, digits)
+	b = protowire.AppendTag(b, migrationOTPType, protowire.VarintType)
+	b = protowire.AppendVarint(b, typ)
+
+	if account.Type == TypeHOTP {
+		b = protowire.AppendTag(b, migrationOTPCounter, protowire.VarintType)
+		b = protowire.AppendVarint(b, account.Counter)
+	}
+
+	return b, nil
+}
@@ -0,0 +1,197 @@
+package authenticator_test
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go.nhat.io/authenticator"
+)
+
+func TestParseMigrationQRCode_Success(t *testing.T) {
+	t.Parallel()
+
+	actual, err := authenticator.ParseMigrationQRCode("resources/fixtures/valid_migration.png")
+	require.NoError(t, err)
+
+	expected := []authenticator.Account{
+		{
+			Name:       "john.doe@example.com",
+			TOTPSecret: "JBSWY3DPEHPK3PXP",
+			Issuer:     "Example",
+			Digits:     6,
+			Algorithm:  authenticator.AlgorithmSHA1,
+		},
+		{
+			Name:       "hotp-token",
+			TOTPSecret: "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ",
+			Type:       authenticator.TypeHOTP,
+			Counter:    3,
+			Digits:     8,
+			Algorithm:  authenticator.AlgorithmSHA1,
+		},
+		{
+			Name:       "jane.doe@example.com",
+			TOTPSecret: "JBSWY3DPEHPK3PXP",
+			Issuer:     "Other",
+			Digits:     6,
+			Algorithm:  authenticator.AlgorithmSHA512,
+		},
+	}
+
+	assert.Equal(t, expected, actual)
+}
+
+func TestParseMigrationQRCode_FileNotFound(t *testing.T) {
+	t.Parallel()
+
+	actual, err := authenticator.ParseMigrationQRCode("resources/fixtures/unknown.png")
+	require.EqualError(t, err, `failed to qr code file: open resources/fixtures/unknown.png: no such file or directory`)
+	assert.Empty(t, actual)
+}
+
+func TestParseMigrationQRCode_NotMigration(t *testing.T) {
+	t.Parallel()
+
+	actual, err := authenticator.ParseMigrationQRCode("resources/fixtures/valid.png")
+	require.EqualError(t, err, `invalid otpauth-migration uri: otpauth://totp/john.doe@example.com?issuer=example.com&secret=NBSWY3DP`)
+	assert.Empty(t, actual)
+}
+
+func TestParseMigrationQRCode_InvalidPayload(t *testing.T) {
+	t.Parallel()
+
+	actual, err := authenticator.ParseMigrationQRCode("resources/fixtures/invalid_migration_payload.png")
+	require.ErrorIs(t, err, authenticator.ErrInvalidMigrationPayload)
+	assert.Empty(t, actual)
+}
+
+func TestEncodeMigrationQRCode_RoundTrip(t *testing.T) {
+	t.Parallel()
+
+	accounts := []authenticator.Account{
+		{
+			Name:       "john.doe@example.com",
+			TOTPSecret: "JBSWY3DPEHPK3PXP",
+			Issuer:     "Example",
+		},
+		{
+			Name:       "hotp-token",
+			TOTPSecret: "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ",
+			Type:       authenticator.TypeHOTP,
+			Counter:    3,
+			Digits:     8,
+			Algorithm:  "sha256",
+		},
+	}
+
+	buf := new(bytes.Buffer)
+
+	err := authenticator.EncodeMigrationQRCode(buf, accounts, "png", 400, 400)
+	require.NoError(t, err)
+
+	actual, err := authenticator.DecodeMigrationQRCode(buf)
+	require.NoError(t, err)
+
+	expected := []authenticator.Account{
+		{
+			Name:       "john.doe@example.com",
+			TOTPSecret: "JBSWY3DPEHPK3PXP",
+			Issuer:     "Example",
+			Digits:     6,
+			Algorithm:  authenticator.AlgorithmSHA1,
+		},
+		{
+			Name:       "hotp-token",
+			TOTPSecret: "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ",
+			Type:       authenticator.TypeHOTP,
+			Counter:    3,
+			Digits:     8,
+			Algorithm:  authenticator.AlgorithmSHA256,
+		},
+	}
+
+	assert.Equal(t, expected, actual)
+}
+
+func TestEncodeMigrationQRCode_TooLarge(t *testing.T) {
+	t.Parallel()
+
+	accounts := make([]authenticator.Account, 0, 50)
+
+	for i := range 50 {
+		accounts = append(accounts, authenticator.Account{
+			Name:       fmt.Sprintf("john.doe+%d@example.com", i),
+			TOTPSecret: "GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ",
+			Issuer:     "example.com",
+		})
+	}
+
+	err := authenticator.EncodeMigrationQRCode(io.Discard, accounts, "png", 400, 400)
+	require.ErrorIs(t, err, authenticator.ErrMigrationPayloadTooLarge)
+
+	images, err := authenticator.EncodeMigrationQRCodeBatch(accounts, "png", 600, 600)
+	require.NoError(t, err)
+	require.Greater(t, len(images), 1)
+
+	var actual []authenticator.Account
+
+	for _, img := range images {
+		batch, err := authenticator.DecodeMigrationQRCode(bytes.NewReader(img))
+		require.NoError(t, err)
+
+		actual = append(actual, batch...)
+	}
+
+	require.Len(t, actual, len(accounts))
+
+	for i, a := range actual {
+		assert.Equal(t, accounts[i].Name, a.Name)
+		assert.Equal(t, accounts[i].TOTPSecret, a.TOTPSecret)
+		assert.Equal(t, accounts[i].Issuer, a.Issuer)
+	}
+}
+
+func TestEncodeMigrationQRCode_InvalidAccount(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		scenario      string
+		account       authenticator.Account
+		expectedError error
+	}{
+		{
+			scenario:      "invalid secret",
+			account:       authenticator.Account{Name: "john", TOTPSecret: "not base32!"},
+			expectedError: authenticator.ErrInvalidSecret,
+		},
+		{
+			scenario:      "unsupported digits",
+			account:       authenticator.Account{Name: "john", TOTPSecret: "NBSWY3DP", Digits: 7},
+			expectedError: authenticator.ErrInvalidDigits,
+		},
+		{
+			scenario:      "unsupported period",
+			account:       authenticator.Account{Name: "john", TOTPSecret: "NBSWY3DP", Period: 60},
+			expectedError: authenticator.ErrInvalidPeriod,
+		},
+		{
+			scenario:      "unsupported algorithm",
+			account:       authenticator.Account{Name: "john", TOTPSecret: "NBSWY3DP", Algorithm: "MD5"},
+			expectedError: authenticator.ErrUnsupportedAlgorithm,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.scenario, func(t *testing.T) {
+			t.Parallel()
+
+			err := authenticator.EncodeMigrationQRCode(io.Discard, []authenticator.Account{tc.account}, "png", 200, 200)
+			require.ErrorIs(t, err, tc.expectedError)
+		})
+	}
+}
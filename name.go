@@ -0,0 +1,33 @@
+package authenticator
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// ErrInvalidName indicates that a namespace id or an account name is empty or could escape the storage boundaries.
+var ErrInvalidName = errors.New("invalid name")
+
+// validateName rejects the empty names, the names with control characters or path separators, and the "." and ".."
+// names, e.g. "../evil".
+func validateName(name string) error {
+	if name == "" {
+		return fmt.Errorf("%w: must not be empty", ErrInvalidName)
+	}
+
+	if strings.IndexFunc(name, unicode.IsControl) >= 0 {
+		return fmt.Errorf("%w: %q, must not contain control characters", ErrInvalidName, name)
+	}
+
+	if strings.ContainsAny(name, `/\`) {
+		return fmt.Errorf("%w: %q, must not contain path separators", ErrInvalidName, name)
+	}
+
+	if name == "." || name == ".." {
+		return fmt.Errorf("%w: %q, must not be a relative path element", ErrInvalidName, name)
+	}
+
+	return nil
+}
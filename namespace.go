@@ -6,6 +6,7 @@ import (
 	"fmt"
 	"slices"
 	"sort"
+	"strings"
 
 	"go.nhat.io/secretstorage"
 	"go.uber.org/multierr"
@@ -16,14 +17,51 @@ var (
 	ErrNamespaceExists = errors.New("namespace already exists")
 	// ErrNamespaceNotFound indicates that the namespace was not found.
 	ErrNamespaceNotFound = errors.New("namespace not found")
+	// ErrNamespaceListingUnsupported indicates that the namespace storage cannot list the namespaces.
+	ErrNamespaceListingUnsupported = errors.New("namespace storage does not support listing")
 )
 
 var namespaceStorage secretstorage.Storage[Namespace] = secretstorage.NewKeyringStorage[Namespace]()
 
 // Namespace represents a namespace.
+//
+// Digits, Algorithm and Period are the defaults of the accounts in the namespace. They apply when neither the options
+// of GenerateTOTP nor the account set them.
+//
+// Issuers is the index of the account names by issuer, see FindAccountsByIssuer. It is kept up to date when the accounts
+// are stored or deleted.
 type Namespace struct {
-	Name     string   `json:"name" toml:"name" yaml:"name"`
-	Accounts []string `json:"accounts" toml:"accounts" yaml:"accounts"`
+	Name        string              `json:"name" toml:"name" yaml:"name"`
+	Description string              `json:"description" toml:"description" yaml:"description"`
+	Accounts    []string            `json:"accounts" toml:"accounts" yaml:"accounts"`
+	Issuers     map[string][]string `json:"issuers,omitempty" toml:"issuers,omitempty" yaml:"issuers,omitempty"`
+	Digits      int                 `json:"digits,omitempty" toml:"digits,omitempty" yaml:"digits,omitempty"`
+	Algorithm   string              `json:"algorithm,omitempty" toml:"algorithm,omitempty" yaml:"algorithm,omitempty"`
+	Period      uint                `json:"period,omitempty" toml:"period,omitempty" yaml:"period,omitempty"`
+}
+
+// withDefaults sets the parameters that the account does not set to the defaults of the namespace.
+func (c Namespace) withDefaults(a Account) Account {
+	if a.Digits == 0 {
+		a.Digits = c.Digits
+	}
+
+	if a.Algorithm == "" {
+		a.Algorithm = c.Algorithm
+	}
+
+	if a.Period == 0 {
+		a.Period = c.Period
+	}
+
+	return a
+}
+
+// NamespaceInfo is a summary of a namespace.
+type NamespaceInfo struct {
+	ID           string `json:"id" toml:"id" yaml:"id"`
+	Name         string `json:"name" toml:"name" yaml:"name"`
+	AccountCount int    `json:"account_count" toml:"account_count" yaml:"account_count"`
 }
 
 // UnmarshalText implements the encoding.TextUnmarshaler interface.
@@ -54,16 +92,77 @@ func (c Namespace) MarshalText() (text []byte, err error) {
 }
 
 // GetAllNamespaceIDs returns all namespace ids.
-func GetAllNamespaceIDs() ([]string, error) {
+func GetAllNamespaceIDs(opts ...ConfigOption) ([]string, error) {
+	configMu.RLock()
+	defer configMu.RUnlock()
+
+	return loadNamespaceIDs(newConfigOptions(opts...))
+}
+
+// loadNamespaceIDs returns the namespace ids from the config file, or from the namespace storage if the config registry
+// is disabled.
+func loadNamespaceIDs(o configOptions) ([]string, error) {
+	if o.withoutRegistry {
+		return storageNamespaceIDs()
+	}
+
+	return o.store().Load() //nolint: wrapcheck
+}
+
+func storageNamespaceIDs() ([]string, error) {
+	lister, ok := namespaceStorage.(KeyLister)
+	if !ok {
+		return nil, fmt.Errorf("failed to list namespaces: %w", ErrNamespaceListingUnsupported)
+	}
+
+	ids, err := lister.Keys(serviceName)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list namespaces: %w", err)
+	}
+
+	return ids, nil
+}
+
+// ListNamespaces returns the summary of all the namespaces in the config. The namespaces that are in the config but
+// missing in the storage are skipped and reported in an error wrapping ErrNamespaceNotFound, along with the others.
+func ListNamespaces(opts ...ConfigOption) ([]NamespaceInfo, error) {
 	configMu.RLock()
 	defer configMu.RUnlock()
 
-	cfg, err := loadConfigFile()
+	ids, err := loadNamespaceIDs(newConfigOptions(opts...))
 	if err != nil {
 		return nil, err
 	}
 
-	return cfg.Namespaces, nil
+	var (
+		result  = make([]NamespaceInfo, 0, len(ids))
+		missing []string
+	)
+
+	for _, id := range ids {
+		n, err := getNamespace(id)
+		if err != nil {
+			if errors.Is(err, ErrNamespaceNotFound) {
+				missing = append(missing, id)
+
+				continue
+			}
+
+			return nil, err
+		}
+
+		result = append(result, NamespaceInfo{
+			ID:           id,
+			Name:         n.Name,
+			AccountCount: len(n.Accounts),
+		})
+	}
+
+	if len(missing) > 0 {
+		return result, fmt.Errorf("failed to list namespaces: %w: %s", ErrNamespaceNotFound, strings.Join(missing, ", "))
+	}
+
+	return result, nil
 }
 
 func getNamespace(id string) (Namespace, error) {
@@ -76,6 +175,13 @@ func getNamespace(id string) (Namespace, error) {
 		return Namespace{}, fmt.Errorf("failed to get namespace %s: %w", id, err)
 	}
 
+	// The accounts are sorted when they are written, but the storage may not keep the order. The slice is cloned because
+	// it may be shared with the storage, e.g. in memory.
+	if !slices.IsSorted(n.Accounts) {
+		n.Accounts = slices.Clone(n.Accounts)
+		slices.Sort(n.Accounts)
+	}
+
 	return n, nil
 }
 
@@ -87,20 +193,64 @@ func GetNamespace(id string) (Namespace, error) {
 	return getNamespace(id)
 }
 
+// NamespaceExists tells whether the namespace is in the storage. The error is only for the storage failures.
+func NamespaceExists(id string) (bool, error) {
+	configMu.RLock()
+	defer configMu.RUnlock()
+
+	_, err := getNamespace(id)
+	if err != nil {
+		if errors.Is(err, ErrNamespaceNotFound) {
+			return false, nil
+		}
+
+		return false, err
+	}
+
+	return true, nil
+}
+
 // CreateNamespace creates a new namespace.
-func CreateNamespace(id, name string) error {
+func CreateNamespace(id, name string, opts ...ConfigOption) error {
 	configMu.Lock()
 	defer configMu.Unlock()
 
-	cfg, err := loadConfigFile()
+	return createNamespace(newConfigOptions(opts...), id, name)
+}
+
+func createNamespace(o configOptions, id, name string) error {
+	if err := validateName(id); err != nil {
+		return fmt.Errorf("failed to create namespace: %w", err)
+	}
+
+	if o.withoutRegistry {
+		return createStorageNamespace(o, id, name)
+	}
+
+	store := o.store()
+
+	unlock, err := lockConfigStore(store)
 	if err != nil {
 		return err
 	}
 
-	if slices.Contains(cfg.Namespaces, id) {
+	defer unlock()
+
+	ids, err := store.Load()
+	if err != nil {
+		return err //nolint: wrapcheck
+	}
+
+	if slices.Contains(ids, id) {
 		return fmt.Errorf("%w: %s", ErrNamespaceExists, id)
 	}
 
+	if o.caseInsensitiveNamespaces {
+		if err := checkNamespaceCase(ids, id); err != nil {
+			return err
+		}
+	}
+
 	if _, err := getNamespace(id); err == nil {
 		return fmt.Errorf("%w in storage: %s", ErrNamespaceExists, id)
 	}
@@ -110,11 +260,11 @@ func CreateNamespace(id, name string) error {
 		return fmt.Errorf("failed to create namespace %s: %w", id, errors.Unwrap(err))
 	}
 
-	cfg.Namespaces = append(cfg.Namespaces, id)
+	ids = append(ids, id)
 
-	sort.Strings(cfg.Namespaces)
+	sort.Strings(ids)
 
-	err = saveConfigFile(cfg)
+	err = store.Save(ids)
 	if err != nil {
 		// Rollback.
 		if dErr := namespaceStorage.Delete(serviceName, id); dErr != nil {
@@ -125,6 +275,39 @@ func CreateNamespace(id, name string) error {
 	return err
 }
 
+// createStorageNamespace creates the namespace in the storage only, when the config registry is disabled.
+func createStorageNamespace(o configOptions, id, name string) error {
+	if o.caseInsensitiveNamespaces {
+		ids, err := storageNamespaceIDs()
+		if err != nil {
+			return err
+		}
+
+		if err := checkNamespaceCase(ids, id); err != nil {
+			return err
+		}
+	}
+
+	if _, err := getNamespace(id); err == nil {
+		return fmt.Errorf("%w: %s", ErrNamespaceExists, id)
+	}
+
+	if err := updateNamespace(id, Namespace{Name: name}); err != nil {
+		return fmt.Errorf("failed to create namespace %s: %w", id, errors.Unwrap(err))
+	}
+
+	return nil
+}
+
+// checkNamespaceCase returns ErrNamespaceExists if one of the ids is a case variant of the given one.
+func checkNamespaceCase(ids []string, id string) error {
+	if i := slices.IndexFunc(ids, func(s string) bool { return s != id && strings.EqualFold(s, id) }); i >= 0 {
+		return fmt.Errorf("%w: %s, differs only by case from %s", ErrNamespaceExists, id, ids[i])
+	}
+
+	return nil
+}
+
 func updateNamespace(id string, n Namespace) error {
 	err := namespaceStorage.Set(serviceName, id, n)
 	if err != nil {
@@ -142,22 +325,54 @@ func UpdateNamespace(id string, n Namespace) error {
 	return updateNamespace(id, n)
 }
 
-func deleteNamespace(id string) error {
-	cfg, err := loadConfigFile()
+// DeletedReport tells what DeleteNamespaceWithReport deleted.
+type DeletedReport struct {
+	// ID is the id of the namespace.
+	ID string
+	// Name is the name of the namespace, if it was found in the storage.
+	Name string
+	// Accounts are the accounts that were deleted. The accounts that were already missing from the storage are not
+	// reported.
+	Accounts []string
+	// FailedAccounts are the accounts that could not be deleted.
+	FailedAccounts []string
+}
+
+func deleteNamespace(o configOptions, id string, report *DeletedReport) error {
+	if o.withoutRegistry {
+		return deleteStorageNamespace(id, report)
+	}
+
+	store := o.store()
+
+	unlock, err := lockConfigStore(store)
 	if err != nil {
 		return err
 	}
 
-	if slices.Contains(cfg.Namespaces, id) {
-		cfg.Namespaces = slices.DeleteFunc(cfg.Namespaces, func(s string) bool {
+	defer unlock()
+
+	ids, err := store.Load()
+	if err != nil {
+		return err //nolint: wrapcheck
+	}
+
+	if slices.Contains(ids, id) {
+		ids = slices.DeleteFunc(ids, func(s string) bool {
 			return s == id
 		})
 
-		if err := saveConfigFile(cfg); err != nil {
+		if err := store.Save(ids); err != nil {
 			return fmt.Errorf("failed to delete namespace: %w", err)
 		}
 	}
 
+	return deleteStorageNamespace(id, report)
+}
+
+// deleteStorageNamespace deletes the namespace and its accounts from the storage. It carries on when an account cannot
+// be deleted and returns the combined errors.
+func deleteStorageNamespace(id string, report *DeletedReport) error {
 	n, err := getNamespace(id)
 	if err != nil {
 		if errors.Is(err, ErrNamespaceNotFound) {
@@ -167,26 +382,71 @@ func deleteNamespace(id string) error {
 		return fmt.Errorf("failed to get namespace for deletion: %w", errors.Unwrap(err))
 	}
 
+	report.Name = n.Name
+
 	err = namespaceStorage.Delete(serviceName, id)
 	if err != nil {
 		return fmt.Errorf("failed to delete namespace: %w", err)
 	}
 
+	var errs error
+
 	for _, account := range n.Accounts {
-		if err := deleteAccount(id, account); err != nil && !errors.Is(err, secretstorage.ErrNotFound) {
-			return fmt.Errorf("failed to delete account %s: %w", account, errors.Unwrap(err))
+		dErr := deleteAccount(id, account)
+
+		switch {
+		case dErr == nil:
+			report.Accounts = append(report.Accounts, account)
+
+		case !errors.Is(dErr, secretstorage.ErrNotFound):
+			report.FailedAccounts = append(report.FailedAccounts, account)
+			errs = multierr.Append(errs, fmt.Errorf("failed to delete account %s: %w", account, errors.Unwrap(dErr)))
 		}
 	}
 
-	return nil
+	return errs
 }
 
 // DeleteNamespace deletes a namespace.
-func DeleteNamespace(id string) error {
+func DeleteNamespace(id string, opts ...ConfigOption) error {
+	_, err := DeleteNamespaceWithReport(id, opts...)
+
+	return err
+}
+
+// DeleteNamespaceWithReport deletes a namespace like DeleteNamespace, and reports the accounts that were deleted and the
+// ones that could not be, e.g. for an audit log. The report is returned even if there is an error.
+func DeleteNamespaceWithReport(id string, opts ...ConfigOption) (DeletedReport, error) {
+	configMu.Lock()
+	defer configMu.Unlock()
+
+	report := DeletedReport{ID: id}
+
+	err := deleteNamespace(newConfigOptions(opts...), id, &report)
+
+	return report, err
+}
+
+// DeleteAllNamespaces deletes all the namespaces in the config and their accounts. It carries on when a namespace cannot
+// be deleted and returns the combined errors.
+func DeleteAllNamespaces(opts ...ConfigOption) error {
 	configMu.Lock()
 	defer configMu.Unlock()
 
-	return deleteNamespace(id)
+	o := newConfigOptions(opts...)
+
+	ids, err := loadNamespaceIDs(o)
+	if err != nil {
+		return err
+	}
+
+	for _, id := range ids {
+		if dErr := deleteNamespace(o, id, &DeletedReport{}); dErr != nil {
+			err = multierr.Append(err, fmt.Errorf("failed to delete namespace %s: %w", id, dErr))
+		}
+	}
+
+	return err
 }
 
 // SetNamespaceStorage sets the namespace storage.
@@ -198,6 +458,9 @@ func SetNamespaceStorage(s secretstorage.Storage[Namespace]) func() {
 	namespaceStorage = s
 
 	return func() {
+		configMu.Lock()
+		defer configMu.Unlock()
+
 		namespaceStorage = ns
 	}
 }
@@ -3,6 +3,8 @@ package authenticator_test
 import (
 	"encoding/json"
 	"fmt"
+	"os"
+	"path/filepath"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -16,8 +18,9 @@ import (
 
 func TestNamespace_Marshal(t *testing.T) {
 	expected := authenticator.Namespace{
-		Name:     "namespace",
-		Accounts: []string{"john.doe@example.com"},
+		Name:        "namespace",
+		Description: "Accounts of the namespace",
+		Accounts:    []string{"john.doe@example.com"},
 	}
 
 	data, err := json.Marshal(expected)
@@ -31,6 +34,22 @@ func TestNamespace_Marshal(t *testing.T) {
 	require.Equal(t, expected, actual)
 }
 
+func TestNamespace_UnmarshalText_WithoutDescription(t *testing.T) {
+	t.Parallel()
+
+	var actual authenticator.Namespace
+
+	err := json.Unmarshal([]byte(`"{\"name\": \"namespace\", \"accounts\": [\"john.doe@example.com\"]}"`), &actual)
+	require.NoError(t, err)
+
+	expected := authenticator.Namespace{
+		Name:     "namespace",
+		Accounts: []string{"john.doe@example.com"},
+	}
+
+	assert.Equal(t, expected, actual)
+}
+
 func TestNamespace_UnmarshalText_Error(t *testing.T) {
 	t.Parallel()
 
@@ -94,6 +113,23 @@ func TestGetNamespace_Success(t *testing.T) {
 	require.Equal(t, expected, actual)
 }
 
+func TestGetNamespace_SortsAccounts(t *testing.T) {
+	setNamespaceStorage(t, func(s *mockss.Storage[authenticator.Namespace]) {
+		s.On("Get", "go.nhat.io/authenticator", t.Name()).
+			Return(authenticator.Namespace{
+				Name:     t.Name(),
+				Accounts: []string{"john.doe@example.com", "alice@example.com", "jane.doe@example.com"},
+			}, nil)
+	})
+
+	actual, err := authenticator.GetNamespace(t.Name())
+	require.NoError(t, err)
+
+	expected := []string{"alice@example.com", "jane.doe@example.com", "john.doe@example.com"}
+
+	assert.Equal(t, expected, actual.Accounts)
+}
+
 func TestGetNamespace_NotFound(t *testing.T) {
 	setNamespaceStorage(t, func(s *mockss.Storage[authenticator.Namespace]) {
 		s.On("Get", "go.nhat.io/authenticator", t.Name()).
@@ -170,6 +206,55 @@ func TestCreateNamespace_FailedToCreate(t *testing.T) {
 	require.EqualError(t, err, `failed to create namespace TestCreateNamespace_FailedToCreate: assert.AnError general error for testing`)
 }
 
+func TestCreateNamespace_CaseVariant(t *testing.T) {
+	testCases := []struct {
+		scenario      string
+		options       []authenticator.ConfigOption
+		expectedIDs   []string
+		expectedError string
+	}{
+		{
+			scenario:    "case sensitive by default",
+			expectedIDs: []string{"Work", "work"},
+		},
+		{
+			scenario:    "case sensitive",
+			options:     []authenticator.ConfigOption{authenticator.WithCaseSensitiveNamespaces(true)},
+			expectedIDs: []string{"Work", "work"},
+		},
+		{
+			scenario:      "case insensitive",
+			options:       []authenticator.ConfigOption{authenticator.WithCaseSensitiveNamespaces(false)},
+			expectedIDs:   []string{"work"},
+			expectedError: "namespace already exists: Work, differs only by case from work",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.scenario, func(t *testing.T) {
+			setConfigFile(t)
+
+			useInMemoryStorage(t)
+
+			err := authenticator.CreateNamespace("work", "Work", tc.options...)
+			require.NoError(t, err)
+
+			err = authenticator.CreateNamespace("Work", "Work", tc.options...)
+
+			if tc.expectedError != "" {
+				require.ErrorIs(t, err, authenticator.ErrNamespaceExists)
+				require.EqualError(t, err, tc.expectedError)
+			} else {
+				require.NoError(t, err)
+			}
+
+			actual, err := authenticator.GetAllNamespaceIDs()
+			require.NoError(t, err)
+			assert.Equal(t, tc.expectedIDs, actual)
+		})
+	}
+}
+
 func TestUpdateNamespace_Success(t *testing.T) {
 	setNamespaceStorage(t, func(s *mockss.Storage[authenticator.Namespace]) {
 		s.On("Set", "go.nhat.io/authenticator", t.Name(), authenticator.Namespace{
@@ -186,6 +271,24 @@ func TestUpdateNamespace_Success(t *testing.T) {
 	require.NoError(t, err)
 }
 
+func TestUpdateNamespace_Description(t *testing.T) {
+	setNamespaceStorage(t, func(s *mockss.Storage[authenticator.Namespace]) {
+		s.On("Set", "go.nhat.io/authenticator", t.Name(), authenticator.Namespace{
+			Name:        t.Name(),
+			Description: "Production accounts",
+			Accounts:    []string{"john.doe@example.com"},
+		}).
+			Return(nil)
+	})
+
+	err := authenticator.UpdateNamespace(t.Name(), authenticator.Namespace{
+		Name:        t.Name(),
+		Description: "Production accounts",
+		Accounts:    []string{"john.doe@example.com"},
+	})
+	require.NoError(t, err)
+}
+
 func TestUpdateNamespace_Failed(t *testing.T) {
 	setNamespaceStorage(t, func(s *mockss.Storage[authenticator.Namespace]) {
 		s.On("Set", "go.nhat.io/authenticator", t.Name(), mock.Anything).
@@ -210,7 +313,7 @@ func TestDeleteNamespace_NoAccounts_FailedToLoadConfig(t *testing.T) {
 	setConfigFileWithContent(t, "{")
 
 	err := authenticator.DeleteNamespace(t.Name())
-	require.EqualError(t, err, `failed to decode config file: toml: invalid character at start of key: {`)
+	require.EqualError(t, err, `failed to decode config: toml: invalid character at start of key: {`)
 }
 
 func TestDeleteNamespace_NoAccounts_FailedToLoadNamespaceConfig(t *testing.T) {
@@ -367,6 +470,371 @@ func TestDeleteNamespace_HasAccounts_FailedToDeleteAccount(t *testing.T) {
 	require.EqualError(t, err, `failed to delete account john.doe@example.com: assert.AnError general error for testing`)
 }
 
+func TestDeleteNamespaceWithReport_Success(t *testing.T) {
+	setConfigFile(t)
+
+	useInMemoryStorage(t)
+
+	err := authenticator.CreateNamespace("work", "Work")
+	require.NoError(t, err)
+
+	for _, name := range []string{"john@work.com", "jane@work.com"} {
+		err = authenticator.SetAccount("work", authenticator.Account{Name: name, TOTPSecret: "NBSWY3DP"})
+		require.NoError(t, err)
+	}
+
+	actual, err := authenticator.DeleteNamespaceWithReport("work")
+	require.NoError(t, err)
+
+	expected := authenticator.DeletedReport{
+		ID:       "work",
+		Name:     "Work",
+		Accounts: []string{"jane@work.com", "john@work.com"},
+	}
+
+	assert.Equal(t, expected, actual)
+
+	_, err = authenticator.GetNamespace("work")
+	require.ErrorIs(t, err, authenticator.ErrNamespaceNotFound)
+}
+
+func TestDeleteNamespaceWithReport_FailedToDeleteAccount(t *testing.T) {
+	setConfigFileWithContent(t, fmt.Sprintf(`namespaces = [%q]`, t.Name()))
+
+	setNamespaceStorage(t, func(s *mockss.Storage[authenticator.Namespace]) {
+		s.On("Get", "go.nhat.io/authenticator", t.Name()).
+			Return(authenticator.Namespace{
+				Name:     "Namespace",
+				Accounts: []string{"jane.doe@example.com", "john.doe@example.com", "missing@example.com"},
+			}, nil)
+
+		s.On("Delete", "go.nhat.io/authenticator", t.Name()).
+			Return(nil)
+	})
+
+	setAccountStorage(t, func(s *mockss.Storage[authenticator.Account]) {
+		s.On("Delete", "go.nhat.io/authenticator", "TestDeleteNamespaceWithReport_FailedToDeleteAccount/jane.doe@example.com").
+			Return(assert.AnError)
+
+		s.On("Delete", "go.nhat.io/authenticator", "TestDeleteNamespaceWithReport_FailedToDeleteAccount/john.doe@example.com").
+			Return(nil)
+
+		s.On("Delete", "go.nhat.io/authenticator", "TestDeleteNamespaceWithReport_FailedToDeleteAccount/missing@example.com").
+			Return(secretstorage.ErrNotFound)
+	})
+
+	actual, err := authenticator.DeleteNamespaceWithReport(t.Name())
+	require.EqualError(t, err, `failed to delete account jane.doe@example.com: assert.AnError general error for testing`)
+
+	expected := authenticator.DeletedReport{
+		ID:             t.Name(),
+		Name:           "Namespace",
+		Accounts:       []string{"john.doe@example.com"},
+		FailedAccounts: []string{"jane.doe@example.com"},
+	}
+
+	assert.Equal(t, expected, actual)
+}
+
+func TestNamespace_WithoutConfigRegistry(t *testing.T) {
+	setConfigFile(t)
+
+	useInMemoryStorage(t)
+
+	opt := authenticator.WithoutConfigRegistry()
+
+	err := authenticator.CreateNamespace("work", "Work", opt)
+	require.NoError(t, err)
+
+	err = authenticator.CreateNamespace("personal", "Personal", opt)
+	require.NoError(t, err)
+
+	err = authenticator.CreateNamespace("work", "Work", opt)
+	require.ErrorIs(t, err, authenticator.ErrNamespaceExists)
+
+	err = authenticator.SetAccount("work", authenticator.Account{Name: "john.doe@example.com", TOTPSecret: "NBSWY3DP"})
+	require.NoError(t, err)
+
+	actual, err := authenticator.GetAllNamespaceIDs(opt)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"personal", "work"}, actual)
+
+	// The config file is not used.
+	_, err = os.Stat(os.Getenv("AUTHENTICATOR_CONFIG"))
+	require.ErrorIs(t, err, os.ErrNotExist)
+
+	err = authenticator.DeleteNamespace("work", opt)
+	require.NoError(t, err)
+
+	_, err = authenticator.GetAccount("work", "john.doe@example.com")
+	require.ErrorIs(t, err, authenticator.ErrAccountNotFound)
+
+	actual, err = authenticator.GetAllNamespaceIDs(opt)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"personal"}, actual)
+}
+
+func TestGetAllNamespaceIDs_WithoutConfigRegistry_ListingUnsupported(t *testing.T) {
+	setNamespaceStorage(t, func(*mockss.Storage[authenticator.Namespace]) {})
+
+	actual, err := authenticator.GetAllNamespaceIDs(authenticator.WithoutConfigRegistry())
+	require.ErrorIs(t, err, authenticator.ErrNamespaceListingUnsupported)
+	assert.Nil(t, actual)
+}
+
+func TestNamespaceExists(t *testing.T) {
+	testCases := []struct {
+		scenario       string
+		mockStorage    func(s *mockss.Storage[authenticator.Namespace])
+		expectedResult bool
+		expectedError  string
+	}{
+		{
+			scenario: "exists",
+			mockStorage: func(s *mockss.Storage[authenticator.Namespace]) {
+				s.On("Get", "go.nhat.io/authenticator", "namespace").
+					Return(authenticator.Namespace{Name: "Namespace"}, nil)
+			},
+			expectedResult: true,
+		},
+		{
+			scenario: "not found",
+			mockStorage: func(s *mockss.Storage[authenticator.Namespace]) {
+				s.On("Get", "go.nhat.io/authenticator", "namespace").
+					Return(authenticator.Namespace{}, secretstorage.ErrNotFound)
+			},
+		},
+		{
+			scenario: "error",
+			mockStorage: func(s *mockss.Storage[authenticator.Namespace]) {
+				s.On("Get", "go.nhat.io/authenticator", "namespace").
+					Return(authenticator.Namespace{}, assert.AnError)
+			},
+			expectedError: `failed to get namespace namespace: assert.AnError general error for testing`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.scenario, func(t *testing.T) {
+			setNamespaceStorage(t, tc.mockStorage)
+
+			actual, err := authenticator.NamespaceExists("namespace")
+
+			if tc.expectedError != "" {
+				require.EqualError(t, err, tc.expectedError)
+			} else {
+				require.NoError(t, err)
+			}
+
+			assert.Equal(t, tc.expectedResult, actual)
+		})
+	}
+}
+
+func TestCreateNamespace_InvalidName(t *testing.T) {
+	t.Parallel()
+
+	for _, id := range []string{"", "..", "../evil", "./evil", "work/evil", `work\evil`, "work\nevil", "work\tevil"} {
+		t.Run(id, func(t *testing.T) {
+			t.Parallel()
+
+			err := authenticator.CreateNamespace(id, "Evil")
+			require.ErrorIs(t, err, authenticator.ErrInvalidName)
+		})
+	}
+}
+
+func TestNamespace_InMemoryConfigStore(t *testing.T) {
+	setConfigFile(t)
+
+	store := authenticator.NewInMemoryConfigStore()
+
+	t.Cleanup(authenticator.SetConfigStore(store))
+	useInMemoryStorage(t)
+
+	err := authenticator.CreateNamespace("work", "Work")
+	require.NoError(t, err)
+
+	err = authenticator.CreateNamespace("personal", "Personal")
+	require.NoError(t, err)
+
+	err = authenticator.CreateNamespace("work", "Work")
+	require.ErrorIs(t, err, authenticator.ErrNamespaceExists)
+
+	actual, err := store.Load()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"personal", "work"}, actual)
+
+	err = authenticator.DeleteNamespace("work")
+	require.NoError(t, err)
+
+	actual, err = authenticator.GetAllNamespaceIDs()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"personal"}, actual)
+
+	// The config file is not used.
+	_, err = os.Stat(os.Getenv("AUTHENTICATOR_CONFIG"))
+	require.ErrorIs(t, err, os.ErrNotExist)
+}
+
+func TestDeleteAllNamespaces_PartialFailure(t *testing.T) {
+	setConfigFileWithContent(t, `namespaces = ["personal", "work"]`)
+
+	setNamespaceStorage(t, func(s *mockss.Storage[authenticator.Namespace]) {
+		s.On("Get", "go.nhat.io/authenticator", "personal").
+			Return(authenticator.Namespace{Name: "Personal", Accounts: []string{"john.doe@example.com"}}, nil)
+
+		s.On("Delete", "go.nhat.io/authenticator", "personal").
+			Return(nil)
+
+		s.On("Get", "go.nhat.io/authenticator", "work").
+			Return(authenticator.Namespace{Name: "Work", Accounts: []string{"jane@work.com"}}, nil)
+
+		s.On("Delete", "go.nhat.io/authenticator", "work").
+			Return(nil)
+	})
+
+	setAccountStorage(t, func(s *mockss.Storage[authenticator.Account]) {
+		s.On("Delete", "go.nhat.io/authenticator", "personal/john.doe@example.com").Once().
+			Return(nil)
+
+		s.On("Delete", "go.nhat.io/authenticator", "work/jane@work.com").Once().
+			Return(assert.AnError)
+	})
+
+	err := authenticator.DeleteAllNamespaces()
+	require.EqualError(t, err, `failed to delete namespace work: failed to delete account jane@work.com: assert.AnError general error for testing`)
+
+	actual, err := authenticator.GetAllNamespaceIDs()
+	require.NoError(t, err)
+	assert.Empty(t, actual)
+}
+
+func TestDeleteAllNamespaces_Success(t *testing.T) {
+	setConfigFile(t)
+
+	useInMemoryStorage(t)
+
+	for _, id := range []string{"personal", "work"} {
+		err := authenticator.CreateNamespace(id, id)
+		require.NoError(t, err)
+
+		err = authenticator.SetAccount(id, authenticator.Account{Name: "john.doe@example.com", TOTPSecret: "NBSWY3DP"})
+		require.NoError(t, err)
+	}
+
+	err := authenticator.DeleteAllNamespaces()
+	require.NoError(t, err)
+
+	actual, err := authenticator.GetAllNamespaceIDs()
+	require.NoError(t, err)
+	assert.Empty(t, actual)
+
+	for _, id := range []string{"personal", "work"} {
+		_, err := authenticator.GetNamespace(id)
+		require.ErrorIs(t, err, authenticator.ErrNamespaceNotFound)
+
+		_, err = authenticator.GetAccount(id, "john.doe@example.com")
+		require.ErrorIs(t, err, authenticator.ErrAccountNotFound)
+	}
+}
+
+func TestNamespace_WithConfigFile(t *testing.T) {
+	setConfigFile(t)
+
+	dir := t.TempDir()
+	first := authenticator.WithConfigFile(filepath.Join(dir, "first.toml"))
+	second := authenticator.WithConfigFile(filepath.Join(dir, "second.toml"))
+
+	useInMemoryStorage(t)
+
+	err := authenticator.CreateNamespace("first", "First", first)
+	require.NoError(t, err)
+
+	err = authenticator.CreateNamespace("second", "Second", second)
+	require.NoError(t, err)
+
+	actual, err := authenticator.GetAllNamespaceIDs(first)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"first"}, actual)
+
+	actual, err = authenticator.GetAllNamespaceIDs(second)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"second"}, actual)
+
+	actual, err = authenticator.GetAllNamespaceIDs()
+	require.NoError(t, err)
+	assert.Empty(t, actual)
+
+	err = authenticator.DeleteNamespace("first", first)
+	require.NoError(t, err)
+
+	actual, err = authenticator.GetAllNamespaceIDs(first)
+	require.NoError(t, err)
+	assert.Empty(t, actual)
+
+	actual, err = authenticator.GetAllNamespaceIDs(second)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"second"}, actual)
+}
+
+func TestListNamespaces_Success(t *testing.T) {
+	setConfigFileWithContent(t, `namespaces = ["personal", "work"]`)
+
+	setNamespaceStorage(t, func(s *mockss.Storage[authenticator.Namespace]) {
+		s.On("Get", "go.nhat.io/authenticator", "personal").
+			Return(authenticator.Namespace{Name: "Personal", Accounts: []string{"john.doe@example.com"}}, nil)
+
+		s.On("Get", "go.nhat.io/authenticator", "work").
+			Return(authenticator.Namespace{Name: "Work", Accounts: []string{"jane@work.com", "john@work.com", "ops@work.com"}}, nil)
+	})
+
+	actual, err := authenticator.ListNamespaces()
+	require.NoError(t, err)
+
+	expected := []authenticator.NamespaceInfo{
+		{ID: "personal", Name: "Personal", AccountCount: 1},
+		{ID: "work", Name: "Work", AccountCount: 3},
+	}
+
+	assert.Equal(t, expected, actual)
+}
+
+func TestListNamespaces_MissingInStorage(t *testing.T) {
+	setConfigFileWithContent(t, `namespaces = ["missing", "work"]`)
+
+	setNamespaceStorage(t, func(s *mockss.Storage[authenticator.Namespace]) {
+		s.On("Get", "go.nhat.io/authenticator", "missing").
+			Return(authenticator.Namespace{}, secretstorage.ErrNotFound)
+
+		s.On("Get", "go.nhat.io/authenticator", "work").
+			Return(authenticator.Namespace{Name: "Work"}, nil)
+	})
+
+	actual, err := authenticator.ListNamespaces()
+	require.ErrorIs(t, err, authenticator.ErrNamespaceNotFound)
+	require.EqualError(t, err, `failed to list namespaces: namespace not found: missing`)
+
+	expected := []authenticator.NamespaceInfo{
+		{ID: "work", Name: "Work", AccountCount: 0},
+	}
+
+	assert.Equal(t, expected, actual)
+}
+
+func TestListNamespaces_Failed(t *testing.T) {
+	setConfigFileWithContent(t, `namespaces = ["work"]`)
+
+	setNamespaceStorage(t, func(s *mockss.Storage[authenticator.Namespace]) {
+		s.On("Get", "go.nhat.io/authenticator", "work").
+			Return(authenticator.Namespace{}, assert.AnError)
+	})
+
+	actual, err := authenticator.ListNamespaces()
+	require.EqualError(t, err, `failed to get namespace work: assert.AnError general error for testing`)
+	assert.Nil(t, actual)
+}
+
 func setNamespaceStorage(t *testing.T, mocks ...func(s *mockss.Storage[authenticator.Namespace])) {
 	t.Helper()
 
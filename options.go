@@ -24,3 +24,27 @@ func WithLogger(logger ctxd.Logger) Option {
 		}),
 	}
 }
+
+// IssuerOption is an option for both the imports and the QR codes.
+type IssuerOption interface {
+	ImportOption
+	QRCodeOption
+}
+
+type issuerOption struct {
+	ImportOption
+	QRCodeOption
+}
+
+// WithDefaultIssuer sets the issuer of the accounts that have none. The imported accounts are stored with it, while the
+// QR codes only use it in the otpauth uri, the account is not changed.
+func WithDefaultIssuer(issuer string) IssuerOption {
+	return issuerOption{
+		ImportOption: importOptionFunc(func(cfg *importConfig) {
+			cfg.defaultIssuer = issuer
+		}),
+		QRCodeOption: qrCodeOptionFunc(func(cfg *qrCodeConfig) {
+			cfg.defaultIssuer = issuer
+		}),
+	}
+}
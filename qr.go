@@ -1,19 +1,34 @@
 package authenticator
 
 import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
 	"fmt"
 	"image"
+	"image/color"
+	"image/draw"
+	"image/gif"
 	"image/jpeg"
 	"image/png"
 	"io"
 	"net/url"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"time"
 
+	"github.com/HugoSmits86/nativewebp"
 	"github.com/makiuchi-d/gozxing"
+	"github.com/makiuchi-d/gozxing/aztec"
+	"github.com/makiuchi-d/gozxing/datamatrix"
 	"github.com/makiuchi-d/gozxing/qrcode"
+	"github.com/makiuchi-d/gozxing/qrcode/decoder"
 	"go.nhat.io/otp"
+	"go.uber.org/multierr"
+	_ "golang.org/x/image/tiff" // Registers the tiff decoder.
 )
 
 var (
@@ -21,16 +36,42 @@ var (
 	ErrUnknownFormat = fmt.Errorf("unknown format")
 	// ErrUnsupportedFormat indicates that the format is unsupported.
 	ErrUnsupportedFormat = fmt.Errorf("unsupported format")
+	// ErrUnsupportedImageFormat indicates that the image to decode is not in a supported format.
+	ErrUnsupportedImageFormat = fmt.Errorf("unsupported image format")
+	// ErrCorruptImage indicates that the image to decode is truncated or corrupted, e.g. a partial upload.
+	ErrCorruptImage = fmt.Errorf("corrupt image")
+	// ErrInvalidOTPAuthScheme indicates that the content is not an otpauth uri, e.g. a QR code of a website.
+	ErrInvalidOTPAuthScheme = fmt.Errorf("invalid totpauth uri")
+	// ErrMalformedOTPAuthURI indicates that the content looks like an otpauth uri but cannot be parsed.
+	ErrMalformedOTPAuthURI = fmt.Errorf("malformed otpauth uri")
 )
 
 const (
-	totpAuthProtocol    = "otpauth://totp/"
-	totpAuthSecretParam = "secret"
-	totpAuthIssuerParam = "issuer"
+	totpAuthProtocol     = "otpauth://totp/"
+	hotpAuthProtocol     = "otpauth://hotp/"
+	totpAuthSecretParam  = "secret"
+	totpAuthIssuerParam  = "issuer"
+	totpAuthCounterParam = "counter"
+	totpAuthDigitsParam  = "digits"
+	totpAuthAlgoParam    = "algorithm"
+	totpAuthPeriodParam  = "period"
+	totpAuthImageParam   = "image"
 )
 
-// ParseTOTPQRCode decodes a TOTP QR code from the given file path.
-func ParseTOTPQRCode(path string) (Account, error) {
+// stdin is where ParseTOTPQRCode reads the image when the path is "-".
+var stdin io.Reader = os.Stdin
+
+// ParseTOTPQRCode decodes a TOTP QR code from the given file path. The path "-" reads the image from the standard input.
+func ParseTOTPQRCode(path string, opts ...DecodeQRCodeOption) (Account, error) {
+	if path == "-" {
+		account, err := DecodeTOTPQRCode(stdin, opts...)
+		if err != nil {
+			return Account{}, fmt.Errorf("failed to read qr code from stdin: %w", err)
+		}
+
+		return account, nil
+	}
+
 	f, err := os.Open(filepath.Clean(path))
 	if err != nil {
 		return Account{}, fmt.Errorf("failed to qr code file: %w", err)
@@ -38,89 +79,493 @@ func ParseTOTPQRCode(path string) (Account, error) {
 
 	defer f.Close() //nolint: errcheck,gosec
 
-	return DecodeTOTPQRCode(f)
+	return DecodeTOTPQRCode(f, opts...)
+}
+
+// ParseTOTPQRCodeBytes parses a TOTP QR code from the given image data.
+func ParseTOTPQRCodeBytes(data []byte, opts ...DecodeQRCodeOption) (Account, error) {
+	return DecodeTOTPQRCode(bytes.NewReader(data), opts...)
 }
 
-// GenerateTOTPQRCode generates a TOTP QR code for the given account.
+// GenerateTOTPQRCode generates a TOTP QR code for the given account. The format is taken from the file extension, e.g.
+// png, jpg, gif, webp or svg. An existing file is overwritten. Use GenerateTOTPQRCodeWithOptions for the other options.
 func GenerateTOTPQRCode(path string, account Account, width, height int, listOfHints ...map[gozxing.EncodeHintType]any) error {
-	f, err := os.OpenFile(filepath.Clean(path), os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0o600) //nolint: gomnd
+	return GenerateTOTPQRCodeWithOptions(path, account, width, height, qrHintsOptions(listOfHints)...)
+}
+
+// GenerateTOTPQRCodeWithOptions generates a TOTP QR code for the given account like GenerateTOTPQRCode, with the
+// options, e.g. WithQRColors. An existing file is overwritten, unless WithNoOverwrite is given.
+//
+// The QR code is encoded before the file is created, and the file is removed if it cannot be written, so a failure does
+// not leave an empty file behind.
+func GenerateTOTPQRCodeWithOptions(path string, account Account, width, height int, opts ...QRCodeOption) error {
+	var buf bytes.Buffer
+
+	if err := EncodeTOTPQRCodeWithOptions(&buf, account, strings.TrimPrefix(filepath.Ext(path), "."), width, height, opts...); err != nil {
+		return err
+	}
+
+	flag := os.O_WRONLY | os.O_CREATE | os.O_TRUNC
+
+	if newQRCodeConfig(opts...).noOverwrite {
+		flag = os.O_WRONLY | os.O_CREATE | os.O_EXCL
+	}
+
+	path = filepath.Clean(path)
+
+	f, err := os.OpenFile(path, flag, 0o600) //nolint: gomnd
 	if err != nil {
 		return fmt.Errorf("failed to create qr code file: %w", err)
 	}
 
-	defer f.Close() //nolint: errcheck,gosec
+	_, err = buf.WriteTo(f)
+	err = multierr.Combine(err, f.Close())
+
+	if err != nil {
+		_ = os.Remove(path) //nolint: errcheck
+
+		return fmt.Errorf("failed to write qr code file: %w", err)
+	}
 
-	return EncodeTOTPQRCode(f, account, strings.TrimPrefix(filepath.Ext(path), "."), width, height, listOfHints...)
+	return nil
 }
 
-// DecodeTOTPQRCode decodes a TOTP QR code from the given file path.
-func DecodeTOTPQRCode(r io.Reader) (Account, error) {
-	img, _, err := image.Decode(r)
+// DecodeTOTPQRCode decodes a TOTP QR code from the given image. The image can be png, jpeg, gif, webp or tiff, and heic
+// when built with the heic tag.
+func DecodeTOTPQRCode(r io.Reader, opts ...DecodeQRCodeOption) (Account, error) {
+	_, account, err := DecodeTOTPQRCodeRaw(r, opts...)
+
+	return account, err
+}
+
+// DecodeTOTPQRCodeRaw decodes a TOTP QR code and returns its text content alongside the parsed account. The content is
+// returned even if it is not a valid otpauth uri, in which case the account is empty.
+func DecodeTOTPQRCodeRaw(r io.Reader, opts ...DecodeQRCodeOption) (string, Account, error) {
+	content, err := decodeQRCode(r, opts...)
 	if err != nil {
-		return Account{}, fmt.Errorf("failed to decode image: %w", err)
+		return "", Account{}, err
 	}
 
-	bmp, _ := gozxing.NewBinaryBitmapFromImage(img) //nolint: errcheck
-	qrReader := qrcode.NewQRCodeReader()
+	account, err := ParseOTPAuthURI(content)
+	if err != nil {
+		return content, Account{}, err
+	}
+
+	return content, account, nil
+}
+
+// DecodeTOTPQRCodeMultiFormat decodes a TOTP code from the given image like DecodeTOTPQRCode, but also accepts the Data
+// Matrix and Aztec codes. It is slower because the readers are tried one after another.
+func DecodeTOTPQRCodeMultiFormat(r io.Reader, opts ...DecodeQRCodeOption) (Account, error) {
+	readers := []gozxing.Reader{qrcode.NewQRCodeReader(), datamatrix.NewDataMatrixReader(), aztec.NewAztecReader()}
 
-	result, err := qrReader.Decode(bmp, nil)
+	content, err := decodeCode(r, readers, opts...)
 	if err != nil {
-		return Account{}, fmt.Errorf("failed to decode qr code: %w", err)
+		return Account{}, err
 	}
 
-	if !strings.Contains(result.String(), totpAuthProtocol) {
-		return Account{}, fmt.Errorf("invalid totpauth uri: %s", result.String()) //nolint: goerr113
+	return ParseOTPAuthURI(content)
+}
+
+// ParseOTPAuthURI parses an otpauth uri, e.g. otpauth://totp/john.doe@example.com?secret=...&issuer=..., into an
+// account.
+func ParseOTPAuthURI(uri string) (Account, error) {
+	lowerURI := strings.ToLower(uri)
+
+	if !strings.Contains(lowerURI, totpAuthProtocol) && !strings.Contains(lowerURI, hotpAuthProtocol) {
+		return Account{}, fmt.Errorf("%w: %s", ErrInvalidOTPAuthScheme, uri)
 	}
 
-	u, err := url.Parse(result.String())
+	u, err := url.Parse(uri)
 	if err != nil {
-		return Account{}, fmt.Errorf("failed to parse otpauth uri: %w", err)
+		return Account{}, &markedError{mark: ErrMalformedOTPAuthURI, err: fmt.Errorf("failed to parse otpauth uri: %w", err)}
 	}
 
+	query := normalizeOTPAuthQuery(u.Query())
+	name, issuer := parseOTPAuthLabel(strings.Trim(u.EscapedPath(), "/"), query.Get(totpAuthIssuerParam))
+
 	account := Account{
-		Name:       strings.Trim(u.Path, "/"),
-		TOTPSecret: otp.TOTPSecret(u.Query().Get(totpAuthSecretParam)),
-		Issuer:     u.Query().Get(totpAuthIssuerParam),
+		Name:       name,
+		TOTPSecret: normalizeSecret(otp.TOTPSecret(query.Get(totpAuthSecretParam))),
+		Issuer:     issuer,
+		ImageURL:   query.Get(totpAuthImageParam),
 		Metadata:   nil,
 	}
 
+	if strings.EqualFold(u.Host, TypeHOTP) {
+		account.Type = TypeHOTP
+	}
+
+	if err := parseOTPAuthParams(&account, query); err != nil {
+		return Account{}, err
+	}
+
 	return account, nil
 }
 
-// EncodeTOTPQRCode produces a TOTP QR code for the given account.
-func EncodeTOTPQRCode(w io.Writer, account Account, format string, width, height int, listOfHints ...map[gozxing.EncodeHintType]any) error {
+// decodeQRCode reads the text content of the QR code in the given image.
+func decodeQRCode(r io.Reader, opts ...DecodeQRCodeOption) (string, error) {
+	return decodeCode(r, []gozxing.Reader{qrcode.NewQRCodeReader()}, opts...)
+}
+
+// decodeCode reads the text content of the code in the given image with the first reader that succeeds. The error of
+// the first reader is returned if none does. A panic in the image decoders or the readers is returned as
+// ErrCorruptImage.
+func decodeCode(r io.Reader, readers []gozxing.Reader, opts ...DecodeQRCodeOption) (_ string, err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = &markedError{mark: ErrCorruptImage, err: fmt.Errorf("failed to decode image: %v", p)}
+		}
+	}()
+
+	img, _, err := image.Decode(r)
+	if err != nil {
+		if errors.Is(err, image.ErrFormat) {
+			err = &markedError{mark: ErrUnsupportedImageFormat, err: err}
+		} else if isCorruptImageError(err) {
+			err = &markedError{mark: ErrCorruptImage, err: err}
+		}
+
+		return "", fmt.Errorf("failed to decode image: %w", err)
+	}
+
+	if cfg := newDecodeQRCodeConfig(opts...); cfg.cropRegion != nil {
+		if img, err = cropImage(img, *cfg.cropRegion); err != nil {
+			return "", err
+		}
+	}
+
+	bmp, _ := gozxing.NewBinaryBitmapFromImage(img) //nolint: errcheck
+
+	var firstErr error
+
+	for _, reader := range readers {
+		result, err := reader.Decode(bmp, nil)
+		if err == nil {
+			return result.String(), nil
+		}
+
+		if firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	return "", fmt.Errorf("failed to decode qr code: %w", firstErr)
+}
+
+// cropImage returns the part of the image inside the region.
+func cropImage(img image.Image, r image.Rectangle) (image.Image, error) {
+	cropped := r.Intersect(img.Bounds())
+	if cropped.Empty() {
+		return nil, fmt.Errorf("%w: %v is outside of the image %v", ErrInvalidCropRegion, r, img.Bounds())
+	}
+
+	if s, ok := img.(interface {
+		SubImage(r image.Rectangle) image.Image
+	}); ok {
+		return s.SubImage(cropped), nil
+	}
+
+	dst := image.NewRGBA(cropped)
+	draw.Draw(dst, cropped, img, cropped.Min, draw.Src)
+
+	return dst, nil
+}
+
+// isCorruptImageError tells whether the error of image.Decode is caused by the data, rather than by the reader.
+func isCorruptImageError(err error) bool {
+	var (
+		pngErr  png.FormatError
+		jpegErr jpeg.FormatError
+	)
+
+	return errors.Is(err, io.ErrUnexpectedEOF) || errors.Is(err, io.EOF) ||
+		errors.As(err, &pngErr) || errors.As(err, &jpegErr)
+}
+
+// normalizeOTPAuthQuery lowercases the parameter names, because some exporters emit them as "Secret" or "ISSUER". The
+// values of the lowercase names come first.
+func normalizeOTPAuthQuery(query url.Values) url.Values {
+	normalized := make(url.Values, len(query))
+
+	for k, v := range query {
+		if k == strings.ToLower(k) {
+			normalized[k] = append(normalized[k], v...)
+		}
+	}
+
+	for k, v := range query {
+		if lk := strings.ToLower(k); k != lk {
+			normalized[lk] = append(normalized[lk], v...)
+		}
+	}
+
+	return normalized
+}
+
+// parseOTPAuthLabel splits the escaped "issuer:name" label into the account name and the issuer. The issuer parameter
+// takes precedence over the prefix of the label. The separator can be a literal or an escaped colon, but when the label
+// has a literal one, the escaped ones belong to the name or the issuer.
+func parseOTPAuthLabel(label, issuer string) (string, string) {
+	if !strings.Contains(label, ":") {
+		label = unescapeOTPAuthLabel(label)
+
+		if issuer != "" && strings.HasPrefix(label, issuer+":") {
+			return strings.TrimLeft(label[len(issuer)+1:], " "), issuer
+		}
+	} else if issuer != "" {
+		for i := 0; i < len(label); i++ {
+			if label[i] == ':' && unescapeOTPAuthLabel(label[:i]) == issuer {
+				return strings.TrimLeft(unescapeOTPAuthLabel(label[i+1:]), " "), issuer
+			}
+		}
+	}
+
+	prefix, name, ok := strings.Cut(label, ":")
+	if !ok {
+		return unescapeOTPAuthLabel(label), issuer
+	}
+
+	if issuer == "" {
+		issuer = unescapeOTPAuthLabel(prefix)
+	}
+
+	return strings.TrimLeft(unescapeOTPAuthLabel(name), " "), issuer
+}
+
+// unescapeOTPAuthLabel decodes a part of the escaped label. The label has already been validated by url.Parse.
+func unescapeOTPAuthLabel(s string) string {
+	unescaped, err := url.PathUnescape(s)
+	if err != nil {
+		return s
+	}
+
+	return unescaped
+}
+
+// parseOTPAuthParams parses the optional otpauth parameters into the account. The absent parameters are left empty so
+// that the defaults apply.
+func parseOTPAuthParams(account *Account, params url.Values) error {
+	if d := params.Get(totpAuthDigitsParam); d != "" {
+		digits, err := strconv.Atoi(d)
+		if err != nil {
+			return fmt.Errorf("failed to parse otpauth digits: %w: %s", ErrInvalidDigits, d)
+		}
+
+		if digits < minDigits || digits > maxDigits {
+			return fmt.Errorf("failed to parse otpauth digits: %w: %d, must be between %d and %d", ErrInvalidDigits, digits, minDigits, maxDigits)
+		}
+
+		account.Digits = digits
+	}
+
+	if p := params.Get(totpAuthPeriodParam); p != "" {
+		period, err := strconv.ParseUint(p, 10, 0)
+		if err != nil || period == 0 {
+			return fmt.Errorf("failed to parse otpauth period: %w: %s", ErrInvalidPeriod, p)
+		}
+
+		account.Period = uint(period)
+	}
+
+	if a := params.Get(totpAuthAlgoParam); a != "" {
+		if _, err := parseAlgorithm(a); err != nil {
+			return fmt.Errorf("failed to parse otpauth algorithm: %w", err)
+		}
+
+		account.Algorithm = strings.ToUpper(a)
+	}
+
+	if c := params.Get(totpAuthCounterParam); c != "" && account.Type == TypeHOTP {
+		counter, err := strconv.ParseUint(c, 10, 64)
+		if err != nil {
+			return fmt.Errorf("failed to parse otpauth counter: %w", err)
+		}
+
+		account.Counter = counter
+	}
+
+	return nil
+}
+
+// OTPAuthURI returns the otpauth uri of the account, e.g. otpauth://totp/example.com:john.doe@example.com?issuer=...
+// The label is prefixed with the issuer if there is one. The digits, algorithm and period parameters are omitted when
+// they are the defaults.
+func (a Account) OTPAuthURI() string {
 	params := url.Values{}
-	params.Set(totpAuthSecretParam, account.TOTPSecret.String())
-	params.Set(totpAuthIssuerParam, account.Issuer)
+	params.Set(totpAuthSecretParam, a.TOTPSecret.String())
+	params.Set(totpAuthIssuerParam, a.Issuer)
+
+	if a.Digits != 0 && a.Digits != defaultDigits {
+		params.Set(totpAuthDigitsParam, strconv.Itoa(a.Digits))
+	}
+
+	if a.Algorithm != "" && !strings.EqualFold(a.Algorithm, AlgorithmSHA1) {
+		params.Set(totpAuthAlgoParam, strings.ToUpper(a.Algorithm))
+	}
+
+	if a.Period != 0 && time.Duration(a.Period)*time.Second != defaultPeriod { //nolint: gosec
+		params.Set(totpAuthPeriodParam, strconv.FormatUint(uint64(a.Period), 10))
+	}
 
-	u, _ := url.Parse(totpAuthProtocol) //nolint: errcheck
-	u.Path = account.Name
+	if a.ImageURL != "" {
+		params.Set(totpAuthImageParam, a.ImageURL)
+	}
+
+	protocol := totpAuthProtocol
+
+	if a.Type == TypeHOTP {
+		protocol = hotpAuthProtocol
+
+		params.Set(totpAuthCounterParam, strconv.FormatUint(a.Counter, 10))
+	}
+
+	u, _ := url.Parse(protocol) //nolint: errcheck
+	u.Path = a.Name
+	u.RawPath = strings.ReplaceAll(url.PathEscape(a.Name), ":", "%3A")
 	u.RawQuery = params.Encode()
 
-	qrWriter := qrcode.NewQRCodeWriter()
-	totpAuthURI := u.String()
+	// An escaped colon also separates the issuer from the name, so a name with a colon gets an empty issuer prefix.
+	if a.Issuer != "" || strings.Contains(a.Name, ":") {
+		u.Path = a.Issuer + ":" + u.Path
+		u.RawPath = strings.ReplaceAll(url.PathEscape(a.Issuer), ":", "%3A") + ":" + u.RawPath
+	}
+
+	return u.String()
+}
+
+// EncodeTOTPQRCode produces a TOTP QR code for the given account. Use EncodeTOTPQRCodeWithOptions for the other options.
+func EncodeTOTPQRCode(w io.Writer, account Account, format string, width, height int, listOfHints ...map[gozxing.EncodeHintType]any) error {
+	return EncodeTOTPQRCodeWithOptions(w, account, format, width, height, qrHintsOptions(listOfHints)...)
+}
+
+// EncodeTOTPQRCodeWithOptions produces a TOTP QR code for the given account like EncodeTOTPQRCode, with the options, e.g.
+// WithQRColors.
+func EncodeTOTPQRCodeWithOptions(w io.Writer, account Account, format string, width, height int, opts ...QRCodeOption) error {
+	if err := validateQRCodeAccount(account); err != nil {
+		return err
+	}
+
+	if account.Issuer == "" {
+		account.Issuer = newQRCodeConfig(opts...).defaultIssuer
+	}
+
+	return encodeQRCode(w, account.OTPAuthURI(), format, width, height, opts...)
+}
+
+// EncodeAccountQRCode produces a TOTP QR code for the account in the storage. It returns ErrAccountNotFound if the
+// account does not exist.
+func EncodeAccountQRCode(_ context.Context, w io.Writer, namespace, account, format string, width, height int, opts ...QRCodeOption) error {
+	a, err := GetAccount(namespace, account)
+	if err != nil {
+		return err
+	}
+
+	return EncodeTOTPQRCodeWithOptions(w, a, format, width, height, opts...)
+}
+
+// qrCodeMIMETypes are the mime types of the formats that encodeQRCode supports.
+var qrCodeMIMETypes = map[string]string{
+	"svg":  "image/svg+xml",
+	"png":  "image/png",
+	"jpg":  "image/jpeg",
+	"jpeg": "image/jpeg",
+	"gif":  "image/gif",
+	"webp": "image/webp",
+}
+
+// EncodeTOTPQRCodeDataURI produces a TOTP QR code for the given account as a data uri, e.g. "data:image/png;base64,...",
+// to embed in html.
+func EncodeTOTPQRCodeDataURI(account Account, format string, width, height int, opts ...QRCodeOption) (string, error) {
+	var buf bytes.Buffer
+
+	if err := EncodeTOTPQRCodeWithOptions(&buf, account, format, width, height, opts...); err != nil {
+		return "", err
+	}
+
+	return "data:" + qrCodeMIMETypes[format] + ";base64," + base64.StdEncoding.EncodeToString(buf.Bytes()), nil
+}
+
+// validateQRCodeAccount rejects the accounts that would produce a QR code that authenticator apps cannot use.
+func validateQRCodeAccount(account Account) error {
+	if account.Name == "" {
+		return fmt.Errorf("failed to encode totp qr code: %w", ErrMissingAccountName)
+	}
+
+	if account.TOTPSecret == "" {
+		return fmt.Errorf("failed to encode totp qr code: %w", ErrMissingTOTPSecret)
+	}
+
+	return nil
+}
+
+// encodeQRCode renders the content as a QR code image in the given format.
+func encodeQRCode(w io.Writer, content string, format string, width, height int, opts ...QRCodeOption) error {
+	cfg := newQRCodeConfig(opts...)
+
+	if ratio := qrContrastRatio(cfg.dark, cfg.light); ratio < minQRContrastRatio {
+		return fmt.Errorf("failed to encode totp qr code: %w: %.2f:1, must be at least %.0f:1", ErrInsufficientContrast, ratio, minQRContrastRatio)
+	}
+
+	if cfg.jpegQuality < 1 || cfg.jpegQuality > maxJPEGQuality {
+		return fmt.Errorf("failed to encode totp qr code: %w: %d, must be between 1 and %d", ErrInvalidJPEGQuality, cfg.jpegQuality, maxJPEGQuality)
+	}
+
+	if cfg.moduleSize < 0 {
+		return fmt.Errorf("failed to encode totp qr code: %w: %d, must not be negative", ErrInvalidModuleSize, cfg.moduleSize)
+	}
+
+	if cfg.logo != nil {
+		if cfg.logoScale <= 0 || cfg.logoScale > maxQRLogoScale {
+			return fmt.Errorf("failed to encode totp qr code: %w: %v, must be greater than 0 and at most %v", ErrInvalidLogoScale, cfg.logoScale, maxQRLogoScale)
+		}
+
+		if format == "svg" {
+			return fmt.Errorf("failed to encode totp qr code: %w %s with center logo", ErrUnsupportedFormat, format)
+		}
 
-	encodeHints := map[gozxing.EncodeHintType]any{
-		gozxing.EncodeHintType_MARGIN: 0,
+		cfg.hints[gozxing.EncodeHintType_ERROR_CORRECTION] = decoder.ErrorCorrectionLevel_H
 	}
 
-	for _, hints := range listOfHints {
-		for k, v := range hints {
-			encodeHints[k] = v
+	qrWriter := qrcode.NewQRCodeWriter()
+
+	if cfg.moduleSize > 0 {
+		// Encode one pixel per module to count the modules, the final size is an exact multiple of it.
+		bmp, err := qrWriter.Encode(content, gozxing.BarcodeFormat_QR_CODE, 0, 0, cfg.hints)
+		if err != nil {
+			return fmt.Errorf("failed to encode totp qr code: %w", err)
 		}
+
+		width, height = bmp.GetWidth()*cfg.moduleSize, bmp.GetHeight()*cfg.moduleSize
 	}
 
-	bmp, err := qrWriter.Encode(totpAuthURI, gozxing.BarcodeFormat_QR_CODE, width, height, encodeHints)
+	requestedWidth, requestedHeight := width, height
+
+	if format == "svg" {
+		// Encode one module per unit, the viewBox scales it to the requested size.
+		width, height = 0, 0
+	}
+
+	bmp, err := qrWriter.Encode(content, gozxing.BarcodeFormat_QR_CODE, width, height, cfg.hints)
 	if err != nil {
 		return fmt.Errorf("failed to encode totp qr code: %w", err)
 	}
 
 	switch format {
+	case "svg":
+		err = encodeSVG(w, bmp, requestedWidth, requestedHeight, cfg.dark, cfg.light)
+
 	case "png":
-		err = png.Encode(w, bmp)
+		err = png.Encode(w, qrCodeImage(bmp, cfg))
 
 	case "jpg", "jpeg":
-		err = jpeg.Encode(w, bmp, &jpeg.Options{Quality: 100})
+		err = jpeg.Encode(w, qrCodeImage(bmp, cfg), &jpeg.Options{Quality: cfg.jpegQuality})
+
+	case "gif":
+		err = gif.Encode(w, qrCodeImage(bmp, cfg), nil)
+
+	case "webp":
+		err = nativewebp.Encode(w, qrCodeImage(bmp, cfg), nil)
 
 	case "":
 		return fmt.Errorf("failed to encode totp qr code: %w", ErrUnknownFormat)
@@ -135,3 +580,103 @@ func EncodeTOTPQRCode(w io.Writer, account Account, format string, width, height
 
 	return nil
 }
+
+// qrCodeImage returns the image to encode. The bit matrix is used as is when the colors are the defaults and there is
+// no logo.
+func qrCodeImage(bmp *gozxing.BitMatrix, cfg qrCodeConfig) image.Image {
+	if cfg.hasDefaultColors() && cfg.logo == nil {
+		return bmp
+	}
+
+	img := image.NewPaletted(bmp.Bounds(), color.Palette{cfg.light, cfg.dark})
+
+	for y := range bmp.GetHeight() {
+		for x := range bmp.GetWidth() {
+			if bmp.Get(x, y) {
+				img.SetColorIndex(x, y, 1)
+			}
+		}
+	}
+
+	if cfg.logo == nil {
+		return img
+	}
+
+	return drawCenterLogo(img, cfg.logo, cfg.logoScale)
+}
+
+// drawCenterLogo draws the logo, resized with the nearest neighbor, over the center of the QR code.
+func drawCenterLogo(qr image.Image, logo image.Image, scale float64) image.Image {
+	bounds := qr.Bounds()
+	out := image.NewRGBA(bounds)
+
+	draw.Draw(out, bounds, qr, bounds.Min, draw.Src)
+
+	src := logo.Bounds()
+	if src.Empty() {
+		return out
+	}
+
+	// Fit the logo in a square of the given scale while keeping its aspect ratio.
+	size := float64(min(bounds.Dx(), bounds.Dy())) * scale
+	ratio := min(size/float64(src.Dx()), size/float64(src.Dy()))
+	w, h := max(int(float64(src.Dx())*ratio), 1), max(int(float64(src.Dy())*ratio), 1)
+
+	scaled := image.NewRGBA(image.Rect(0, 0, w, h))
+
+	for y := range h {
+		for x := range w {
+			scaled.Set(x, y, logo.At(src.Min.X+x*src.Dx()/w, src.Min.Y+y*src.Dy()/h))
+		}
+	}
+
+	offset := image.Pt(bounds.Min.X+(bounds.Dx()-w)/2, bounds.Min.Y+(bounds.Dy()-h)/2)
+
+	draw.Draw(out, scaled.Bounds().Add(offset), scaled, image.Point{}, draw.Over)
+
+	return out
+}
+
+// encodeSVG writes the bit matrix as an SVG document with a single path, one unit per module.
+func encodeSVG(w io.Writer, bmp *gozxing.BitMatrix, width, height int, dark, light color.Color) error {
+	if width <= 0 {
+		width = bmp.GetWidth()
+	}
+
+	if height <= 0 {
+		height = bmp.GetHeight()
+	}
+
+	var sb strings.Builder
+
+	for y := range bmp.GetHeight() {
+		for x := 0; x < bmp.GetWidth(); x++ {
+			if !bmp.Get(x, y) {
+				continue
+			}
+
+			start := x
+
+			for x < bmp.GetWidth() && bmp.Get(x, y) {
+				x++
+			}
+
+			_, _ = fmt.Fprintf(&sb, "M%d %dh%dv1h-%dz", start, y, x-start, x-start)
+		}
+	}
+
+	_, err := fmt.Fprintf(w, `<svg xmlns="http://www.w3.org/2000/svg" version="1.1" width="%d" height="%d" viewBox="0 0 %d %d" shape-rendering="crispEdges">
+<rect width="100%%" height="100%%" fill="%s"/>
+<path fill="%s" d="%s"/>
+</svg>
+`, width, height, bmp.GetWidth(), bmp.GetHeight(), hexColor(light), hexColor(dark), sb.String())
+
+	return err //nolint: wrapcheck
+}
+
+// hexColor formats the color as #rrggbb, the alpha channel is ignored.
+func hexColor(c color.Color) string {
+	n := color.NRGBAModel.Convert(c).(color.NRGBA) //nolint: errcheck,forcetypeassert
+
+	return fmt.Sprintf("#%02x%02x%02x", n.R, n.G, n.B)
+}
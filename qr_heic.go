@@ -0,0 +1,5 @@
+//go:build heic
+
+package authenticator
+
+import _ "github.com/jdeng/goheif" // Registers the heic decoder, it needs cgo.
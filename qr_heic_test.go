@@ -0,0 +1,27 @@
+//go:build heic
+
+package authenticator_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go.nhat.io/authenticator"
+)
+
+func TestParseTOTPQRCode_Success_HEIC(t *testing.T) {
+	t.Parallel()
+
+	actual, err := authenticator.ParseTOTPQRCode("resources/fixtures/valid.heic")
+	require.NoError(t, err)
+
+	expected := authenticator.Account{
+		Name:       "john.doe@example.com",
+		TOTPSecret: "NBSWY3DP",
+		Issuer:     "example.com",
+	}
+
+	assert.Equal(t, expected, actual)
+}
@@ -0,0 +1,50 @@
+package authenticator
+
+import (
+	"bytes"
+	"io"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setStdin(t *testing.T, r io.Reader) {
+	t.Helper()
+
+	prev := stdin
+	stdin = r
+
+	t.Cleanup(func() {
+		stdin = prev
+	})
+}
+
+func TestParseTOTPQRCode_Stdin(t *testing.T) {
+	data, err := os.ReadFile("resources/fixtures/valid.png")
+	require.NoError(t, err)
+
+	setStdin(t, bytes.NewReader(data))
+
+	actual, err := ParseTOTPQRCode("-")
+	require.NoError(t, err)
+
+	expected := Account{
+		Name:       "john.doe@example.com",
+		TOTPSecret: "NBSWY3DP",
+		Issuer:     "example.com",
+	}
+
+	assert.Equal(t, expected, actual)
+}
+
+func TestParseTOTPQRCode_Stdin_Error(t *testing.T) {
+	setStdin(t, strings.NewReader("not an image"))
+
+	actual, err := ParseTOTPQRCode("-")
+	require.EqualError(t, err, `failed to read qr code from stdin: failed to decode image: image: unknown format`) //nolint: dupword
+	require.ErrorIs(t, err, ErrUnsupportedImageFormat)
+	assert.Empty(t, actual)
+}
@@ -0,0 +1,20 @@
+//go:build !heic
+
+package authenticator_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go.nhat.io/authenticator"
+)
+
+func TestParseTOTPQRCode_HEICWithoutBuildTag(t *testing.T) {
+	t.Parallel()
+
+	actual, err := authenticator.ParseTOTPQRCode("resources/fixtures/valid.heic")
+	require.ErrorIs(t, err, authenticator.ErrUnsupportedImageFormat)
+	assert.Empty(t, actual)
+}
@@ -0,0 +1,209 @@
+package authenticator
+
+import (
+	"errors"
+	"image"
+	"image/color"
+	"math"
+
+	"github.com/makiuchi-d/gozxing"
+)
+
+const (
+	// minQRContrastRatio is the minimum contrast ratio between the dark and light modules, using the WCAG definition.
+	minQRContrastRatio = 3.0
+	// maxQRLogoScale is the maximum size of the center logo relative to the QR code. At this scale, the logo covers 9%
+	// of the code, which is well within the 30% that the error correction level H can restore.
+	maxQRLogoScale = 0.3
+	// maxJPEGQuality is the highest jpeg quality, and the default one.
+	maxJPEGQuality = 100
+)
+
+var (
+	// ErrInsufficientContrast indicates that the QR code colors are too close to be reliably scanned.
+	ErrInsufficientContrast = errors.New("insufficient contrast between qr code colors")
+	// ErrInvalidLogoScale indicates that the center logo is too small or too large.
+	ErrInvalidLogoScale = errors.New("invalid logo scale")
+	// ErrInvalidJPEGQuality indicates that the jpeg quality is out of range.
+	ErrInvalidJPEGQuality = errors.New("invalid jpeg quality")
+	// ErrInvalidModuleSize indicates that the module size is negative.
+	ErrInvalidModuleSize = errors.New("invalid module size")
+	// ErrInvalidCropRegion indicates that the crop region does not overlap the image.
+	ErrInvalidCropRegion = errors.New("invalid crop region")
+)
+
+type qrCodeConfig struct {
+	hints     map[gozxing.EncodeHintType]any
+	dark      color.Color
+	light     color.Color
+	logo      image.Image
+	logoScale float64
+
+	jpegQuality int
+	moduleSize  int
+
+	defaultIssuer string
+	noOverwrite   bool
+}
+
+// QRCodeOption is an option to configure the QR code image.
+type QRCodeOption interface {
+	applyQRCodeOption(cfg *qrCodeConfig)
+}
+
+type qrCodeOptionFunc func(cfg *qrCodeConfig)
+
+func (f qrCodeOptionFunc) applyQRCodeOption(cfg *qrCodeConfig) {
+	f(cfg)
+}
+
+// WithQRHints sets the hints for the QR code encoder. The hints are merged with the previous ones.
+func WithQRHints(hints map[gozxing.EncodeHintType]any) QRCodeOption {
+	return qrCodeOptionFunc(func(cfg *qrCodeConfig) {
+		for k, v := range hints {
+			cfg.hints[k] = v
+		}
+	})
+}
+
+// qrHintsOptions converts the hints of EncodeTOTPQRCode and GenerateTOTPQRCode to the options.
+func qrHintsOptions(listOfHints []map[gozxing.EncodeHintType]any) []QRCodeOption {
+	opts := make([]QRCodeOption, len(listOfHints))
+
+	for i, hints := range listOfHints {
+		opts[i] = WithQRHints(hints)
+	}
+
+	return opts
+}
+
+// WithQRColors sets the colors of the dark and light modules. Defaults to black on white.
+//
+// The dark color must be darker than the light color with a contrast ratio of at least 3:1, otherwise the encoding
+// fails with ErrInsufficientContrast.
+func WithQRColors(dark, light color.Color) QRCodeOption {
+	return qrCodeOptionFunc(func(cfg *qrCodeConfig) {
+		cfg.dark = dark
+		cfg.light = light
+	})
+}
+
+// WithCenterLogo overlays the image in the center of the QR code, sized as a fraction of the code, e.g. 0.2 for a fifth
+// of its width. The scale must be greater than 0 and at most 0.3. The QR code is encoded with the error correction
+// level H so that it can still be scanned. Only the png and jpeg formats support the logo.
+func WithCenterLogo(img image.Image, scale float64) QRCodeOption {
+	return qrCodeOptionFunc(func(cfg *qrCodeConfig) {
+		cfg.logo = img
+		cfg.logoScale = scale
+	})
+}
+
+// WithNoOverwrite makes GenerateTOTPQRCodeWithOptions fail with an error matching os.ErrExist when the file already
+// exists, instead of overwriting it. The existing file is left untouched.
+func WithNoOverwrite() QRCodeOption {
+	return qrCodeOptionFunc(func(cfg *qrCodeConfig) {
+		cfg.noOverwrite = true
+	})
+}
+
+// WithJPEGQuality sets the quality of the jpeg images, from 1 to 100. Defaults to 100. A quality out of range fails the
+// encoding with ErrInvalidJPEGQuality.
+func WithJPEGQuality(q int) QRCodeOption {
+	return qrCodeOptionFunc(func(cfg *qrCodeConfig) {
+		cfg.jpegQuality = q
+	})
+}
+
+// WithModuleSize renders each module of the QR code as a square of px pixels, so that the code is scaled by an exact
+// integer factor. The size of the image is the number of modules, including the quiet zone set by the margin hint, times
+// px; the width and the height given to the encoder are ignored. Zero keeps the given dimensions.
+func WithModuleSize(px int) QRCodeOption {
+	return qrCodeOptionFunc(func(cfg *qrCodeConfig) {
+		cfg.moduleSize = px
+	})
+}
+
+type decodeQRCodeConfig struct {
+	cropRegion *image.Rectangle
+}
+
+// DecodeQRCodeOption is an option to configure the decoding of a QR code image.
+type DecodeQRCodeOption interface {
+	applyDecodeQRCodeOption(cfg *decodeQRCodeConfig)
+}
+
+type decodeQRCodeOptionFunc func(cfg *decodeQRCodeConfig)
+
+func (f decodeQRCodeOptionFunc) applyDecodeQRCodeOption(cfg *decodeQRCodeConfig) {
+	f(cfg)
+}
+
+// WithCropRegion decodes only the given region of the image, in pixels, e.g. the part of a phone screenshot where the QR
+// code is. The region is clipped to the image, and the decoding fails with ErrInvalidCropRegion if nothing is left.
+func WithCropRegion(r image.Rectangle) DecodeQRCodeOption {
+	return decodeQRCodeOptionFunc(func(cfg *decodeQRCodeConfig) {
+		cfg.cropRegion = &r
+	})
+}
+
+func newDecodeQRCodeConfig(opts ...DecodeQRCodeOption) decodeQRCodeConfig {
+	var cfg decodeQRCodeConfig
+
+	for _, opt := range opts {
+		opt.applyDecodeQRCodeOption(&cfg)
+	}
+
+	return cfg
+}
+
+func newQRCodeConfig(opts ...QRCodeOption) qrCodeConfig {
+	cfg := qrCodeConfig{
+		hints: map[gozxing.EncodeHintType]any{
+			gozxing.EncodeHintType_MARGIN: 0,
+		},
+		dark:        color.Black,
+		light:       color.White,
+		jpegQuality: maxJPEGQuality,
+	}
+
+	for _, opt := range opts {
+		opt.applyQRCodeOption(&cfg)
+	}
+
+	return cfg
+}
+
+// hasDefaultColors tells whether the QR code is black on white.
+func (c qrCodeConfig) hasDefaultColors() bool {
+	return sameColor(c.dark, color.Black) && sameColor(c.light, color.White)
+}
+
+func sameColor(a, b color.Color) bool {
+	ar, ag, ab, aa := a.RGBA()
+	br, bg, bb, ba := b.RGBA()
+
+	return ar == br && ag == bg && ab == bb && aa == ba
+}
+
+// qrContrastRatio returns the WCAG contrast ratio of the dark color against the light color. The ratio is below 1 when
+// the dark color is lighter than the light color.
+func qrContrastRatio(dark, light color.Color) float64 {
+	return (relativeLuminance(light) + 0.05) / (relativeLuminance(dark) + 0.05) //nolint: gomnd
+}
+
+// relativeLuminance returns the relative luminance of the color as defined by WCAG 2.
+func relativeLuminance(c color.Color) float64 {
+	r, g, b, _ := color.NRGBAModel.Convert(c).RGBA()
+
+	return 0.2126*linearRGB(r) + 0.7152*linearRGB(g) + 0.0722*linearRGB(b) //nolint: gomnd
+}
+
+func linearRGB(v uint32) float64 {
+	s := float64(v) / 0xffff
+
+	if s <= 0.03928 { //nolint: gomnd
+		return s / 12.92 //nolint: gomnd
+	}
+
+	return math.Pow((s+0.055)/1.055, 2.4) //nolint: gomnd
+}
@@ -1,14 +1,26 @@
 package authenticator_test
 
 import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/xml"
+	"errors"
+	"image"
+	"image/color"
+	"image/draw"
 	_ "image/jpeg"
 	_ "image/png"
 	"io"
 	"os"
 	"path/filepath"
+	"strconv"
+	"strings"
 	"testing"
 
 	"github.com/makiuchi-d/gozxing"
+	"github.com/makiuchi-d/gozxing/qrcode/decoder"
+	"github.com/makiuchi-d/gozxing/qrcode/encoder"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/require"
 
@@ -30,6 +42,102 @@ func TestParseTOTPQRCode_Success(t *testing.T) {
 	assert.Equal(t, expected, actual)
 }
 
+func TestParseTOTPQRCode_Success_CustomParameters(t *testing.T) {
+	t.Parallel()
+
+	actual, err := authenticator.ParseTOTPQRCode("resources/fixtures/valid_custom_params.png")
+	require.NoError(t, err)
+
+	expected := authenticator.Account{
+		Name:       "john.doe@example.com",
+		TOTPSecret: "NBSWY3DP",
+		Issuer:     "example.com",
+		Digits:     8,
+		Algorithm:  authenticator.AlgorithmSHA256,
+		Period:     60,
+	}
+
+	assert.Equal(t, expected, actual)
+}
+
+func TestParseTOTPQRCode_Success_HOTP(t *testing.T) {
+	t.Parallel()
+
+	actual, err := authenticator.ParseTOTPQRCode("resources/fixtures/valid_hotp.png")
+	require.NoError(t, err)
+
+	expected := authenticator.Account{
+		Name:       "john.doe@example.com",
+		TOTPSecret: "NBSWY3DP",
+		Issuer:     "example.com",
+		Type:       authenticator.TypeHOTP,
+		Counter:    7,
+	}
+
+	assert.Equal(t, expected, actual)
+}
+
+func TestParseTOTPQRCode_Success_CaseInsensitive(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		scenario string
+		file     string
+		expected authenticator.Account
+	}{
+		{
+			scenario: "uppercase scheme",
+			file:     "resources/fixtures/valid_uppercase_scheme.png",
+			expected: authenticator.Account{
+				Name:       "john.doe@example.com",
+				TOTPSecret: "NBSWY3DP",
+				Issuer:     "example.com",
+			},
+		},
+		{
+			scenario: "mixed case params",
+			file:     "resources/fixtures/valid_mixed_case_params.png",
+			expected: authenticator.Account{
+				Name:       "john.doe@example.com",
+				TOTPSecret: "NBSWY3DP",
+				Issuer:     "example.com",
+				Digits:     8,
+				Algorithm:  authenticator.AlgorithmSHA256,
+				Period:     60,
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.scenario, func(t *testing.T) {
+			t.Parallel()
+
+			actual, err := authenticator.ParseTOTPQRCode(tc.file)
+			require.NoError(t, err)
+
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+}
+
+func TestParseTOTPQRCode_InvalidDigits(t *testing.T) {
+	t.Parallel()
+
+	actual, err := authenticator.ParseTOTPQRCode("resources/fixtures/invalid_digits.png")
+	require.ErrorIs(t, err, authenticator.ErrInvalidDigits)
+	require.EqualError(t, err, `failed to parse otpauth digits: invalid number of digits: eight`)
+	assert.Empty(t, actual)
+}
+
+func TestParseTOTPQRCode_UnsupportedAlgorithm(t *testing.T) {
+	t.Parallel()
+
+	actual, err := authenticator.ParseTOTPQRCode("resources/fixtures/invalid_algorithm.png")
+	require.ErrorIs(t, err, authenticator.ErrUnsupportedAlgorithm)
+	require.EqualError(t, err, `failed to parse otpauth algorithm: unsupported algorithm: MD5`)
+	assert.Empty(t, actual)
+}
+
 func TestParseTOTPQRCode_FileNotFound(t *testing.T) {
 	t.Parallel()
 
@@ -39,11 +147,61 @@ func TestParseTOTPQRCode_FileNotFound(t *testing.T) {
 	assert.Empty(t, actual)
 }
 
+func TestParseTOTPQRCode_Success_TIFF(t *testing.T) {
+	t.Parallel()
+
+	actual, err := authenticator.ParseTOTPQRCode("resources/fixtures/valid.tiff")
+	require.NoError(t, err)
+
+	expected := authenticator.Account{
+		Name:       "john.doe@example.com",
+		TOTPSecret: "NBSWY3DP",
+		Issuer:     "example.com",
+	}
+
+	assert.Equal(t, expected, actual)
+}
+
+func TestDecodeTOTPQRCodeMultiFormat(t *testing.T) {
+	t.Parallel()
+
+	expected := authenticator.Account{
+		Name:       "john.doe@example.com",
+		TOTPSecret: "NBSWY3DP",
+		Issuer:     "example.com",
+	}
+
+	for _, file := range []string{"valid.png", "valid_datamatrix.png"} {
+		t.Run(file, func(t *testing.T) {
+			t.Parallel()
+
+			f, err := os.Open(filepath.Join("resources/fixtures", file))
+			require.NoError(t, err)
+
+			defer f.Close() //nolint: errcheck
+
+			actual, err := authenticator.DecodeTOTPQRCodeMultiFormat(f)
+			require.NoError(t, err)
+
+			assert.Equal(t, expected, actual)
+		})
+	}
+}
+
+func TestDecodeTOTPQRCode_DataMatrix(t *testing.T) {
+	t.Parallel()
+
+	actual, err := authenticator.ParseTOTPQRCode("resources/fixtures/valid_datamatrix.png")
+	require.ErrorContains(t, err, "failed to decode qr code")
+	assert.Empty(t, actual)
+}
+
 func TestParseTOTPQRCode_UnsupportedFormat(t *testing.T) {
 	t.Parallel()
 
 	actual, err := authenticator.ParseTOTPQRCode("resources/fixtures/valid.bmp")
 	require.EqualError(t, err, `failed to decode image: image: unknown format`) //nolint: dupword
+	require.ErrorIs(t, err, authenticator.ErrUnsupportedImageFormat)
 	assert.Empty(t, actual)
 }
 
@@ -60,6 +218,7 @@ func TestParseTOTPQRCode_WrongProtocol(t *testing.T) {
 
 	actual, err := authenticator.ParseTOTPQRCode("resources/fixtures/invalid_link.png")
 	require.EqualError(t, err, `invalid totpauth uri: https://example.com`)
+	require.ErrorIs(t, err, authenticator.ErrInvalidOTPAuthScheme)
 	assert.Empty(t, actual)
 }
 
@@ -68,9 +227,62 @@ func TestParseTOTPQRCode_InvalidTOTPURI(t *testing.T) {
 
 	actual, err := authenticator.ParseTOTPQRCode("resources/fixtures/invalid_totpauth_uri.png")
 	require.EqualError(t, err, `failed to parse otpauth uri: parse "otpauth://totp/\tjohn.doe%40example.com?secret=NBSWY3DP&issuer=example.com": net/url: invalid control character in URL`)
+	require.ErrorIs(t, err, authenticator.ErrMalformedOTPAuthURI)
 	assert.Empty(t, actual)
 }
 
+func TestParseTOTPQRCodeBytes_Success(t *testing.T) {
+	t.Parallel()
+
+	data, err := os.ReadFile("resources/fixtures/valid.png")
+	require.NoError(t, err)
+
+	actual, err := authenticator.ParseTOTPQRCodeBytes(data)
+	require.NoError(t, err)
+
+	expected := authenticator.Account{
+		Name:       "john.doe@example.com",
+		TOTPSecret: "NBSWY3DP",
+		Issuer:     "example.com",
+	}
+
+	assert.Equal(t, expected, actual)
+}
+
+func TestParseTOTPQRCodeBytes_Error(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		scenario      string
+		file          string
+		expectedError string
+	}{
+		{
+			scenario:      "unsupported format",
+			file:          "resources/fixtures/valid.bmp",
+			expectedError: `failed to decode image: image: unknown format`, //nolint: dupword
+		},
+		{
+			scenario:      "no qr code",
+			file:          "resources/fixtures/invalid_noqr.png",
+			expectedError: `failed to decode qr code: NotFoundException: startSize = 0`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.scenario, func(t *testing.T) {
+			t.Parallel()
+
+			data, err := os.ReadFile(tc.file)
+			require.NoError(t, err)
+
+			actual, err := authenticator.ParseTOTPQRCodeBytes(data)
+			require.EqualError(t, err, tc.expectedError)
+			assert.Empty(t, actual)
+		})
+	}
+}
+
 func TestGenerateTOTPQRCode_Success_PNG(t *testing.T) {
 	t.Parallel()
 
@@ -121,20 +333,108 @@ func TestGenerateTOTPQRCode_Success_JPEG(t *testing.T) {
 	assert.Equal(t, expectedFileContent, actualFileContent)
 }
 
+func TestGenerateTOTPQRCode_Success_SVG(t *testing.T) {
+	t.Parallel()
+
+	actualFile := filepath.Join(t.TempDir(), "qr.svg")
+
+	account := authenticator.Account{
+		Name:       "john.doe@example.com",
+		TOTPSecret: "NBSWY3DP",
+		Issuer:     "example.com",
+	}
+
+	err := authenticator.GenerateTOTPQRCode(actualFile, account, 200, 200, map[gozxing.EncodeHintType]any{
+		gozxing.EncodeHintType_MARGIN: 1,
+	})
+	require.NoError(t, err)
+
+	actualFileContent, err := os.ReadFile(actualFile) //nolint: gosec
+	require.NoError(t, err)
+
+	assert.True(t, strings.HasPrefix(string(actualFileContent), "<svg"))
+	assert.Contains(t, string(actualFileContent), `width="200" height="200"`)
+	assert.Contains(t, string(actualFileContent), "<path")
+
+	d := xml.NewDecoder(bytes.NewReader(actualFileContent))
+
+	for {
+		_, err := d.Token()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+
+		require.NoError(t, err)
+	}
+}
+
 func TestGenerateTOTPQRCode_FailedToOpenFile(t *testing.T) {
 	t.Parallel()
 
-	err := authenticator.GenerateTOTPQRCode("/path/to/unknown", authenticator.Account{}, 200, 200)
+	err := authenticator.GenerateTOTPQRCode("/path/to/unknown.png", authenticator.Account{Name: "john.doe@example.com", TOTPSecret: "NBSWY3DP"}, 200, 200)
 	require.ErrorIs(t, err, os.ErrNotExist)
 }
 
+func TestGenerateTOTPQRCode_FailedToEncode(t *testing.T) {
+	t.Parallel()
+
+	filePath := filepath.Join(t.TempDir(), "qr.png")
+	account := authenticator.Account{Name: "john.doe@example.com", TOTPSecret: "NBSWY3DP"}
+
+	err := authenticator.GenerateTOTPQRCodeWithOptions(filePath, account, -1, -1, authenticator.WithNoOverwrite())
+	require.Error(t, err)
+	assert.NoFileExists(t, filePath)
+
+	// The retry is not blocked by a file left behind.
+	err = authenticator.GenerateTOTPQRCodeWithOptions(filePath, account, 200, 200, authenticator.WithNoOverwrite())
+	require.NoError(t, err)
+
+	actual, err := authenticator.ParseTOTPQRCode(filePath)
+	require.NoError(t, err)
+	assert.Equal(t, account.Name, actual.Name)
+}
+
+func TestGenerateTOTPQRCode_NoOverwrite(t *testing.T) {
+	t.Parallel()
+
+	filePath := filepath.Join(t.TempDir(), "qr.png")
+	account := authenticator.Account{Name: "john.doe@example.com", TOTPSecret: "NBSWY3DP"}
+
+	require.NoError(t, os.WriteFile(filePath, []byte("unrelated"), 0o600))
+
+	err := authenticator.GenerateTOTPQRCodeWithOptions(filePath, account, 200, 200, authenticator.WithNoOverwrite())
+	require.ErrorIs(t, err, os.ErrExist)
+
+	actual, err := os.ReadFile(filePath) //nolint: gosec
+	require.NoError(t, err)
+	assert.Equal(t, []byte("unrelated"), actual)
+
+	err = authenticator.GenerateTOTPQRCode(filePath, account, 200, 200)
+	require.NoError(t, err)
+
+	actualAccount, err := authenticator.ParseTOTPQRCode(filePath)
+	require.NoError(t, err)
+	assert.Equal(t, account.Name, actualAccount.Name)
+}
+
+func TestGenerateTOTPQRCode_NoOverwrite_NewFile(t *testing.T) {
+	t.Parallel()
+
+	filePath := filepath.Join(t.TempDir(), "qr.png")
+
+	err := authenticator.GenerateTOTPQRCodeWithOptions(filePath, authenticator.Account{Name: "john.doe@example.com", TOTPSecret: "NBSWY3DP"}, 200, 200, authenticator.WithNoOverwrite())
+	require.NoError(t, err)
+	assert.FileExists(t, filePath)
+}
+
 func TestGenerateTOTPQRCode_MissingFileExtension(t *testing.T) {
 	t.Parallel()
 
 	filePath := filepath.Join(t.TempDir(), "qr")
 
-	err := authenticator.GenerateTOTPQRCode(filePath, authenticator.Account{}, 200, 200)
+	err := authenticator.GenerateTOTPQRCode(filePath, authenticator.Account{Name: "john.doe@example.com", TOTPSecret: "NBSWY3DP"}, 200, 200)
 	require.EqualError(t, err, `failed to encode totp qr code: unknown format`)
+	assert.NoFileExists(t, filePath)
 }
 
 func TestGenerateTOTPQRCode_UnsupportedFormat(t *testing.T) {
@@ -142,14 +442,15 @@ func TestGenerateTOTPQRCode_UnsupportedFormat(t *testing.T) {
 
 	filePath := filepath.Join(t.TempDir(), "qr.bmp")
 
-	err := authenticator.GenerateTOTPQRCode(filePath, authenticator.Account{}, 200, 200)
+	err := authenticator.GenerateTOTPQRCode(filePath, authenticator.Account{Name: "john.doe@example.com", TOTPSecret: "NBSWY3DP"}, 200, 200)
 	require.EqualError(t, err, `failed to encode totp qr code: unsupported format bmp`)
+	assert.NoFileExists(t, filePath)
 }
 
 func TestEncodeTOTPQRCode_FailedToGenerateImage(t *testing.T) {
 	t.Parallel()
 
-	err := authenticator.EncodeTOTPQRCode(io.Discard, authenticator.Account{}, "", -1, -1)
+	err := authenticator.EncodeTOTPQRCode(io.Discard, authenticator.Account{Name: "john.doe@example.com", TOTPSecret: "NBSWY3DP"}, "", -1, -1)
 	require.EqualError(t, err, `failed to encode totp qr code: WriterException: IllegalArgumentException: Requested dimensions are too small: -1x-1`)
 }
 
@@ -160,7 +461,7 @@ func TestEncodeTOTPQRCode_FailedToWritePNG(t *testing.T) {
 		return 0, io.ErrShortWrite
 	})
 
-	err := authenticator.EncodeTOTPQRCode(w, authenticator.Account{}, "png", 100, 100)
+	err := authenticator.EncodeTOTPQRCode(w, authenticator.Account{Name: "john.doe@example.com", TOTPSecret: "NBSWY3DP"}, "png", 100, 100)
 	require.EqualError(t, err, `failed to write totp qr code: short write`)
 }
 
@@ -171,12 +472,1144 @@ func TestEncodeTOTPQRCode_FailedToWriteJPG(t *testing.T) {
 		return 0, io.ErrShortWrite
 	})
 
-	err := authenticator.EncodeTOTPQRCode(w, authenticator.Account{}, "jpg", 100, 100)
+	err := authenticator.EncodeTOTPQRCode(w, authenticator.Account{Name: "john.doe@example.com", TOTPSecret: "NBSWY3DP"}, "jpg", 100, 100)
+	require.EqualError(t, err, `failed to write totp qr code: short write`)
+}
+
+func TestEncodeTOTPQRCode_FailedToWriteSVG(t *testing.T) {
+	t.Parallel()
+
+	w := writerFunc(func([]byte) (int, error) {
+		return 0, io.ErrShortWrite
+	})
+
+	err := authenticator.EncodeTOTPQRCode(w, authenticator.Account{Name: "john.doe@example.com", TOTPSecret: "NBSWY3DP"}, "svg", 100, 100)
 	require.EqualError(t, err, `failed to write totp qr code: short write`)
 }
 
+func TestEncodeTOTPQRCode_HOTP(t *testing.T) {
+	t.Parallel()
+
+	account := authenticator.Account{
+		Name:       "john.doe@example.com",
+		TOTPSecret: "NBSWY3DP",
+		Issuer:     "example.com",
+		Type:       authenticator.TypeHOTP,
+		Counter:    42,
+	}
+
+	buf := new(bytes.Buffer)
+
+	err := authenticator.EncodeTOTPQRCode(buf, account, "png", 200, 200)
+	require.NoError(t, err)
+
+	actual, err := authenticator.DecodeTOTPQRCode(buf)
+	require.NoError(t, err)
+
+	assert.Equal(t, account, actual)
+}
+
+func TestEncodeTOTPQRCode_DigitsAndAlgorithm(t *testing.T) {
+	t.Parallel()
+
+	account := authenticator.Account{
+		Name:       "john.doe@example.com",
+		TOTPSecret: "NBSWY3DP",
+		Issuer:     "example.com",
+		Digits:     8,
+		Algorithm:  authenticator.AlgorithmSHA256,
+	}
+
+	buf := new(bytes.Buffer)
+
+	err := authenticator.EncodeTOTPQRCode(buf, account, "png", 200, 200)
+	require.NoError(t, err)
+
+	actual, err := authenticator.DecodeTOTPQRCode(buf)
+	require.NoError(t, err)
+
+	assert.Equal(t, account, actual)
+}
+
+func TestEncodeTOTPQRCode_RoundTrip(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		scenario string
+		account  authenticator.Account
+		expected authenticator.Account
+	}{
+		{
+			scenario: "custom parameters",
+			account: authenticator.Account{
+				Name:       "john.doe@example.com",
+				TOTPSecret: "NBSWY3DP",
+				Issuer:     "example.com",
+				Digits:     8,
+				Algorithm:  authenticator.AlgorithmSHA512,
+				Period:     60,
+			},
+			expected: authenticator.Account{
+				Name:       "john.doe@example.com",
+				TOTPSecret: "NBSWY3DP",
+				Issuer:     "example.com",
+				Digits:     8,
+				Algorithm:  authenticator.AlgorithmSHA512,
+				Period:     60,
+			},
+		},
+		{
+			scenario: "default parameters are omitted",
+			account: authenticator.Account{
+				Name:       "john.doe@example.com",
+				TOTPSecret: "NBSWY3DP",
+				Issuer:     "example.com",
+				Digits:     6,
+				Algorithm:  "sha1",
+				Period:     30,
+			},
+			expected: authenticator.Account{
+				Name:       "john.doe@example.com",
+				TOTPSecret: "NBSWY3DP",
+				Issuer:     "example.com",
+			},
+		},
+		{
+			scenario: "hotp",
+			account: authenticator.Account{
+				Name:       "john.doe@example.com",
+				TOTPSecret: "NBSWY3DP",
+				Issuer:     "example.com",
+				Type:       authenticator.TypeHOTP,
+				Counter:    7,
+				Digits:     8,
+			},
+			expected: authenticator.Account{
+				Name:       "john.doe@example.com",
+				TOTPSecret: "NBSWY3DP",
+				Issuer:     "example.com",
+				Type:       authenticator.TypeHOTP,
+				Counter:    7,
+				Digits:     8,
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.scenario, func(t *testing.T) {
+			t.Parallel()
+
+			buf := new(bytes.Buffer)
+
+			err := authenticator.EncodeTOTPQRCode(buf, tc.account, "png", 200, 200)
+			require.NoError(t, err)
+
+			actual, err := authenticator.DecodeTOTPQRCode(buf)
+			require.NoError(t, err)
+
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+}
+
 type writerFunc func(p []byte) (n int, err error)
 
 func (f writerFunc) Write(p []byte) (n int, err error) {
 	return f(p)
 }
+
+func TestAccount_OTPAuthURI(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		scenario string
+		account  authenticator.Account
+		expected string
+	}{
+		{
+			scenario: "basic",
+			account: authenticator.Account{
+				Name:       "john.doe@example.com",
+				TOTPSecret: "NBSWY3DP",
+				Issuer:     "example.com",
+			},
+			expected: "otpauth://totp/example.com:john.doe@example.com?issuer=example.com&secret=NBSWY3DP",
+		},
+		{
+			scenario: "defaults are omitted",
+			account: authenticator.Account{
+				Name:       "john.doe@example.com",
+				TOTPSecret: "NBSWY3DP",
+				Issuer:     "example.com",
+				Digits:     6,
+				Algorithm:  "sha1",
+				Period:     30,
+			},
+			expected: "otpauth://totp/example.com:john.doe@example.com?issuer=example.com&secret=NBSWY3DP",
+		},
+		{
+			scenario: "custom parameters",
+			account: authenticator.Account{
+				Name:       "john.doe@example.com",
+				TOTPSecret: "NBSWY3DP",
+				Issuer:     "Example & Co",
+				Digits:     8,
+				Algorithm:  "sha256",
+				Period:     60,
+			},
+			expected: "otpauth://totp/Example%20&%20Co:john.doe@example.com?algorithm=SHA256&digits=8&issuer=Example+%26+Co&period=60&secret=NBSWY3DP",
+		},
+		{
+			scenario: "hotp",
+			account: authenticator.Account{
+				Name:       "token",
+				TOTPSecret: "NBSWY3DP",
+				Type:       authenticator.TypeHOTP,
+				Counter:    42,
+			},
+			expected: "otpauth://hotp/token?counter=42&issuer=&secret=NBSWY3DP",
+		},
+		{
+			scenario: "escaped name",
+			account: authenticator.Account{
+				Name:       "John Doe/Work?#1",
+				TOTPSecret: "NBSWY3DP",
+			},
+			expected: "otpauth://totp/John%20Doe%2FWork%3F%231?issuer=&secret=NBSWY3DP",
+		},
+		{
+			scenario: "image",
+			account: authenticator.Account{
+				Name:       "john.doe@example.com",
+				TOTPSecret: "NBSWY3DP",
+				Issuer:     "example.com",
+				ImageURL:   "https://example.com/icon.png?size=64",
+			},
+			expected: "otpauth://totp/example.com:john.doe@example.com?image=https%3A%2F%2Fexample.com%2Ficon.png%3Fsize%3D64&issuer=example.com&secret=NBSWY3DP",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.scenario, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, tc.expected, tc.account.OTPAuthURI())
+		})
+	}
+}
+
+func TestEncodeTOTPQRCode_IssuerLabel(t *testing.T) {
+	t.Parallel()
+
+	account := authenticator.Account{
+		Name:       "john.doe@example.com",
+		TOTPSecret: "NBSWY3DP",
+		Issuer:     "Example Co",
+	}
+
+	buf := new(bytes.Buffer)
+
+	err := authenticator.EncodeTOTPQRCode(buf, account, "png", 300, 300)
+	require.NoError(t, err)
+
+	content, actual, err := authenticator.DecodeTOTPQRCodeRaw(buf)
+	require.NoError(t, err)
+
+	assert.Equal(t, "otpauth://totp/Example%20Co:john.doe@example.com?issuer=Example+Co&secret=NBSWY3DP", content)
+	assert.Equal(t, account, actual)
+}
+
+func TestAccount_OTPAuthURI_RoundTrip(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		scenario string
+		account  authenticator.Account
+	}{
+		{
+			scenario: "special characters",
+			account: authenticator.Account{
+				Name:       "John Doe/Work?#1",
+				TOTPSecret: "NBSWY3DP",
+				Issuer:     "Example & Co",
+			},
+		},
+		{
+			scenario: "colon in the name without issuer",
+			account: authenticator.Account{
+				Name:       "work:john",
+				TOTPSecret: "NBSWY3DP",
+			},
+		},
+		{
+			scenario: "colons in the name and the issuer",
+			account: authenticator.Account{
+				Name:       "work:john",
+				TOTPSecret: "NBSWY3DP",
+				Issuer:     "Example:Co",
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.scenario, func(t *testing.T) {
+			t.Parallel()
+
+			buf := new(bytes.Buffer)
+
+			err := authenticator.EncodeTOTPQRCode(buf, tc.account, "png", 300, 300)
+			require.NoError(t, err)
+
+			actual, err := authenticator.DecodeTOTPQRCode(buf)
+			require.NoError(t, err)
+
+			assert.Equal(t, tc.account, actual)
+		})
+	}
+}
+
+func TestAccount_OTPAuthURI_RoundTrip_Image(t *testing.T) {
+	t.Parallel()
+
+	account := authenticator.Account{
+		Name:       "john.doe@example.com",
+		TOTPSecret: "NBSWY3DP",
+		Issuer:     "example.com",
+		ImageURL:   "https://example.com/icon.png?size=64",
+	}
+
+	actual, err := authenticator.ParseOTPAuthURI(account.OTPAuthURI())
+	require.NoError(t, err)
+
+	assert.Equal(t, account, actual)
+}
+
+func TestParseOTPAuthURI(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		scenario        string
+		uri             string
+		expectedAccount authenticator.Account
+		expectedError   string
+		expectedIs      error
+	}{
+		{
+			scenario: "totp",
+			uri:      "otpauth://totp/john.doe@example.com?secret=NBSWY3DP&issuer=example.com&digits=8&period=60&algorithm=sha256",
+			expectedAccount: authenticator.Account{
+				Name:       "john.doe@example.com",
+				TOTPSecret: "NBSWY3DP",
+				Issuer:     "example.com",
+				Digits:     8,
+				Algorithm:  authenticator.AlgorithmSHA256,
+				Period:     60,
+			},
+		},
+		{
+			scenario: "hotp",
+			uri:      "otpauth://hotp/token?secret=NBSWY3DP&counter=42",
+			expectedAccount: authenticator.Account{
+				Name:       "token",
+				TOTPSecret: "NBSWY3DP",
+				Type:       authenticator.TypeHOTP,
+				Counter:    42,
+			},
+		},
+		{
+			scenario: "uppercase hotp",
+			uri:      "OTPAUTH://HOTP/token?SECRET=NBSWY3DP&Counter=42",
+			expectedAccount: authenticator.Account{
+				Name:       "token",
+				TOTPSecret: "NBSWY3DP",
+				Type:       authenticator.TypeHOTP,
+				Counter:    42,
+			},
+		},
+		{
+			scenario: "lowercase param takes precedence",
+			uri:      "otpauth://totp/john.doe@example.com?Secret=JBSWY3DP&secret=NBSWY3DP",
+			expectedAccount: authenticator.Account{
+				Name:       "john.doe@example.com",
+				TOTPSecret: "NBSWY3DP",
+			},
+		},
+		{
+			scenario: "issuer label",
+			uri:      "otpauth://totp/example.com:john.doe@example.com?secret=NBSWY3DP&issuer=example.com",
+			expectedAccount: authenticator.Account{
+				Name:       "john.doe@example.com",
+				TOTPSecret: "NBSWY3DP",
+				Issuer:     "example.com",
+			},
+		},
+		{
+			scenario: "issuer label without issuer parameter",
+			uri:      "otpauth://totp/Example%20Co%3A%20john.doe@example.com?secret=NBSWY3DP",
+			expectedAccount: authenticator.Account{
+				Name:       "john.doe@example.com",
+				TOTPSecret: "NBSWY3DP",
+				Issuer:     "Example Co",
+			},
+		},
+		{
+			scenario: "issuer with colon",
+			uri:      "otpauth://totp/a%3Ab:john.doe@example.com?secret=NBSWY3DP&issuer=a%3Ab",
+			expectedAccount: authenticator.Account{
+				Name:       "john.doe@example.com",
+				TOTPSecret: "NBSWY3DP",
+				Issuer:     "a:b",
+			},
+		},
+		{
+			scenario:      "wrong scheme",
+			uri:           "https://example.com",
+			expectedError: `invalid totpauth uri: https://example.com`,
+			expectedIs:    authenticator.ErrInvalidOTPAuthScheme,
+		},
+		{
+			scenario:      "malformed",
+			uri:           "otpauth://totp/\tjohn.doe@example.com?secret=NBSWY3DP",
+			expectedError: `failed to parse otpauth uri: parse "otpauth://totp/\tjohn.doe@example.com?secret=NBSWY3DP": net/url: invalid control character in URL`,
+			expectedIs:    authenticator.ErrMalformedOTPAuthURI,
+		},
+		{
+			scenario:      "invalid digits",
+			uri:           "otpauth://totp/john.doe@example.com?secret=NBSWY3DP&digits=eight",
+			expectedError: `failed to parse otpauth digits: invalid number of digits: eight`,
+			expectedIs:    authenticator.ErrInvalidDigits,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.scenario, func(t *testing.T) {
+			t.Parallel()
+
+			actual, err := authenticator.ParseOTPAuthURI(tc.uri)
+
+			if tc.expectedError != "" {
+				require.EqualError(t, err, tc.expectedError)
+				require.ErrorIs(t, err, tc.expectedIs)
+			} else {
+				require.NoError(t, err)
+			}
+
+			assert.Equal(t, tc.expectedAccount, actual)
+		})
+	}
+}
+
+func TestEncodeTOTPQRCode_InvalidAccount(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		scenario      string
+		account       authenticator.Account
+		expectedError error
+	}{
+		{
+			scenario:      "empty account",
+			account:       authenticator.Account{},
+			expectedError: authenticator.ErrMissingAccountName,
+		},
+		{
+			scenario:      "missing name",
+			account:       authenticator.Account{TOTPSecret: "NBSWY3DP"},
+			expectedError: authenticator.ErrMissingAccountName,
+		},
+		{
+			scenario:      "missing secret",
+			account:       authenticator.Account{Name: "john.doe@example.com"},
+			expectedError: authenticator.ErrMissingTOTPSecret,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.scenario, func(t *testing.T) {
+			t.Parallel()
+
+			buf := new(bytes.Buffer)
+
+			err := authenticator.EncodeTOTPQRCode(buf, tc.account, "png", 200, 200)
+			require.ErrorIs(t, err, tc.expectedError)
+			assert.Empty(t, buf.Bytes())
+
+			_, err = authenticator.EncodeTOTPQRCodeString(tc.account)
+			require.ErrorIs(t, err, tc.expectedError)
+		})
+	}
+}
+
+func TestEncodeTOTPQRCode_Colors(t *testing.T) {
+	t.Parallel()
+
+	dark := color.RGBA{R: 0x1a, G: 0x23, B: 0x7e, A: 0xff}
+	light := color.RGBA{R: 0xff, G: 0xf8, B: 0xe1, A: 0xff}
+
+	account := authenticator.Account{
+		Name:       "john.doe@example.com",
+		TOTPSecret: "NBSWY3DP",
+		Issuer:     "example.com",
+	}
+
+	for _, format := range []string{"png", "jpg"} {
+		t.Run(format, func(t *testing.T) {
+			t.Parallel()
+
+			buf := new(bytes.Buffer)
+
+			err := authenticator.EncodeTOTPQRCodeWithOptions(buf, account, format, 200, 200, authenticator.WithQRColors(dark, light))
+			require.NoError(t, err)
+
+			img, _, err := image.Decode(bytes.NewReader(buf.Bytes()))
+			require.NoError(t, err)
+
+			assertColor(t, light, img.At(0, 0))
+
+			// The top-left finder pattern is the first dark area on the diagonal.
+			i := 0
+			for i < img.Bounds().Dx() && nearColor(light, img.At(i, i)) {
+				i++
+			}
+
+			assertColor(t, dark, img.At(i+2, i+2))
+
+			actual, err := authenticator.ParseTOTPQRCodeBytes(buf.Bytes())
+			require.NoError(t, err)
+
+			assert.Equal(t, account, actual)
+		})
+	}
+}
+
+func TestEncodeTOTPQRCode_Colors_SVG(t *testing.T) {
+	t.Parallel()
+
+	account := authenticator.Account{
+		Name:       "john.doe@example.com",
+		TOTPSecret: "NBSWY3DP",
+	}
+
+	buf := new(bytes.Buffer)
+
+	err := authenticator.EncodeTOTPQRCodeWithOptions(buf, account, "svg", 200, 200, authenticator.WithQRColors(
+		color.RGBA{R: 0x1a, G: 0x23, B: 0x7e, A: 0xff},
+		color.RGBA{R: 0xff, G: 0xf8, B: 0xe1, A: 0xff},
+	))
+	require.NoError(t, err)
+
+	assert.Contains(t, buf.String(), `<rect width="100%" height="100%" fill="#fff8e1"/>`)
+	assert.Contains(t, buf.String(), `<path fill="#1a237e"`)
+}
+
+func TestEncodeTOTPQRCode_InsufficientContrast(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		scenario string
+		dark     color.Color
+		light    color.Color
+	}{
+		{
+			scenario: "same colors",
+			dark:     color.White,
+			light:    color.White,
+		},
+		{
+			scenario: "low contrast",
+			dark:     color.RGBA{R: 0xaa, G: 0xaa, B: 0xaa, A: 0xff},
+			light:    color.White,
+		},
+		{
+			scenario: "inverted",
+			dark:     color.White,
+			light:    color.Black,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.scenario, func(t *testing.T) {
+			t.Parallel()
+
+			buf := new(bytes.Buffer)
+			account := authenticator.Account{Name: "john.doe@example.com", TOTPSecret: "NBSWY3DP"}
+
+			err := authenticator.EncodeTOTPQRCodeWithOptions(buf, account, "png", 200, 200, authenticator.WithQRColors(tc.dark, tc.light))
+			require.ErrorIs(t, err, authenticator.ErrInsufficientContrast)
+			assert.Empty(t, buf.Bytes())
+		})
+	}
+}
+
+func assertColor(t *testing.T, expected, actual color.Color) {
+	t.Helper()
+
+	er, eg, eb, _ := expected.RGBA()
+	ar, ag, ab, _ := actual.RGBA()
+
+	assert.InDelta(t, er>>8, ar>>8, 32)
+	assert.InDelta(t, eg>>8, ag>>8, 32)
+	assert.InDelta(t, eb>>8, ab>>8, 32)
+}
+
+func nearColor(expected, actual color.Color) bool {
+	er, eg, eb, _ := expected.RGBA()
+	ar, ag, ab, _ := actual.RGBA()
+
+	near := func(e, a uint32) bool {
+		return max(e, a)>>8-min(e, a)>>8 <= 32
+	}
+
+	return near(er, ar) && near(eg, ag) && near(eb, ab)
+}
+
+func TestEncodeTOTPQRCode_CenterLogo(t *testing.T) {
+	t.Parallel()
+
+	logo := image.NewRGBA(image.Rect(0, 0, 64, 32))
+	draw.Draw(logo, logo.Bounds(), image.NewUniform(color.RGBA{R: 0xe5, G: 0x39, B: 0x35, A: 0xff}), image.Point{}, draw.Src)
+
+	account := authenticator.Account{
+		Name:       "john.doe@example.com",
+		TOTPSecret: "NBSWY3DP",
+		Issuer:     "example.com",
+	}
+
+	testCases := []struct {
+		scenario string
+		format   string
+		scale    float64
+	}{
+		{scenario: "png small", format: "png", scale: 0.1},
+		{scenario: "png max", format: "png", scale: 0.3},
+		{scenario: "jpg", format: "jpg", scale: 0.2},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.scenario, func(t *testing.T) {
+			t.Parallel()
+
+			buf := new(bytes.Buffer)
+
+			err := authenticator.EncodeTOTPQRCodeWithOptions(buf, account, tc.format, 300, 300, authenticator.WithCenterLogo(logo, tc.scale))
+			require.NoError(t, err)
+
+			img, _, err := image.Decode(bytes.NewReader(buf.Bytes()))
+			require.NoError(t, err)
+
+			assertColor(t, color.RGBA{R: 0xe5, G: 0x39, B: 0x35, A: 0xff}, img.At(150, 150))
+
+			actual, err := authenticator.ParseTOTPQRCodeBytes(buf.Bytes())
+			require.NoError(t, err)
+
+			assert.Equal(t, account, actual)
+		})
+	}
+}
+
+func TestEncodeTOTPQRCode_CenterLogo_Error(t *testing.T) {
+	t.Parallel()
+
+	logo := image.NewRGBA(image.Rect(0, 0, 10, 10))
+
+	testCases := []struct {
+		scenario      string
+		format        string
+		scale         float64
+		expectedError error
+	}{
+		{scenario: "zero scale", format: "png", scale: 0, expectedError: authenticator.ErrInvalidLogoScale},
+		{scenario: "negative scale", format: "png", scale: -0.1, expectedError: authenticator.ErrInvalidLogoScale},
+		{scenario: "scale too large", format: "png", scale: 0.5, expectedError: authenticator.ErrInvalidLogoScale},
+		{scenario: "svg", format: "svg", scale: 0.2, expectedError: authenticator.ErrUnsupportedFormat},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.scenario, func(t *testing.T) {
+			t.Parallel()
+
+			buf := new(bytes.Buffer)
+			account := authenticator.Account{Name: "john.doe@example.com", TOTPSecret: "NBSWY3DP"}
+
+			err := authenticator.EncodeTOTPQRCodeWithOptions(buf, account, tc.format, 200, 200, authenticator.WithCenterLogo(logo, tc.scale))
+			require.ErrorIs(t, err, tc.expectedError)
+			assert.Empty(t, buf.Bytes())
+		})
+	}
+}
+
+func TestEncodeTOTPQRCode_JPEGQuality(t *testing.T) {
+	t.Parallel()
+
+	account := authenticator.Account{
+		Name:       "john.doe@example.com",
+		TOTPSecret: "NBSWY3DP",
+		Issuer:     "example.com",
+	}
+
+	full := new(bytes.Buffer)
+
+	err := authenticator.EncodeTOTPQRCode(full, account, "jpg", 200, 200)
+	require.NoError(t, err)
+
+	low := new(bytes.Buffer)
+
+	err = authenticator.EncodeTOTPQRCodeWithOptions(low, account, "jpg", 200, 200, authenticator.WithJPEGQuality(50))
+	require.NoError(t, err)
+
+	assert.Less(t, low.Len(), full.Len())
+
+	actual, err := authenticator.DecodeTOTPQRCode(low)
+	require.NoError(t, err)
+
+	assert.Equal(t, account, actual)
+}
+
+func TestEncodeTOTPQRCode_ModuleSize(t *testing.T) {
+	t.Parallel()
+
+	account := authenticator.Account{
+		Name:       "john.doe@example.com",
+		TOTPSecret: "NBSWY3DP",
+		Issuer:     "example.com",
+	}
+
+	code, err := encoder.Encoder_encodeWithoutHint(account.OTPAuthURI(), decoder.ErrorCorrectionLevel_L)
+	require.NoError(t, err)
+
+	modules := code.GetMatrix().GetWidth()
+
+	testCases := []struct {
+		scenario string
+		margin   int
+		px       int
+	}{
+		{scenario: "no quiet zone", margin: 0, px: 3},
+		{scenario: "quiet zone", margin: 4, px: 5},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.scenario, func(t *testing.T) {
+			t.Parallel()
+
+			buf := new(bytes.Buffer)
+
+			// The requested dimensions are ignored.
+			err := authenticator.EncodeTOTPQRCodeWithOptions(buf, account, "png", 1000, 1000,
+				authenticator.WithModuleSize(tc.px),
+				authenticator.WithQRHints(map[gozxing.EncodeHintType]any{gozxing.EncodeHintType_MARGIN: tc.margin}),
+			)
+			require.NoError(t, err)
+
+			img, _, err := image.Decode(bytes.NewReader(buf.Bytes()))
+			require.NoError(t, err)
+
+			size := (modules + 2*tc.margin) * tc.px
+
+			assert.Equal(t, image.Rect(0, 0, size, size), img.Bounds())
+
+			// The top-left module of the finder pattern fills exactly px by px pixels.
+			offset := tc.margin * tc.px
+
+			assertColor(t, color.Black, img.At(offset, offset))
+			assertColor(t, color.Black, img.At(offset+tc.px-1, offset+tc.px-1))
+
+			actual, err := authenticator.DecodeTOTPQRCode(buf)
+			require.NoError(t, err)
+
+			assert.Equal(t, account, actual)
+		})
+	}
+}
+
+func TestEncodeTOTPQRCode_ModuleSize_Error(t *testing.T) {
+	t.Parallel()
+
+	buf := new(bytes.Buffer)
+	account := authenticator.Account{Name: "john.doe@example.com", TOTPSecret: "NBSWY3DP"}
+
+	err := authenticator.EncodeTOTPQRCodeWithOptions(buf, account, "png", 200, 200, authenticator.WithModuleSize(-1))
+	require.ErrorIs(t, err, authenticator.ErrInvalidModuleSize)
+	assert.Empty(t, buf.Bytes())
+}
+
+func TestEncodeTOTPQRCode_JPEGQuality_Error(t *testing.T) {
+	t.Parallel()
+
+	for _, q := range []int{0, -1, 101} {
+		t.Run(strconv.Itoa(q), func(t *testing.T) {
+			t.Parallel()
+
+			buf := new(bytes.Buffer)
+			account := authenticator.Account{Name: "john.doe@example.com", TOTPSecret: "NBSWY3DP"}
+
+			err := authenticator.EncodeTOTPQRCodeWithOptions(buf, account, "jpg", 200, 200, authenticator.WithJPEGQuality(q))
+			require.ErrorIs(t, err, authenticator.ErrInvalidJPEGQuality)
+			assert.Empty(t, buf.Bytes())
+		})
+	}
+}
+
+func TestGenerateTOTPQRCode_GIFAndWEBP(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		scenario       string
+		file           string
+		expectedHeader string
+	}{
+		{
+			scenario:       "gif",
+			file:           "qr.gif",
+			expectedHeader: "GIF89a",
+		},
+		{
+			scenario:       "webp",
+			file:           "qr.webp",
+			expectedHeader: "RIFF",
+		},
+	}
+
+	account := authenticator.Account{
+		Name:       "john.doe@example.com",
+		TOTPSecret: "NBSWY3DP",
+		Issuer:     "example.com",
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.scenario, func(t *testing.T) {
+			t.Parallel()
+
+			actualFile := filepath.Join(t.TempDir(), tc.file)
+
+			err := authenticator.GenerateTOTPQRCode(actualFile, account, 200, 200)
+			require.NoError(t, err)
+
+			actualFileContent, err := os.ReadFile(actualFile) //nolint: gosec
+			require.NoError(t, err)
+
+			assert.True(t, bytes.HasPrefix(actualFileContent, []byte(tc.expectedHeader)))
+
+			actual, err := authenticator.ParseTOTPQRCode(actualFile)
+			require.NoError(t, err)
+
+			assert.Equal(t, account, actual)
+		})
+	}
+}
+
+func TestDecodeTOTPQRCodeRaw(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		scenario        string
+		file            string
+		expectedContent string
+		expectedAccount authenticator.Account
+		expectedError   string
+	}{
+		{
+			scenario:        "valid",
+			file:            "resources/fixtures/valid.png",
+			expectedContent: "otpauth://totp/john.doe@example.com?issuer=example.com&secret=NBSWY3DP",
+			expectedAccount: authenticator.Account{
+				Name:       "john.doe@example.com",
+				TOTPSecret: "NBSWY3DP",
+				Issuer:     "example.com",
+			},
+		},
+		{
+			scenario:        "not an otpauth uri",
+			file:            "resources/fixtures/invalid_link.png",
+			expectedContent: "https://example.com",
+			expectedError:   `invalid totpauth uri: https://example.com`,
+		},
+		{
+			scenario:        "invalid otpauth uri",
+			file:            "resources/fixtures/invalid_totpauth_uri.png",
+			expectedContent: "otpauth://totp/\tjohn.doe%40example.com?secret=NBSWY3DP&issuer=example.com",
+			expectedError:   `failed to parse otpauth uri: parse "otpauth://totp/\tjohn.doe%40example.com?secret=NBSWY3DP&issuer=example.com": net/url: invalid control character in URL`,
+		},
+		{
+			scenario:      "no qr code",
+			file:          "resources/fixtures/invalid_noqr.png",
+			expectedError: `failed to decode qr code: NotFoundException: startSize = 0`,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.scenario, func(t *testing.T) {
+			t.Parallel()
+
+			data, err := os.ReadFile(tc.file)
+			require.NoError(t, err)
+
+			content, account, err := authenticator.DecodeTOTPQRCodeRaw(bytes.NewReader(data))
+
+			if tc.expectedError != "" {
+				require.EqualError(t, err, tc.expectedError)
+			} else {
+				require.NoError(t, err)
+			}
+
+			assert.Equal(t, tc.expectedContent, content)
+			assert.Equal(t, tc.expectedAccount, account)
+		})
+	}
+}
+
+func TestEncodeTOTPQRCode_WithDefaultIssuer(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		scenario       string
+		account        authenticator.Account
+		expectedIssuer string
+	}{
+		{
+			scenario:       "no issuer",
+			account:        authenticator.Account{Name: "john.doe@example.com", TOTPSecret: "NBSWY3DP"},
+			expectedIssuer: "Acme",
+		},
+		{
+			scenario:       "own issuer",
+			account:        authenticator.Account{Name: "john.doe@example.com", TOTPSecret: "NBSWY3DP", Issuer: "example.com"},
+			expectedIssuer: "example.com",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.scenario, func(t *testing.T) {
+			t.Parallel()
+
+			account := tc.account
+			buf := new(bytes.Buffer)
+
+			err := authenticator.EncodeTOTPQRCodeWithOptions(buf, account, "png", 200, 200, authenticator.WithDefaultIssuer("Acme"))
+			require.NoError(t, err)
+
+			actual, err := authenticator.ParseTOTPQRCodeBytes(buf.Bytes())
+			require.NoError(t, err)
+
+			assert.Equal(t, tc.expectedIssuer, actual.Issuer)
+			assert.Equal(t, tc.account, account, "the account must not be changed")
+		})
+	}
+}
+
+func TestEncodeAccountQRCode_Success(t *testing.T) {
+	setConfigFile(t)
+
+	useInMemoryStorage(t)
+
+	err := authenticator.CreateNamespace("namespace", "Namespace")
+	require.NoError(t, err)
+
+	err = authenticator.SetAccount("namespace", authenticator.Account{
+		Name:       "john.doe@example.com",
+		TOTPSecret: "NBSWY3DP",
+		Issuer:     "example.com",
+	})
+	require.NoError(t, err)
+
+	buf := new(bytes.Buffer)
+
+	err = authenticator.EncodeAccountQRCode(context.Background(), buf, "namespace", "john.doe@example.com", "png", 200, 200)
+	require.NoError(t, err)
+
+	actual, err := authenticator.DecodeTOTPQRCode(buf)
+	require.NoError(t, err)
+
+	expected := authenticator.Account{
+		Name:       "john.doe@example.com",
+		TOTPSecret: "NBSWY3DP",
+		Issuer:     "example.com",
+	}
+
+	assert.Equal(t, expected, actual)
+}
+
+func TestEncodeAccountQRCode_HOTPCounter(t *testing.T) {
+	setConfigFile(t)
+
+	useInMemoryStorage(t)
+
+	err := authenticator.CreateNamespace("namespace", "Namespace")
+	require.NoError(t, err)
+
+	err = authenticator.SetAccount("namespace", authenticator.Account{
+		Name:       "john.doe@example.com",
+		TOTPSecret: "NBSWY3DP",
+		Issuer:     "example.com",
+		Type:       authenticator.TypeHOTP,
+		Counter:    6,
+	})
+	require.NoError(t, err)
+
+	// A code is consumed, the counter is now 7.
+	_, err = authenticator.GenerateHOTPNext(context.Background(), "namespace", "john.doe@example.com")
+	require.NoError(t, err)
+
+	buf := new(bytes.Buffer)
+
+	err = authenticator.EncodeAccountQRCode(context.Background(), buf, "namespace", "john.doe@example.com", "png", 200, 200)
+	require.NoError(t, err)
+
+	uri, actual, err := authenticator.DecodeTOTPQRCodeRaw(buf)
+	require.NoError(t, err)
+
+	assert.Contains(t, uri, "counter=7")
+
+	expected := authenticator.Account{
+		Name:       "john.doe@example.com",
+		TOTPSecret: "NBSWY3DP",
+		Issuer:     "example.com",
+		Type:       authenticator.TypeHOTP,
+		Counter:    7,
+	}
+
+	assert.Equal(t, expected, actual)
+}
+
+func TestEncodeAccountQRCode_AccountNotFound(t *testing.T) {
+	setConfigFile(t)
+
+	useInMemoryStorage(t)
+
+	buf := new(bytes.Buffer)
+
+	err := authenticator.EncodeAccountQRCode(context.Background(), buf, "namespace", "john.doe@example.com", "png", 200, 200)
+	require.ErrorIs(t, err, authenticator.ErrAccountNotFound)
+	assert.Zero(t, buf.Len())
+}
+
+func TestParseTOTPQRCode_TruncatedImage(t *testing.T) {
+	t.Parallel()
+
+	actual, err := authenticator.ParseTOTPQRCode("resources/fixtures/invalid_truncated.png")
+	require.EqualError(t, err, `failed to decode image: png: invalid format: not enough pixel data`)
+	require.ErrorIs(t, err, authenticator.ErrCorruptImage)
+	assert.Empty(t, actual)
+}
+
+func TestParseTOTPQRCode_WithCropRegion(t *testing.T) {
+	t.Parallel()
+
+	// The screenshot has a larger QR code of a download link above the one of the account, in the bottom right corner.
+	const fixture = "resources/fixtures/valid_screenshot.png"
+
+	actual, err := authenticator.ParseTOTPQRCode(fixture)
+	require.ErrorIs(t, err, authenticator.ErrInvalidOTPAuthScheme)
+	assert.Empty(t, actual)
+
+	actual, err = authenticator.ParseTOTPQRCode(fixture, authenticator.WithCropRegion(image.Rect(900, 2160, 1080, 2340)))
+	require.NoError(t, err)
+
+	expected := authenticator.Account{
+		Name:       "john.doe@example.com",
+		TOTPSecret: "NBSWY3DP",
+		Issuer:     "example.com",
+	}
+
+	assert.Equal(t, expected, actual)
+
+	// The region is clipped to the image.
+	actual, err = authenticator.ParseTOTPQRCode(fixture, authenticator.WithCropRegion(image.Rect(900, 2160, 2000, 3000)))
+	require.NoError(t, err)
+	assert.Equal(t, expected, actual)
+}
+
+func TestParseTOTPQRCode_WithCropRegion_OutsideOfImage(t *testing.T) {
+	t.Parallel()
+
+	actual, err := authenticator.ParseTOTPQRCode("resources/fixtures/valid.png", authenticator.WithCropRegion(image.Rect(5000, 5000, 5100, 5100)))
+	require.ErrorIs(t, err, authenticator.ErrInvalidCropRegion)
+	assert.Empty(t, actual)
+}
+
+func TestDecodeTOTPQRCodeMultiFormat_WithCropRegion(t *testing.T) {
+	t.Parallel()
+
+	data, err := os.ReadFile("resources/fixtures/valid_screenshot.png")
+	require.NoError(t, err)
+
+	actual, err := authenticator.DecodeTOTPQRCodeMultiFormat(bytes.NewReader(data), authenticator.WithCropRegion(image.Rect(900, 2160, 1080, 2340)))
+	require.NoError(t, err)
+
+	assert.Equal(t, "john.doe@example.com", actual.Name)
+}
+
+func TestDecodeTOTPQRCode_CorruptImage(t *testing.T) {
+	t.Parallel()
+
+	data, err := os.ReadFile("resources/fixtures/valid.png")
+	require.NoError(t, err)
+
+	for n := 0; n < len(data); n += 7 {
+		assert.NotPanics(t, func() {
+			_, err := authenticator.DecodeTOTPQRCode(bytes.NewReader(data[:n]))
+			assert.Error(t, err, "length %d", n)
+		})
+	}
+
+	// A corrupted chunk is reported as a corrupt image.
+	corrupted := bytes.Clone(data)
+	corrupted[40] ^= 0xff
+
+	_, err = authenticator.DecodeTOTPQRCode(bytes.NewReader(corrupted))
+	require.ErrorIs(t, err, authenticator.ErrCorruptImage)
+}
+
+func TestEncodeTOTPQRCodeDataURI(t *testing.T) {
+	t.Parallel()
+
+	account := authenticator.Account{
+		Name:       "john.doe@example.com",
+		TOTPSecret: "NBSWY3DP",
+		Issuer:     "example.com",
+	}
+
+	testCases := []struct {
+		format         string
+		expectedPrefix string
+	}{
+		{format: "png", expectedPrefix: "data:image/png;base64,"},
+		{format: "jpg", expectedPrefix: "data:image/jpeg;base64,"},
+		{format: "gif", expectedPrefix: "data:image/gif;base64,"},
+		{format: "webp", expectedPrefix: "data:image/webp;base64,"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.format, func(t *testing.T) {
+			t.Parallel()
+
+			actual, err := authenticator.EncodeTOTPQRCodeDataURI(account, tc.format, 200, 200)
+			require.NoError(t, err)
+			require.True(t, strings.HasPrefix(actual, tc.expectedPrefix), "prefix of %q", actual)
+
+			data, err := base64.StdEncoding.DecodeString(strings.TrimPrefix(actual, tc.expectedPrefix))
+			require.NoError(t, err)
+
+			decoded, err := authenticator.DecodeTOTPQRCode(bytes.NewReader(data))
+			require.NoError(t, err)
+
+			assert.Equal(t, account, decoded)
+		})
+	}
+}
+
+func TestEncodeTOTPQRCodeDataURI_SVG(t *testing.T) {
+	t.Parallel()
+
+	actual, err := authenticator.EncodeTOTPQRCodeDataURI(authenticator.Account{Name: "john.doe@example.com", TOTPSecret: "NBSWY3DP"}, "svg", 100, 100)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(actual, "data:image/svg+xml;base64,"))
+}
+
+func TestEncodeTOTPQRCodeDataURI_UnsupportedFormat(t *testing.T) {
+	t.Parallel()
+
+	actual, err := authenticator.EncodeTOTPQRCodeDataURI(authenticator.Account{Name: "john.doe@example.com", TOTPSecret: "NBSWY3DP"}, "bmp", 100, 100)
+	require.ErrorIs(t, err, authenticator.ErrUnsupportedFormat)
+	assert.Empty(t, actual)
+}
@@ -0,0 +1,126 @@
+package authenticator
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/makiuchi-d/gozxing"
+	"github.com/makiuchi-d/gozxing/qrcode"
+)
+
+const defaultQRTextQuietZone = 2
+
+// ErrInvalidQuietZone indicates that the quiet zone is negative.
+var ErrInvalidQuietZone = errors.New("invalid quiet zone")
+
+type qrTextConfig struct {
+	quietZone int
+	ascii     bool
+}
+
+// QRTextOption is an option to configure the text rendering of a QR code.
+type QRTextOption interface {
+	applyQRTextOption(cfg *qrTextConfig)
+}
+
+type qrTextOptionFunc func(cfg *qrTextConfig)
+
+func (f qrTextOptionFunc) applyQRTextOption(cfg *qrTextConfig) {
+	f(cfg)
+}
+
+// WithQuietZone sets the number of light modules around the QR code. Defaults to 2.
+func WithQuietZone(n int) QRTextOption {
+	return qrTextOptionFunc(func(cfg *qrTextConfig) {
+		cfg.quietZone = n
+	})
+}
+
+// WithASCII renders the QR code with ASCII characters, two "#" per dark module, instead of Unicode half blocks.
+func WithASCII() QRTextOption {
+	return qrTextOptionFunc(func(cfg *qrTextConfig) {
+		cfg.ascii = true
+	})
+}
+
+// EncodeTOTPQRCodeString renders a TOTP QR code for the given account as text that can be printed in a terminal.
+//
+// By default, each character holds two modules stacked vertically using the Unicode half blocks ▀, ▄ and █.
+func EncodeTOTPQRCodeString(account Account, opts ...QRTextOption) (string, error) {
+	cfg := qrTextConfig{
+		quietZone: defaultQRTextQuietZone,
+	}
+
+	for _, opt := range opts {
+		opt.applyQRTextOption(&cfg)
+	}
+
+	if err := validateQRCodeAccount(account); err != nil {
+		return "", err
+	}
+
+	if cfg.quietZone < 0 {
+		return "", fmt.Errorf("failed to encode totp qr code: %w: %d", ErrInvalidQuietZone, cfg.quietZone)
+	}
+
+	bmp, err := qrcode.NewQRCodeWriter().Encode(account.OTPAuthURI(), gozxing.BarcodeFormat_QR_CODE, 0, 0, map[gozxing.EncodeHintType]any{
+		gozxing.EncodeHintType_MARGIN: cfg.quietZone,
+	})
+	if err != nil {
+		return "", fmt.Errorf("failed to encode totp qr code: %w", err)
+	}
+
+	if cfg.ascii {
+		return renderQRASCII(bmp), nil
+	}
+
+	return renderQRHalfBlocks(bmp), nil
+}
+
+func renderQRASCII(bmp *gozxing.BitMatrix) string {
+	var sb strings.Builder
+
+	for y := range bmp.GetHeight() {
+		for x := range bmp.GetWidth() {
+			if bmp.Get(x, y) {
+				sb.WriteString("##")
+			} else {
+				sb.WriteString("  ")
+			}
+		}
+
+		sb.WriteByte('\n')
+	}
+
+	return sb.String()
+}
+
+func renderQRHalfBlocks(bmp *gozxing.BitMatrix) string {
+	var sb strings.Builder
+
+	for y := 0; y < bmp.GetHeight(); y += 2 {
+		for x := range bmp.GetWidth() {
+			top := bmp.Get(x, y)
+			bottom := y+1 < bmp.GetHeight() && bmp.Get(x, y+1)
+
+			switch {
+			case top && bottom:
+				sb.WriteRune('█')
+
+			case top:
+				sb.WriteRune('▀')
+
+			case bottom:
+				sb.WriteRune('▄')
+
+			default:
+				sb.WriteByte(' ')
+			}
+		}
+
+		sb.WriteByte('\n')
+	}
+
+	return sb.String()
+}
@@ -0,0 +1,95 @@
+package authenticator_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go.nhat.io/authenticator"
+)
+
+func TestEncodeTOTPQRCodeString_ASCII(t *testing.T) {
+	t.Parallel()
+
+	account := authenticator.Account{
+		Name:       "john@example.com",
+		TOTPSecret: "NBSWY3DP",
+		Issuer:     "example.com",
+	}
+
+	actual, err := authenticator.EncodeTOTPQRCodeString(account, authenticator.WithASCII())
+	require.NoError(t, err)
+
+	lines := strings.Split(strings.TrimSuffix(actual, "\n"), "\n")
+	blank := strings.Repeat(" ", len(lines[0]))
+
+	// The QR code is square, each module takes 2 characters.
+	assert.Len(t, lines, len(lines[0])/2)
+	assert.Equal(t, blank, lines[0])
+	assert.Equal(t, blank, lines[1])
+	assert.NotEqual(t, blank, lines[2])
+	assert.Equal(t, blank, lines[len(lines)-1])
+	assert.Equal(t, blank, lines[len(lines)-2])
+
+	again, err := authenticator.EncodeTOTPQRCodeString(account, authenticator.WithASCII())
+	require.NoError(t, err)
+
+	assert.Equal(t, actual, again)
+}
+
+func TestEncodeTOTPQRCodeString_HalfBlocks(t *testing.T) {
+	t.Parallel()
+
+	account := authenticator.Account{
+		Name:       "john@example.com",
+		TOTPSecret: "NBSWY3DP",
+		Issuer:     "example.com",
+	}
+
+	ascii, err := authenticator.EncodeTOTPQRCodeString(account, authenticator.WithASCII(), authenticator.WithQuietZone(0))
+	require.NoError(t, err)
+
+	actual, err := authenticator.EncodeTOTPQRCodeString(account, authenticator.WithQuietZone(0))
+	require.NoError(t, err)
+
+	asciiLines := strings.Split(strings.TrimSuffix(ascii, "\n"), "\n")
+	lines := strings.Split(strings.TrimSuffix(actual, "\n"), "\n")
+
+	require.Len(t, lines, (len(asciiLines)+1)/2)
+
+	for i, line := range lines {
+		runes := []rune(line)
+
+		require.Len(t, runes, len(asciiLines[0])/2)
+
+		for x, r := range runes {
+			top := asciiLines[2*i][2*x] == '#'
+			bottom := 2*i+1 < len(asciiLines) && asciiLines[2*i+1][2*x] == '#'
+
+			switch {
+			case top && bottom:
+				assert.Equal(t, '█', r)
+
+			case top:
+				assert.Equal(t, '▀', r)
+
+			case bottom:
+				assert.Equal(t, '▄', r)
+
+			default:
+				assert.Equal(t, ' ', r)
+			}
+		}
+	}
+}
+
+func TestEncodeTOTPQRCodeString_InvalidQuietZone(t *testing.T) {
+	t.Parallel()
+
+	actual, err := authenticator.EncodeTOTPQRCodeString(authenticator.Account{Name: "john.doe@example.com", TOTPSecret: "NBSWY3DP"}, authenticator.WithQuietZone(-1))
+
+	require.ErrorIs(t, err, authenticator.ErrInvalidQuietZone)
+	assert.Empty(t, actual)
+}
@@ -0,0 +1,150 @@
+package authenticator
+
+import (
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+
+	"go.nhat.io/secretstorage"
+)
+
+// RepairReport is the result of cross-checking a namespace against the account storage.
+type RepairReport struct {
+	// Missing are the accounts listed in the namespace but not found in the storage.
+	Missing []string
+	// Unlisted are the accounts found in the storage but not listed in the namespace. They can only be found when the
+	// account storage implements KeyLister, otherwise this is always empty.
+	Unlisted []string
+	// Legacy are the accounts listed in the namespace and still stored under their legacy key, see MigrateAccountKeys.
+	Legacy []string
+	// StaleIndex tells whether the issuer index of the namespace does not match the stored accounts.
+	StaleIndex bool
+	// Repaired tells whether the namespace was updated to fix the inconsistencies.
+	Repaired bool
+}
+
+// Consistent tells whether the namespace and the account storage agree.
+func (r RepairReport) Consistent() bool {
+	return len(r.Missing) == 0 && len(r.Unlisted) == 0 && len(r.Legacy) == 0 && !r.StaleIndex
+}
+
+// RepairOption is an option to configure RepairNamespace.
+type RepairOption interface {
+	applyRepairOption(cfg *repairConfig)
+}
+
+type repairOptionFunc func(cfg *repairConfig)
+
+func (f repairOptionFunc) applyRepairOption(cfg *repairConfig) {
+	f(cfg)
+}
+
+type repairConfig struct {
+	fix bool
+}
+
+// WithFix updates the namespace so that it lists exactly the stored accounts: the missing accounts are removed from the
+// namespace and the unlisted ones are added. No account is deleted. The accounts stored under their legacy key are
+// migrated like MigrateAccountKeys. The issuer index is rebuilt, or built for the namespaces stored before the index.
+func WithFix() RepairOption {
+	return repairOptionFunc(func(cfg *repairConfig) {
+		cfg.fix = true
+	})
+}
+
+// RepairNamespace reports the accounts that are listed in the namespace but missing in the storage, and the accounts
+// that are stored but not listed in the namespace. Use WithFix to fix them.
+func RepairNamespace(namespace string, opts ...RepairOption) (RepairReport, error) {
+	var cfg repairConfig
+
+	for _, opt := range opts {
+		opt.applyRepairOption(&cfg)
+	}
+
+	configMu.Lock()
+	defer configMu.Unlock()
+
+	n, err := getNamespace(namespace)
+	if err != nil {
+		return RepairReport{}, fmt.Errorf("failed to repair namespace %s: %w", namespace, errors.Unwrap(err))
+	}
+
+	var (
+		report RepairReport
+		stored []Account
+	)
+
+	for _, account := range n.Accounts {
+		a, err := accountStorage.Get(serviceName, formatAccount(namespace, account))
+		if errors.Is(err, secretstorage.ErrNotFound) {
+			if a, err = getLegacyAccount(namespace, account); err == nil {
+				report.Legacy = append(report.Legacy, account)
+			}
+		}
+
+		if err == nil {
+			stored = append(stored, a)
+
+			continue
+		}
+
+		if !errors.Is(err, secretstorage.ErrNotFound) {
+			return RepairReport{}, fmt.Errorf("failed to repair namespace %s: failed to get account %s: %w", namespace, account, err)
+		}
+
+		report.Missing = append(report.Missing, account)
+	}
+
+	if lister, ok := accountStorage.(KeyLister); ok {
+		keys, err := lister.Keys(serviceName)
+		if err != nil {
+			return RepairReport{}, fmt.Errorf("failed to repair namespace %s: failed to list accounts: %w", namespace, err)
+		}
+
+		for _, key := range keys {
+			ns, account, ok := parseAccountKey(key)
+			if ok && ns == namespace && !slices.Contains(n.Accounts, account) {
+				report.Unlisted = append(report.Unlisted, account)
+			}
+		}
+	}
+
+	report.StaleIndex = n.Issuers != nil && !maps.EqualFunc(n.Issuers, issuerIndex(stored), slices.Equal)
+
+	if !cfg.fix || (report.Consistent() && n.indexed()) {
+		return report, nil
+	}
+
+	for _, account := range report.Unlisted {
+		a, err := accountStorage.Get(serviceName, formatAccount(namespace, account))
+		if err != nil {
+			return report, fmt.Errorf("failed to repair namespace %s: failed to get account %s: %w", namespace, account, err)
+		}
+
+		stored = append(stored, a)
+	}
+
+	for _, account := range report.Legacy {
+		if _, err := migrateAccountKey(namespace, account); err != nil {
+			return report, fmt.Errorf("failed to repair namespace %s: %w", namespace, err)
+		}
+	}
+
+	n.Accounts = slices.DeleteFunc(n.Accounts, func(account string) bool {
+		return slices.Contains(report.Missing, account)
+	})
+	n.Accounts = append(n.Accounts, report.Unlisted...)
+
+	slices.Sort(n.Accounts)
+
+	n.Issuers = issuerIndex(stored)
+
+	if err := updateNamespace(namespace, n); err != nil {
+		return report, fmt.Errorf("failed to repair namespace %s: %w", namespace, errors.Unwrap(err))
+	}
+
+	report.Repaired = true
+
+	return report, nil
+}
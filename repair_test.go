@@ -0,0 +1,142 @@
+package authenticator_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.nhat.io/secretstorage"
+	mockss "go.nhat.io/secretstorage/mock"
+
+	"go.nhat.io/authenticator"
+)
+
+func TestRepairNamespace_Mock(t *testing.T) {
+	setNamespaceStorage(t, func(s *mockss.Storage[authenticator.Namespace]) {
+		s.On("Get", "go.nhat.io/authenticator", "namespace").
+			Return(authenticator.Namespace{Name: "namespace", Accounts: []string{"alice", "bob", "carol"}}, nil)
+	})
+
+	setAccountStorage(t, func(s *mockss.Storage[authenticator.Account]) {
+		s.On("Get", "go.nhat.io/authenticator", "namespace/alice").
+			Return(authenticator.Account{Name: "alice"}, nil)
+
+		s.On("Get", "go.nhat.io/authenticator", "namespace/bob").
+			Return(authenticator.Account{}, secretstorage.ErrNotFound)
+
+		s.On("Get", "go.nhat.io/authenticator", "namespace/carol").
+			Return(authenticator.Account{}, secretstorage.ErrNotFound)
+	})
+
+	actual, err := authenticator.RepairNamespace("namespace")
+	require.NoError(t, err)
+
+	expected := authenticator.RepairReport{
+		Missing: []string{"bob", "carol"},
+	}
+
+	assert.Equal(t, expected, actual)
+	assert.False(t, actual.Consistent())
+}
+
+func TestRepairNamespace_Fix(t *testing.T) {
+	accounts, namespaces := useInMemoryStorage(t)
+
+	err := namespaces.Set("go.nhat.io/authenticator", "namespace", authenticator.Namespace{
+		Name:     "namespace",
+		Accounts: []string{"alice", "bob"},
+	})
+	require.NoError(t, err)
+
+	for _, key := range []string{"namespace/alice", "namespace/dave", "other/bob"} {
+		err := accounts.Set("go.nhat.io/authenticator", key, authenticator.Account{})
+		require.NoError(t, err)
+	}
+
+	expected := authenticator.RepairReport{
+		Missing:  []string{"bob"},
+		Unlisted: []string{"dave"},
+	}
+
+	actual, err := authenticator.RepairNamespace("namespace")
+	require.NoError(t, err)
+	assert.Equal(t, expected, actual)
+
+	expected.Repaired = true
+
+	actual, err = authenticator.RepairNamespace("namespace", authenticator.WithFix())
+	require.NoError(t, err)
+	assert.Equal(t, expected, actual)
+
+	n, err := authenticator.GetNamespace("namespace")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"alice", "dave"}, n.Accounts)
+
+	actual, err = authenticator.RepairNamespace("namespace", authenticator.WithFix())
+	require.NoError(t, err)
+	assert.Equal(t, authenticator.RepairReport{}, actual)
+	assert.True(t, actual.Consistent())
+}
+
+func TestRepairNamespace_Error(t *testing.T) {
+	testCases := []struct {
+		scenario       string
+		mockNamespaces func(s *mockss.Storage[authenticator.Namespace])
+		mockAccounts   func(s *mockss.Storage[authenticator.Account])
+		expectedReport authenticator.RepairReport
+		expectedError  string
+	}{
+		{
+			scenario: "namespace not found",
+			mockNamespaces: func(s *mockss.Storage[authenticator.Namespace]) {
+				s.On("Get", "go.nhat.io/authenticator", "namespace").
+					Return(authenticator.Namespace{}, secretstorage.ErrNotFound)
+			},
+			expectedError: "failed to repair namespace namespace: namespace not found",
+		},
+		{
+			scenario: "failed to get account",
+			mockNamespaces: func(s *mockss.Storage[authenticator.Namespace]) {
+				s.On("Get", "go.nhat.io/authenticator", "namespace").
+					Return(authenticator.Namespace{Name: "namespace", Accounts: []string{"alice"}}, nil)
+			},
+			mockAccounts: func(s *mockss.Storage[authenticator.Account]) {
+				s.On("Get", "go.nhat.io/authenticator", "namespace/alice").
+					Return(authenticator.Account{}, assert.AnError)
+			},
+			expectedError: "failed to repair namespace namespace: failed to get account alice: assert.AnError general error for testing",
+		},
+		{
+			scenario: "failed to update namespace",
+			mockNamespaces: func(s *mockss.Storage[authenticator.Namespace]) {
+				s.On("Get", "go.nhat.io/authenticator", "namespace").
+					Return(authenticator.Namespace{Name: "namespace", Accounts: []string{"alice"}}, nil)
+
+				s.On("Set", "go.nhat.io/authenticator", "namespace", authenticator.Namespace{Name: "namespace", Accounts: []string{}}).
+					Return(assert.AnError)
+			},
+			mockAccounts: func(s *mockss.Storage[authenticator.Account]) {
+				s.On("Get", "go.nhat.io/authenticator", "namespace/alice").
+					Return(authenticator.Account{}, secretstorage.ErrNotFound)
+			},
+			expectedReport: authenticator.RepairReport{Missing: []string{"alice"}},
+			expectedError:  "failed to repair namespace namespace: assert.AnError general error for testing",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.scenario, func(t *testing.T) {
+			setNamespaceStorage(t, tc.mockNamespaces)
+
+			if tc.mockAccounts != nil {
+				setAccountStorage(t, tc.mockAccounts)
+			} else {
+				setAccountStorage(t)
+			}
+
+			actual, err := authenticator.RepairNamespace("namespace", authenticator.WithFix())
+			require.EqualError(t, err, tc.expectedError)
+			assert.Equal(t, tc.expectedReport, actual)
+		})
+	}
+}
@@ -0,0 +1,187 @@
+package authenticator
+
+import (
+	"context"
+	"encoding/base32"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"unicode"
+
+	potp "github.com/pquerna/otp"
+	"go.nhat.io/otp"
+)
+
+var (
+	// ErrInvalidSecret indicates that the TOTP secret is not a valid base32 string.
+	ErrInvalidSecret = errors.New("invalid totp secret")
+	// ErrWeakSecret indicates that the TOTP secret is too short, all zeros, or a well-known test secret.
+	ErrWeakSecret = errors.New("weak totp secret")
+)
+
+// DefaultMinSecretBits is the minimum length of a secret for WithRejectWeakSecrets, as recommended by RFC 4226.
+const DefaultMinSecretBits = 128
+
+// knownTestSecrets are the secrets of the docs and the RFCs that end up enrolled as placeholders.
+var knownTestSecrets = []otp.TOTPSecret{
+	"NBSWY3DP",                         // "hello".
+	"JBSWY3DPEHPK3PXP",                 // The example of the Google Authenticator wiki.
+	"GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ", // "12345678901234567890", the secret of the RFC 4226 and RFC 6238 test vectors.
+}
+
+// normalizeSecret cleans up a secret the way the phone authenticators do: it removes the spaces and hyphens, the
+// padding, and uppercases the letters. For example, "nbsw y3dp" becomes "NBSWY3DP".
+func normalizeSecret(s otp.TOTPSecret) otp.TOTPSecret {
+	secret := strings.Map(func(r rune) rune {
+		if unicode.IsSpace(r) || r == '-' {
+			return -1
+		}
+
+		return unicode.ToUpper(r)
+	}, s.String())
+
+	return otp.TOTPSecret(strings.TrimRight(secret, "="))
+}
+
+func decodeTOTPSecret(s otp.TOTPSecret) ([]byte, error) {
+	secret := normalizeSecret(s).String()
+
+	if n := len(secret) % 8; n != 0 {
+		secret += strings.Repeat("=", 8-n)
+	}
+
+	b, err := base32.StdEncoding.DecodeString(secret)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidSecret, err)
+	}
+
+	return b, nil
+}
+
+// maskSecret keeps the first 2 characters of the secret and masks the rest, e.g. "NBSWY3DP" becomes "NB****". The short
+// secrets are masked entirely.
+func maskSecret(s string) string {
+	const visible = 2
+
+	switch {
+	case s == "":
+		return ""
+
+	case len(s) <= 2*visible:
+		return "****"
+	}
+
+	return s[:visible] + "****"
+}
+
+func validateTOTPSecret(s otp.TOTPSecret) error {
+	_, err := decodeTOTPSecret(s)
+
+	return err
+}
+
+// validateSecretStrength rejects the secrets that have fewer than minBits bits, only zeros, or that are well-known
+// test secrets. The empty secret is allowed.
+func validateSecretStrength(s otp.TOTPSecret, minBits int) error {
+	if s == otp.NoTOTPSecret {
+		return nil
+	}
+
+	b, err := decodeTOTPSecret(s)
+	if err != nil {
+		return err
+	}
+
+	if bits := len(b) * 8; bits < minBits {
+		return fmt.Errorf("%w: %d bits, must be at least %d", ErrWeakSecret, bits, minBits)
+	}
+
+	if !slices.ContainsFunc(b, func(c byte) bool { return c != 0 }) {
+		return fmt.Errorf("%w: all zeros", ErrWeakSecret)
+	}
+
+	if slices.Contains(knownTestSecrets, normalizeSecret(s)) {
+		return fmt.Errorf("%w: well-known test secret", ErrWeakSecret)
+	}
+
+	return nil
+}
+
+// wrapInvalidSecret marks the base32 decoding errors of the otp library with ErrInvalidSecret.
+func wrapInvalidSecret(err error) error {
+	if errors.Is(err, potp.ErrValidateSecretInvalidBase32) {
+		return &markedError{mark: ErrInvalidSecret, err: err}
+	}
+
+	return err
+}
+
+// PreviousSecretMetadataKey is the metadata key holding the previous secret when it is kept by RotateTOTPSecret.
+const PreviousSecretMetadataKey = "previous_secret"
+
+// RotateTOTPSecretOption is an option to configure RotateTOTPSecret.
+type RotateTOTPSecretOption interface {
+	applyRotateTOTPSecretOption(cfg *rotateTOTPSecretConfig)
+}
+
+type rotateTOTPSecretOptionFunc func(cfg *rotateTOTPSecretConfig)
+
+func (f rotateTOTPSecretOptionFunc) applyRotateTOTPSecretOption(cfg *rotateTOTPSecretConfig) {
+	f(cfg)
+}
+
+type rotateTOTPSecretConfig struct {
+	keepPrevious bool
+}
+
+// WithKeepPreviousSecret stashes the previous secret in the account metadata under PreviousSecretMetadataKey, so that it
+// can still be used during a grace period. It is up to the caller to remove it once the grace period is over.
+func WithKeepPreviousSecret() RotateTOTPSecretOption {
+	return rotateTOTPSecretOptionFunc(func(cfg *rotateTOTPSecretConfig) {
+		cfg.keepPrevious = true
+	})
+}
+
+// RotateTOTPSecret replaces the secret of the account and returns the previous one. The new secret must be a valid
+// base32 string, otherwise ErrInvalidSecret is returned and the account is left untouched.
+func RotateTOTPSecret(_ context.Context, namespace, account string, newSecret otp.TOTPSecret, opts ...RotateTOTPSecretOption) (otp.TOTPSecret, error) {
+	var cfg rotateTOTPSecretConfig
+
+	for _, opt := range opts {
+		opt.applyRotateTOTPSecretOption(&cfg)
+	}
+
+	if newSecret == otp.NoTOTPSecret {
+		return otp.NoTOTPSecret, fmt.Errorf("failed to rotate secret of account %s in namespace %s: %w: empty secret", account, namespace, ErrInvalidSecret)
+	}
+
+	if err := validateTOTPSecret(newSecret); err != nil {
+		return otp.NoTOTPSecret, fmt.Errorf("failed to rotate secret of account %s in namespace %s: %w", account, namespace, err)
+	}
+
+	configMu.Lock()
+	defer configMu.Unlock()
+
+	a, err := getAccount(namespace, account)
+	if err != nil {
+		return otp.NoTOTPSecret, err
+	}
+
+	old := a.TOTPSecret
+	a.TOTPSecret = newSecret
+
+	if cfg.keepPrevious {
+		if a.Metadata == nil {
+			a.Metadata = make(map[string]any, 1)
+		}
+
+		a.Metadata[PreviousSecretMetadataKey] = old.String()
+	}
+
+	if err := setAccount(namespace, a); err != nil {
+		return otp.NoTOTPSecret, err
+	}
+
+	return old, nil
+}
@@ -0,0 +1,137 @@
+package authenticator
+
+import (
+	"container/list"
+	"crypto/hmac"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"sync"
+
+	potp "github.com/pquerna/otp"
+	"github.com/pquerna/otp/hotp"
+	"go.nhat.io/otp"
+)
+
+// secretCacheSize is the maximum number of decoded secrets kept in memory.
+const secretCacheSize = 256
+
+// secretCache keeps the most recently decoded secrets, keyed by the normalized secret, so that generating the codes of
+// many accounts, or of the same account over and over, does not decode the same base32 string every time.
+var secretCache = newDecodedSecretCache(secretCacheSize)
+
+type decodedSecretEntry struct {
+	key   string
+	value []byte
+}
+
+// decodedSecretCache is a bounded LRU cache of decoded secrets.
+type decodedSecretCache struct {
+	mu       sync.Mutex
+	capacity int
+	order    *list.List
+	entries  map[string]*list.Element
+}
+
+func newDecodedSecretCache(capacity int) *decodedSecretCache {
+	return &decodedSecretCache{
+		capacity: capacity,
+		order:    list.New(),
+		entries:  make(map[string]*list.Element, capacity),
+	}
+}
+
+// decode returns the decoded secret, from the cache if it is there. Only the valid secrets are cached. The returned
+// bytes are shared with the cache and must not be modified.
+func (c *decodedSecretCache) decode(s otp.TOTPSecret) ([]byte, error) {
+	if b, ok := c.get(s.String()); ok {
+		return b, nil
+	}
+
+	b, err := decodeTOTPSecret(s)
+	if err != nil {
+		return nil, err
+	}
+
+	c.set(s.String(), b)
+
+	return b, nil
+}
+
+func (c *decodedSecretCache) get(key string) ([]byte, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+
+	c.order.MoveToFront(e)
+
+	return e.Value.(*decodedSecretEntry).value, true //nolint: forcetypeassert
+}
+
+func (c *decodedSecretCache) set(key string, value []byte) {
+	if c.capacity <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if e, ok := c.entries[key]; ok {
+		e.Value.(*decodedSecretEntry).value = value //nolint: forcetypeassert
+		c.order.MoveToFront(e)
+
+		return
+	}
+
+	c.entries[key] = c.order.PushFront(&decodedSecretEntry{key: key, value: value})
+
+	for c.order.Len() > c.capacity {
+		oldest := c.order.Back()
+
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*decodedSecretEntry).key) //nolint: forcetypeassert
+	}
+}
+
+func (c *decodedSecretCache) len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.order.Len()
+}
+
+// generateCode computes the HOTP code of the counter as defined by RFC 4226, like hotp.GenerateCodeCustom, with the
+// secret decoded by secretCache. The secret must be normalized.
+func generateCode(secret otp.TOTPSecret, counter uint64, opts hotp.ValidateOpts) (otp.OTP, error) {
+	if secret == otp.NoTOTPSecret {
+		return "", fmt.Errorf("could not generate otp: %w", otp.ErrNoTOTPSecret)
+	}
+
+	key, err := secretCache.decode(secret)
+	if err != nil {
+		return "", fmt.Errorf("could not generate otp: %w", wrapInvalidSecret(potp.ErrValidateSecretInvalidBase32))
+	}
+
+	if opts.Digits == 0 {
+		opts.Digits = potp.DigitsSix
+	}
+
+	var buf [8]byte
+
+	binary.BigEndian.PutUint64(buf[:], counter)
+
+	mac := hmac.New(opts.Algorithm.Hash, key)
+	mac.Write(buf[:])
+	sum := mac.Sum(nil)
+
+	// The dynamic truncation of RFC 4226, section 5.4.
+	offset := sum[len(sum)-1] & 0xf //nolint: gomnd
+
+	value := int64(binary.BigEndian.Uint32(sum[offset:offset+4]) & 0x7fffffff) //nolint: gomnd
+
+	return otp.OTP(opts.Digits.Format(int32(value % int64(math.Pow10(opts.Digits.Length()))))), nil
+}
@@ -0,0 +1,131 @@
+package authenticator
+
+import (
+	"context"
+	"encoding/base32"
+	"fmt"
+	"testing"
+	"time"
+
+	potp "github.com/pquerna/otp"
+	"github.com/pquerna/otp/hotp"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.nhat.io/clock"
+	"go.nhat.io/otp"
+)
+
+func TestDecodedSecretCache_Eviction(t *testing.T) {
+	t.Parallel()
+
+	c := newDecodedSecretCache(2)
+
+	c.set("a", []byte("1"))
+	c.set("b", []byte("2"))
+
+	// Touch "a" so that "b" becomes the least recently used.
+	_, ok := c.get("a")
+	assert.True(t, ok)
+
+	c.set("c", []byte("3"))
+
+	_, ok = c.get("b")
+	assert.False(t, ok)
+
+	actual, ok := c.get("a")
+	assert.True(t, ok)
+	assert.Equal(t, []byte("1"), actual)
+
+	actual, ok = c.get("c")
+	assert.True(t, ok)
+	assert.Equal(t, []byte("3"), actual)
+
+	assert.Equal(t, 2, c.len())
+}
+
+func TestDecodedSecretCache_Decode(t *testing.T) {
+	t.Parallel()
+
+	c := newDecodedSecretCache(1)
+
+	expected, err := decodeTOTPSecret("JBSWY3DPEHPK3PXP")
+	require.NoError(t, err)
+
+	for range 2 {
+		actual, err := c.decode("JBSWY3DPEHPK3PXP")
+		require.NoError(t, err)
+		assert.Equal(t, expected, actual)
+	}
+
+	_, err = c.decode("INVALID!")
+	require.ErrorIs(t, err, ErrInvalidSecret)
+
+	_, ok := c.get("INVALID!")
+	assert.False(t, ok, "the invalid secrets are not cached")
+}
+
+func TestGenerateCode_SameAsHOTP(t *testing.T) {
+	t.Parallel()
+
+	secrets := batchSecrets(8)
+
+	for _, algo := range []potp.Algorithm{potp.AlgorithmSHA1, potp.AlgorithmSHA256, potp.AlgorithmSHA512} {
+		for _, digits := range []potp.Digits{potp.DigitsSix, potp.DigitsEight} {
+			opts := hotp.ValidateOpts{Digits: digits, Algorithm: algo}
+
+			for _, s := range secrets {
+				for _, counter := range []uint64{0, 1, 56666666, 1<<63 + 7} {
+					expected, err := hotp.GenerateCodeCustom(s.String(), counter, opts)
+					require.NoError(t, err)
+
+					actual, err := generateCode(s, counter, opts)
+					require.NoError(t, err)
+
+					assert.Equal(t, otp.OTP(expected), actual, "%s %s %d", algo, s, counter)
+				}
+			}
+		}
+	}
+}
+
+func TestGenerateCode_Error(t *testing.T) {
+	t.Parallel()
+
+	_, err := generateCode(otp.NoTOTPSecret, 0, hotp.ValidateOpts{})
+	require.ErrorIs(t, err, otp.ErrNoTOTPSecret)
+
+	_, err = generateCode("INVALID!", 0, hotp.ValidateOpts{})
+	require.ErrorIs(t, err, ErrInvalidSecret)
+	require.ErrorIs(t, err, potp.ErrValidateSecretInvalidBase32)
+}
+
+func batchSecrets(n int) []otp.TOTPSecret {
+	secrets := make([]otp.TOTPSecret, n)
+
+	for i := range secrets {
+		s := base32.StdEncoding.EncodeToString([]byte(fmt.Sprintf("secret-for-account-%04d", i)))
+		secrets[i] = normalizeSecret(otp.TOTPSecret(s))
+	}
+
+	return secrets
+}
+
+func BenchmarkGenerateTOTPFromSecret_Batch(b *testing.B) {
+	secrets := batchSecrets(secretCacheSize)
+	c := clock.Fix(time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC))
+
+	for _, size := range []int{0, secretCacheSize} {
+		b.Run(fmt.Sprintf("cache=%d", size), func(b *testing.B) {
+			prev := secretCache
+			secretCache = newDecodedSecretCache(size)
+
+			b.Cleanup(func() { secretCache = prev })
+
+			for range b.N {
+				for _, s := range secrets {
+					_, _ = GenerateTOTPFromSecret(context.Background(), s, WithClock(c)) //nolint: errcheck
+				}
+			}
+		})
+	}
+}
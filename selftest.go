@@ -0,0 +1,90 @@
+package authenticator
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"go.nhat.io/secretstorage"
+)
+
+// SelfTest checks that the storage backend is reachable by writing, reading and deleting a temporary key in the account
+// and the namespace storages, and that the config can be read and written. It returns on the first failure, e.g. when
+// the keyring is locked or the config directory is not writable. The temporary keys are always deleted. The config is
+// not checked when the config registry is disabled with SetDefaultConfigOptions(WithoutConfigRegistry()).
+func SelfTest(ctx context.Context) error {
+	configMu.RLock()
+	defer configMu.RUnlock()
+
+	key := ".selftest-" + strconv.FormatInt(time.Now().UnixNano(), 36)
+
+	if err := selfTestStorage(ctx, "account", accountStorage, key, Account{Name: key}); err != nil {
+		return err
+	}
+
+	if err := selfTestStorage(ctx, "namespace", namespaceStorage, key, Namespace{Name: key}); err != nil {
+		return err
+	}
+
+	o := newConfigOptions()
+	if o.withoutRegistry {
+		return nil
+	}
+
+	return selfTestConfig(ctx, o.store())
+}
+
+func selfTestStorage[T any](ctx context.Context, name string, s secretstorage.Storage[T], key string, value T) (err error) {
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("self test failed: %w", err)
+	}
+
+	defer func() {
+		if dErr := s.Delete(serviceName, key); dErr != nil && !errors.Is(dErr, secretstorage.ErrNotFound) && err == nil {
+			err = fmt.Errorf("self test failed: failed to delete from %s storage: %w", name, dErr)
+		}
+	}()
+
+	if err := s.Set(serviceName, key, value); err != nil {
+		return fmt.Errorf("self test failed: failed to write to %s storage: %w", name, err)
+	}
+
+	if _, err := s.Get(serviceName, key); err != nil {
+		return fmt.Errorf("self test failed: failed to read from %s storage: %w", name, err)
+	}
+
+	return nil
+}
+
+// selfTestConfig loads the config. For a config file, it also checks that a file can be created next to it, without
+// touching the config file itself.
+func selfTestConfig(ctx context.Context, s ConfigStore) error {
+	if err := ctx.Err(); err != nil {
+		return fmt.Errorf("self test failed: %w", err)
+	}
+
+	if _, err := s.Load(); err != nil {
+		return fmt.Errorf("self test failed: %w", err)
+	}
+
+	fs, ok := s.(fileConfigStore)
+	if !ok {
+		return nil
+	}
+
+	path := filepath.Clean(fs.path)
+
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.selftest")
+	if err != nil {
+		return fmt.Errorf("self test failed: config file is not writable: %w", err)
+	}
+
+	_ = f.Close()           //nolint: errcheck
+	_ = os.Remove(f.Name()) //nolint: errcheck
+
+	return nil
+}
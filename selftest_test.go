@@ -0,0 +1,91 @@
+package authenticator_test
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.nhat.io/secretstorage"
+	mockss "go.nhat.io/secretstorage/mock"
+
+	"go.nhat.io/authenticator"
+)
+
+func TestSelfTest_Success(t *testing.T) {
+	setConfigFile(t)
+
+	accounts, namespaces := useInMemoryStorage(t)
+
+	err := authenticator.SelfTest(context.Background())
+	require.NoError(t, err)
+
+	// The temporary keys are deleted.
+	accountKeys, err := accounts.(authenticator.KeyLister).Keys("go.nhat.io/authenticator")
+	require.NoError(t, err)
+	assert.Empty(t, accountKeys)
+
+	namespaceKeys, err := namespaces.(authenticator.KeyLister).Keys("go.nhat.io/authenticator")
+	require.NoError(t, err)
+	assert.Empty(t, namespaceKeys)
+}
+
+func TestSelfTest_FailedToWrite(t *testing.T) {
+	setConfigFile(t)
+
+	setAccountStorage(t, func(s *mockss.Storage[authenticator.Account]) {
+		s.On("Set", "go.nhat.io/authenticator", mockss.Anything, mockss.Anything).
+			Return(assert.AnError)
+
+		s.On("Delete", "go.nhat.io/authenticator", mockss.Anything).
+			Return(secretstorage.ErrNotFound)
+	})
+
+	// The namespace storage is not reached.
+	setNamespaceStorage(t, func(*mockss.Storage[authenticator.Namespace]) {})
+
+	err := authenticator.SelfTest(context.Background())
+	require.ErrorIs(t, err, assert.AnError)
+	require.EqualError(t, err, "self test failed: failed to write to account storage: assert.AnError general error for testing")
+}
+
+func TestSelfTest_FailedToRead(t *testing.T) {
+	setConfigFile(t)
+
+	setAccountStorage(t, func(s *mockss.Storage[authenticator.Account]) {
+		s.On("Set", "go.nhat.io/authenticator", mockss.Anything, mockss.Anything).
+			Return(nil)
+
+		s.On("Get", "go.nhat.io/authenticator", mockss.Anything).
+			Return(authenticator.Account{}, assert.AnError)
+
+		s.On("Delete", "go.nhat.io/authenticator", mockss.Anything).Once().
+			Return(nil)
+	})
+
+	err := authenticator.SelfTest(context.Background())
+	require.EqualError(t, err, "self test failed: failed to read from account storage: assert.AnError general error for testing")
+}
+
+func TestSelfTest_ConfigNotWritable(t *testing.T) {
+	t.Setenv("AUTHENTICATOR_CONFIG", filepath.Join(t.TempDir(), "missing", ".authenticator.toml"))
+
+	useInMemoryStorage(t)
+
+	err := authenticator.SelfTest(context.Background())
+	require.ErrorContains(t, err, "self test failed: config file is not writable")
+}
+
+func TestSelfTest_ContextCanceled(t *testing.T) {
+	setConfigFile(t)
+
+	// No storage call is expected.
+	setAccountStorage(t, func(*mockss.Storage[authenticator.Account]) {})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	err := authenticator.SelfTest(ctx)
+	require.ErrorIs(t, err, context.Canceled)
+}
@@ -0,0 +1,53 @@
+package authenticator
+
+import (
+	"go.nhat.io/secretstorage"
+)
+
+// StorageBackend is the pair of storages where the accounts and the namespaces are kept.
+type StorageBackend struct {
+	Accounts   secretstorage.Storage[Account]
+	Namespaces secretstorage.Storage[Namespace]
+}
+
+// KeyLister is implemented by the storages that can enumerate their keys, such as the in-memory and the encrypted file
+// storages. The system keyring cannot.
+type KeyLister interface {
+	// Keys returns the keys of the service, sorted.
+	Keys(service string) ([]string, error)
+}
+
+// CurrentStorage returns the storage backend in use. By default, the accounts and the namespaces are kept in the
+// system keyring.
+func CurrentStorage() StorageBackend {
+	configMu.RLock()
+	defer configMu.RUnlock()
+
+	return StorageBackend{
+		Accounts:   accountStorage,
+		Namespaces: namespaceStorage,
+	}
+}
+
+// UseStorage switches the storage backend for the accounts and the namespaces. The returned func restores the previous
+// backend.
+func UseStorage(accounts secretstorage.Storage[Account], namespaces secretstorage.Storage[Namespace]) func() {
+	configMu.Lock()
+	defer configMu.Unlock()
+
+	prev := StorageBackend{
+		Accounts:   accountStorage,
+		Namespaces: namespaceStorage,
+	}
+
+	accountStorage = accounts
+	namespaceStorage = namespaces
+
+	return func() {
+		configMu.Lock()
+		defer configMu.Unlock()
+
+		accountStorage = prev.Accounts
+		namespaceStorage = prev.Namespaces
+	}
+}
@@ -0,0 +1,323 @@
+package authenticator
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"sync"
+
+	"go.nhat.io/secretstorage"
+	"golang.org/x/crypto/scrypt"
+)
+
+const (
+	encryptedFileMagic = "AUTHENC1"
+
+	encryptedFileSaltSize = 16
+	encryptedFileKeySize  = 32
+
+	// scrypt parameters recommended for interactive logins.
+	encryptedFileScryptN = 1 << 15
+	encryptedFileScryptR = 8
+	encryptedFileScryptP = 1
+)
+
+var (
+	// ErrInvalidPassphrase indicates that the encrypted file could not be decrypted with the passphrase.
+	ErrInvalidPassphrase = errors.New("invalid passphrase or corrupted file")
+	// ErrInvalidEncryptedFile indicates that the file is not an encrypted storage file.
+	ErrInvalidEncryptedFile = errors.New("invalid encrypted storage file")
+)
+
+// encryptedFileLocks are the locks of the encrypted files, by path, so that the storages of the same file in the process
+// do not overwrite the changes of each other.
+var encryptedFileLocks sync.Map
+
+type encryptedFileStorage[V any] struct {
+	mu         *sync.Mutex
+	path       string
+	passphrase []byte
+
+	salt []byte
+	key  []byte
+}
+
+// NewEncryptedFileStorage creates a storage that keeps all the values in a single file, encrypted at rest with
+// AES-256-GCM. The key is derived from the passphrase with scrypt. The file is created on the first write.
+//
+// The storages of the same file in the process share a lock. The file is not locked across processes, only one process
+// may write to it at a time.
+func NewEncryptedFileStorage[V any](path string, passphrase []byte) secretstorage.Storage[V] {
+	path = filepath.Clean(path)
+
+	return &encryptedFileStorage[V]{
+		mu:         encryptedFileLock(path),
+		path:       path,
+		passphrase: bytes.Clone(passphrase),
+	}
+}
+
+// encryptedFileLock returns the lock of the file.
+func encryptedFileLock(path string) *sync.Mutex {
+	if abs, err := filepath.Abs(path); err == nil {
+		path = abs
+	}
+
+	mu, _ := encryptedFileLocks.LoadOrStore(path, new(sync.Mutex))
+
+	return mu.(*sync.Mutex) //nolint: forcetypeassert
+}
+
+// Set sets the value for the key in the service.
+func (s *encryptedFileStorage[V]) Set(service string, key string, value V) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	values, err := s.load()
+	if err != nil {
+		return err
+	}
+
+	b, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Errorf("failed to encode value: %w", err)
+	}
+
+	if values[service] == nil {
+		values[service] = make(map[string]json.RawMessage)
+	}
+
+	values[service][key] = b
+
+	return s.save(values)
+}
+
+// Get returns the value for the key in the service, or secretstorage.ErrNotFound if there is none.
+func (s *encryptedFileStorage[V]) Get(service string, key string) (V, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var v V
+
+	values, err := s.load()
+	if err != nil {
+		return v, err
+	}
+
+	b, ok := values[service][key]
+	if !ok {
+		return v, secretstorage.ErrNotFound
+	}
+
+	if err := json.Unmarshal(b, &v); err != nil {
+		return v, fmt.Errorf("failed to decode value: %w", err)
+	}
+
+	return v, nil
+}
+
+// Delete deletes the value for the key in the service. Deleting a missing key is not an error.
+func (s *encryptedFileStorage[V]) Delete(service string, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	values, err := s.load()
+	if err != nil {
+		return err
+	}
+
+	if _, ok := values[service][key]; !ok {
+		return nil
+	}
+
+	delete(values[service], key)
+
+	if len(values[service]) == 0 {
+		delete(values, service)
+	}
+
+	return s.save(values)
+}
+
+// Keys returns the keys of the service, sorted.
+func (s *encryptedFileStorage[V]) Keys(service string) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	values, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]string, 0, len(values[service]))
+
+	for k := range values[service] {
+		keys = append(keys, k)
+	}
+
+	slices.Sort(keys)
+
+	return keys, nil
+}
+
+// load reads and decrypts the file. The layout is the magic, the scrypt salt, the GCM nonce and the ciphertext.
+func (s *encryptedFileStorage[V]) load() (map[string]map[string]json.RawMessage, error) {
+	data, err := os.ReadFile(s.path)
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return make(map[string]map[string]json.RawMessage), nil
+		}
+
+		return nil, fmt.Errorf("failed to read encrypted storage file: %w", err)
+	}
+
+	if !bytes.HasPrefix(data, []byte(encryptedFileMagic)) || len(data) < len(encryptedFileMagic)+encryptedFileSaltSize {
+		return nil, fmt.Errorf("failed to read encrypted storage file: %w", ErrInvalidEncryptedFile)
+	}
+
+	data = data[len(encryptedFileMagic):]
+	salt, data := data[:encryptedFileSaltSize], data[encryptedFileSaltSize:]
+
+	if err := s.deriveKey(salt); err != nil {
+		return nil, err
+	}
+
+	gcm, err := s.cipher()
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) < gcm.NonceSize() {
+		return nil, fmt.Errorf("failed to read encrypted storage file: %w", ErrInvalidEncryptedFile)
+	}
+
+	nonce, ciphertext := data[:gcm.NonceSize()], data[gcm.NonceSize():]
+
+	plaintext, err := gcm.Open(nil, nonce, ciphertext, []byte(encryptedFileMagic))
+	if err != nil {
+		return nil, fmt.Errorf("failed to decrypt encrypted storage file: %w", ErrInvalidPassphrase)
+	}
+
+	values := make(map[string]map[string]json.RawMessage)
+
+	if err := json.Unmarshal(plaintext, &values); err != nil {
+		return nil, fmt.Errorf("failed to decode encrypted storage file: %w", err)
+	}
+
+	return values, nil
+}
+
+func (s *encryptedFileStorage[V]) save(values map[string]map[string]json.RawMessage) error {
+	if s.key == nil {
+		salt := make([]byte, encryptedFileSaltSize)
+
+		if _, err := io.ReadFull(rand.Reader, salt); err != nil {
+			return fmt.Errorf("failed to generate salt: %w", err)
+		}
+
+		if err := s.deriveKey(salt); err != nil {
+			return err
+		}
+	}
+
+	plaintext, err := json.Marshal(values)
+	if err != nil {
+		return fmt.Errorf("failed to encode encrypted storage file: %w", err)
+	}
+
+	gcm, err := s.cipher()
+	if err != nil {
+		return err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+
+	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
+		return fmt.Errorf("failed to generate nonce: %w", err)
+	}
+
+	data := make([]byte, 0, len(encryptedFileMagic)+len(s.salt)+len(nonce)+len(plaintext)+gcm.Overhead())
+	data = append(data, encryptedFileMagic...)
+	data = append(data, s.salt...)
+	data = append(data, nonce...)
+	data = gcm.Seal(data, nonce, plaintext, []byte(encryptedFileMagic))
+
+	return writeEncryptedFile(s.path, data)
+}
+
+// writeEncryptedFile writes to a temporary file in the same directory first, then renames it, so that a failed write
+// does not corrupt the store.
+func writeEncryptedFile(path string, data []byte) (err error) {
+	f, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to open encrypted storage file: %w", err)
+	}
+
+	defer func() {
+		if err != nil {
+			_ = f.Close()           //nolint: errcheck
+			_ = os.Remove(f.Name()) //nolint: errcheck
+		}
+	}()
+
+	if err := f.Chmod(0o600); err != nil {
+		return fmt.Errorf("failed to open encrypted storage file: %w", err)
+	}
+
+	if _, err := f.Write(data); err != nil {
+		return fmt.Errorf("failed to write encrypted storage file: %w", err)
+	}
+
+	if err := f.Sync(); err != nil {
+		return fmt.Errorf("failed to write encrypted storage file: %w", err)
+	}
+
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("failed to write encrypted storage file: %w", err)
+	}
+
+	if err := os.Rename(f.Name(), path); err != nil {
+		return fmt.Errorf("failed to write encrypted storage file: %w", err)
+	}
+
+	return nil
+}
+
+// deriveKey derives the key from the passphrase, unless it was already derived for the same salt.
+func (s *encryptedFileStorage[V]) deriveKey(salt []byte) error {
+	if s.key != nil && bytes.Equal(s.salt, salt) {
+		return nil
+	}
+
+	key, err := scrypt.Key(s.passphrase, salt, encryptedFileScryptN, encryptedFileScryptR, encryptedFileScryptP, encryptedFileKeySize)
+	if err != nil {
+		return fmt.Errorf("failed to derive key: %w", err)
+	}
+
+	s.salt = bytes.Clone(salt)
+	s.key = key
+
+	return nil
+}
+
+func (s *encryptedFileStorage[V]) cipher() (cipher.AEAD, error) {
+	block, err := aes.NewCipher(s.key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+
+	return gcm, nil
+}
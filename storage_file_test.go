@@ -0,0 +1,178 @@
+package authenticator_test
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.nhat.io/secretstorage"
+
+	"go.nhat.io/authenticator"
+)
+
+func TestEncryptedFileStorage_ReadBySecondInstance(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "secrets.enc")
+	account := authenticator.Account{
+		Name:       "john.doe@example.com",
+		TOTPSecret: "NBSWY3DP",
+		Issuer:     "example.com",
+	}
+
+	s := authenticator.NewEncryptedFileStorage[authenticator.Account](path, []byte("passphrase"))
+
+	_, err := s.Get("service", "key")
+	require.ErrorIs(t, err, secretstorage.ErrNotFound)
+
+	err = s.Set("service", "key", account)
+	require.NoError(t, err)
+
+	content, err := os.ReadFile(path) //nolint: gosec
+	require.NoError(t, err)
+	assert.NotContains(t, string(content), "NBSWY3DP")
+
+	s = authenticator.NewEncryptedFileStorage[authenticator.Account](path, []byte("passphrase"))
+
+	actual, err := s.Get("service", "key")
+	require.NoError(t, err)
+	assert.Equal(t, account, actual)
+
+	_, err = s.Get("service", "unknown")
+	require.ErrorIs(t, err, secretstorage.ErrNotFound)
+
+	err = s.Delete("service", "key")
+	require.NoError(t, err)
+
+	_, err = s.Get("service", "key")
+	require.ErrorIs(t, err, secretstorage.ErrNotFound)
+}
+
+func TestEncryptedFileStorage_WrongPassphrase(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "secrets.enc")
+
+	err := authenticator.NewEncryptedFileStorage[string](path, []byte("passphrase")).Set("service", "key", "value")
+	require.NoError(t, err)
+
+	s := authenticator.NewEncryptedFileStorage[string](path, []byte("wrong"))
+
+	actual, err := s.Get("service", "key")
+	require.ErrorIs(t, err, authenticator.ErrInvalidPassphrase)
+	assert.Empty(t, actual)
+
+	err = s.Set("service", "other", "value")
+	require.ErrorIs(t, err, authenticator.ErrInvalidPassphrase)
+}
+
+func TestEncryptedFileStorage_InvalidFile(t *testing.T) {
+	t.Parallel()
+
+	path := filepath.Join(t.TempDir(), "secrets.enc")
+
+	err := os.WriteFile(path, []byte("not encrypted"), 0o600)
+	require.NoError(t, err)
+
+	s := authenticator.NewEncryptedFileStorage[string](path, []byte("passphrase"))
+
+	_, err = s.Get("service", "key")
+	require.ErrorIs(t, err, authenticator.ErrInvalidEncryptedFile)
+}
+
+func TestUseStorage_EncryptedFile(t *testing.T) {
+	setConfigFile(t)
+	setAccountClock(t)
+
+	dir := t.TempDir()
+	passphrase := []byte("passphrase")
+
+	t.Cleanup(authenticator.UseStorage(
+		authenticator.NewEncryptedFileStorage[authenticator.Account](filepath.Join(dir, "accounts.enc"), passphrase),
+		authenticator.NewEncryptedFileStorage[authenticator.Namespace](filepath.Join(dir, "namespaces.enc"), passphrase),
+	))
+
+	err := authenticator.CreateNamespace("ns", "Namespace")
+	require.NoError(t, err)
+
+	account := authenticator.Account{
+		Name:       "john.doe@example.com",
+		TOTPSecret: "NBSWY3DP",
+	}
+
+	err = authenticator.SetAccount("ns", account)
+	require.NoError(t, err)
+
+	actual, err := authenticator.ListAccounts("ns")
+	require.NoError(t, err)
+
+	account.CreatedAt = accountTime
+	account.UpdatedAt = accountTime
+
+	assert.Equal(t, []authenticator.Account{account}, actual)
+}
+
+func TestEncryptedFileStorage_Keys(t *testing.T) {
+	t.Parallel()
+
+	s := authenticator.NewEncryptedFileStorage[string](filepath.Join(t.TempDir(), "secrets.enc"), []byte("passphrase"))
+
+	actual, err := s.(authenticator.KeyLister).Keys("service") //nolint: forcetypeassert
+	require.NoError(t, err)
+	assert.Empty(t, actual)
+
+	for _, key := range []string{"b", "a"} {
+		err := s.Set("service", key, "value")
+		require.NoError(t, err)
+	}
+
+	err = s.Set("other", "c", "value")
+	require.NoError(t, err)
+
+	actual, err = s.(authenticator.KeyLister).Keys("service") //nolint: forcetypeassert
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "b"}, actual)
+}
+
+func TestEncryptedFileStorage_ConcurrentInstances(t *testing.T) {
+	t.Parallel()
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "secrets.enc")
+	storages := []secretstorage.Storage[string]{
+		authenticator.NewEncryptedFileStorage[string](path, []byte("passphrase")),
+		authenticator.NewEncryptedFileStorage[string](path, []byte("passphrase")),
+	}
+
+	// Create the file so that both instances use the same key.
+	err := storages[0].Set("service", "init", "value")
+	require.NoError(t, err)
+
+	var wg sync.WaitGroup
+
+	for i, s := range storages {
+		wg.Add(1)
+
+		go func() {
+			defer wg.Done()
+
+			for j := range 5 {
+				assert.NoError(t, s.Set("service", fmt.Sprintf("key-%d-%d", i, j), "value"))
+			}
+		}()
+	}
+
+	wg.Wait()
+
+	actual, err := storages[1].(authenticator.KeyLister).Keys("service") //nolint: forcetypeassert
+	require.NoError(t, err)
+	assert.Len(t, actual, 11, "no update is lost")
+
+	entries, err := os.ReadDir(dir)
+	require.NoError(t, err)
+	assert.Len(t, entries, 1, "no temporary file is left")
+}
@@ -0,0 +1,119 @@
+package authenticator
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+
+	"go.nhat.io/secretstorage"
+)
+
+// KeyringBackend is a platform secret store.
+type KeyringBackend string
+
+const (
+	// KeyringAuto selects the secret store of the current platform.
+	KeyringAuto KeyringBackend = ""
+	// KeyringMacOSKeychain is the macOS Keychain.
+	KeyringMacOSKeychain KeyringBackend = "keychain"
+	// KeyringWindowsCredentialManager is the Windows Credential Manager.
+	KeyringWindowsCredentialManager KeyringBackend = "wincred"
+	// KeyringSecretService is the Secret Service dbus interface on Linux and *BSD, e.g. GNOME Keyring.
+	KeyringSecretService KeyringBackend = "secret-service"
+)
+
+var (
+	// ErrUnsupportedKeyringBackend indicates that the keyring backend is not available on the current platform.
+	ErrUnsupportedKeyringBackend = errors.New("unsupported keyring backend")
+	// ErrKeyringUnavailable indicates that the platform has no secret store, e.g. a headless Linux without a dbus
+	// session.
+	ErrKeyringUnavailable = errors.New("keyring is unavailable, use NewEncryptedFileStorage to keep the accounts in a file instead")
+)
+
+// keyringPlatform describes what the current platform offers to keep the secrets.
+type keyringPlatform struct {
+	os          string
+	dbusSession bool
+}
+
+// detectKeyringPlatform is replaced in the tests to fake the platform.
+var detectKeyringPlatform = func() keyringPlatform {
+	return keyringPlatform{
+		os:          runtime.GOOS,
+		dbusSession: hasDBusSession(),
+	}
+}
+
+// hasDBusSession tells whether a dbus session bus can be found, the same way as the dbus client does, without
+// connecting to it.
+func hasDBusSession() bool {
+	if os.Getenv("DBUS_SESSION_BUS_ADDRESS") != "" {
+		return true
+	}
+
+	dir := os.Getenv("XDG_RUNTIME_DIR")
+	if dir == "" {
+		return false
+	}
+
+	_, err := os.Stat(filepath.Join(dir, "bus"))
+
+	return err == nil
+}
+
+// selectKeyringBackend returns the backend to use on the platform. The keyring library only supports the secret store
+// of the platform it is built for, so any other backend is rejected.
+func selectKeyringBackend(p keyringPlatform, backend KeyringBackend) (KeyringBackend, error) {
+	var supported KeyringBackend
+
+	switch p.os {
+	case "darwin":
+		supported = KeyringMacOSKeychain
+
+	case "windows":
+		supported = KeyringWindowsCredentialManager
+
+	case "linux", "freebsd", "openbsd", "netbsd", "dragonfly":
+		if !p.dbusSession {
+			return "", fmt.Errorf("%w: no dbus session for the secret service on %s", ErrKeyringUnavailable, p.os)
+		}
+
+		supported = KeyringSecretService
+
+	default:
+		return "", fmt.Errorf("%w: no secret store on %s", ErrKeyringUnavailable, p.os)
+	}
+
+	if backend != KeyringAuto && backend != supported {
+		return "", fmt.Errorf("%w: %s is not available on %s", ErrUnsupportedKeyringBackend, backend, p.os)
+	}
+
+	return supported, nil
+}
+
+// NewKeyringStorageBackend creates the storages of the accounts and the namespaces in the platform secret store. It
+// returns ErrUnsupportedKeyringBackend if the backend is not the one of the current platform, and ErrKeyringUnavailable
+// if the platform has none.
+func NewKeyringStorageBackend(backend KeyringBackend) (StorageBackend, error) {
+	if _, err := selectKeyringBackend(detectKeyringPlatform(), backend); err != nil {
+		return StorageBackend{}, fmt.Errorf("failed to select keyring backend: %w", err)
+	}
+
+	return StorageBackend{
+		Accounts:   secretstorage.NewKeyringStorage[Account](),
+		Namespaces: secretstorage.NewKeyringStorage[Namespace](),
+	}, nil
+}
+
+// UseKeyringStorage switches the storage backend to the platform secret store like UseStorage. The returned func
+// restores the previous backend.
+func UseKeyringStorage(backend KeyringBackend) (func(), error) {
+	b, err := NewKeyringStorageBackend(backend)
+	if err != nil {
+		return nil, err
+	}
+
+	return UseStorage(b.Accounts, b.Namespaces), nil
+}
@@ -0,0 +1,115 @@
+package authenticator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func setKeyringPlatform(t *testing.T, p keyringPlatform) {
+	t.Helper()
+
+	prev := detectKeyringPlatform
+	detectKeyringPlatform = func() keyringPlatform { return p }
+
+	t.Cleanup(func() {
+		detectKeyringPlatform = prev
+	})
+}
+
+func TestSelectKeyringBackend(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		scenario      string
+		platform      keyringPlatform
+		backend       KeyringBackend
+		expected      KeyringBackend
+		expectedError error
+	}{
+		{
+			scenario: "auto on darwin",
+			platform: keyringPlatform{os: "darwin"},
+			expected: KeyringMacOSKeychain,
+		},
+		{
+			scenario: "keychain on darwin",
+			platform: keyringPlatform{os: "darwin"},
+			backend:  KeyringMacOSKeychain,
+			expected: KeyringMacOSKeychain,
+		},
+		{
+			scenario: "auto on windows",
+			platform: keyringPlatform{os: "windows"},
+			expected: KeyringWindowsCredentialManager,
+		},
+		{
+			scenario:      "keychain on windows",
+			platform:      keyringPlatform{os: "windows"},
+			backend:       KeyringMacOSKeychain,
+			expectedError: ErrUnsupportedKeyringBackend,
+		},
+		{
+			scenario: "secret service on linux",
+			platform: keyringPlatform{os: "linux", dbusSession: true},
+			backend:  KeyringSecretService,
+			expected: KeyringSecretService,
+		},
+		{
+			scenario:      "wincred on linux",
+			platform:      keyringPlatform{os: "linux", dbusSession: true},
+			backend:       KeyringWindowsCredentialManager,
+			expectedError: ErrUnsupportedKeyringBackend,
+		},
+		{
+			scenario:      "headless linux",
+			platform:      keyringPlatform{os: "linux"},
+			expectedError: ErrKeyringUnavailable,
+		},
+		{
+			scenario:      "unknown platform",
+			platform:      keyringPlatform{os: "plan9"},
+			expectedError: ErrKeyringUnavailable,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.scenario, func(t *testing.T) {
+			t.Parallel()
+
+			actual, err := selectKeyringBackend(tc.platform, tc.backend)
+			if tc.expectedError != nil {
+				require.ErrorIs(t, err, tc.expectedError)
+			} else {
+				require.NoError(t, err)
+			}
+
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+}
+
+func TestUseKeyringStorage_HeadlessLinux(t *testing.T) {
+	setKeyringPlatform(t, keyringPlatform{os: "linux"})
+
+	restore, err := UseKeyringStorage(KeyringAuto)
+	require.ErrorIs(t, err, ErrKeyringUnavailable)
+	require.EqualError(t, err, "failed to select keyring backend: keyring is unavailable, use NewEncryptedFileStorage to keep the accounts in a file instead: no dbus session for the secret service on linux")
+	assert.Nil(t, restore)
+}
+
+func TestUseKeyringStorage_Success(t *testing.T) {
+	setKeyringPlatform(t, keyringPlatform{os: "darwin"})
+
+	prev := CurrentStorage()
+
+	restore, err := UseKeyringStorage(KeyringMacOSKeychain)
+	require.NoError(t, err)
+
+	assert.NotSame(t, prev.Accounts, CurrentStorage().Accounts)
+
+	restore()
+
+	assert.Same(t, prev.Accounts, CurrentStorage().Accounts)
+}
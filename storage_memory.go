@@ -0,0 +1,80 @@
+package authenticator
+
+import (
+	"slices"
+	"strings"
+	"sync"
+
+	"go.nhat.io/secretstorage"
+)
+
+type inMemoryStorage[V any] struct {
+	mu     sync.RWMutex
+	values map[string]V
+}
+
+// NewInMemoryStorage creates a storage that keeps the values in a map, keyed by service and key. It is safe for
+// concurrent use and is meant for tests and ephemeral use, nothing is persisted.
+func NewInMemoryStorage[V any]() secretstorage.Storage[V] {
+	return &inMemoryStorage[V]{
+		values: make(map[string]V),
+	}
+}
+
+// Set sets the value for the key in the service.
+func (s *inMemoryStorage[V]) Set(service string, key string, value V) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.values[inMemoryStorageKey(service, key)] = value
+
+	return nil
+}
+
+// Get returns the value for the key in the service, or secretstorage.ErrNotFound if there is none.
+func (s *inMemoryStorage[V]) Get(service string, key string) (V, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	v, ok := s.values[inMemoryStorageKey(service, key)]
+	if !ok {
+		var empty V
+
+		return empty, secretstorage.ErrNotFound
+	}
+
+	return v, nil
+}
+
+// Delete deletes the value for the key in the service. Deleting a missing key is not an error.
+func (s *inMemoryStorage[V]) Delete(service string, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.values, inMemoryStorageKey(service, key))
+
+	return nil
+}
+
+// Keys returns the keys of the service, sorted.
+func (s *inMemoryStorage[V]) Keys(service string) ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	prefix := inMemoryStorageKey(service, "")
+	keys := make([]string, 0)
+
+	for k := range s.values {
+		if key, ok := strings.CutPrefix(k, prefix); ok {
+			keys = append(keys, key)
+		}
+	}
+
+	slices.Sort(keys)
+
+	return keys, nil
+}
+
+func inMemoryStorageKey(service, key string) string {
+	return service + "\x00" + key
+}
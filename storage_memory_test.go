@@ -0,0 +1,103 @@
+package authenticator_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.nhat.io/secretstorage"
+
+	"go.nhat.io/authenticator"
+)
+
+func TestInMemoryStorage_GetMissing(t *testing.T) {
+	t.Parallel()
+
+	s := authenticator.NewInMemoryStorage[string]()
+
+	actual, err := s.Get("service", "key")
+	require.ErrorIs(t, err, secretstorage.ErrNotFound)
+	assert.Empty(t, actual)
+}
+
+func TestInMemoryStorage_SetThenGet(t *testing.T) {
+	t.Parallel()
+
+	s := authenticator.NewInMemoryStorage[authenticator.Account]()
+	account := authenticator.Account{Name: "john.doe@example.com", TOTPSecret: "NBSWY3DP"}
+
+	err := s.Set("service", "key", account)
+	require.NoError(t, err)
+
+	actual, err := s.Get("service", "key")
+	require.NoError(t, err)
+	assert.Equal(t, account, actual)
+
+	_, err = s.Get("other", "key")
+	require.ErrorIs(t, err, secretstorage.ErrNotFound)
+
+	_, err = s.Get("service", "other")
+	require.ErrorIs(t, err, secretstorage.ErrNotFound)
+}
+
+func TestInMemoryStorage_Delete(t *testing.T) {
+	t.Parallel()
+
+	s := authenticator.NewInMemoryStorage[string]()
+
+	err := s.Set("service", "key", "value")
+	require.NoError(t, err)
+
+	err = s.Delete("service", "key")
+	require.NoError(t, err)
+
+	_, err = s.Get("service", "key")
+	require.ErrorIs(t, err, secretstorage.ErrNotFound)
+
+	// Deleting a missing key is not an error.
+	err = s.Delete("service", "key")
+	require.NoError(t, err)
+}
+
+func TestInMemoryStorage_Concurrency(t *testing.T) {
+	t.Parallel()
+
+	s := authenticator.NewInMemoryStorage[int]()
+	done := make(chan struct{})
+
+	for i := range 10 {
+		go func() {
+			defer func() { done <- struct{}{} }()
+
+			_ = s.Set("service", "key", i) //nolint: errcheck
+			_, _ = s.Get("service", "key") //nolint: errcheck
+			_ = s.Delete("service", "key") //nolint: errcheck
+		}()
+	}
+
+	for range 10 {
+		<-done
+	}
+}
+
+func TestInMemoryStorage_Keys(t *testing.T) {
+	t.Parallel()
+
+	s := authenticator.NewInMemoryStorage[string]()
+
+	for _, key := range []string{"b", "a", "c"} {
+		err := s.Set("service", key, "value")
+		require.NoError(t, err)
+	}
+
+	err := s.Set("other", "d", "value")
+	require.NoError(t, err)
+
+	actual, err := s.(authenticator.KeyLister).Keys("service") //nolint: forcetypeassert
+	require.NoError(t, err)
+	assert.Equal(t, []string{"a", "b", "c"}, actual)
+
+	actual, err = s.(authenticator.KeyLister).Keys("unknown") //nolint: forcetypeassert
+	require.NoError(t, err)
+	assert.Empty(t, actual)
+}
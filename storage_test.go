@@ -0,0 +1,53 @@
+package authenticator_test
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"go.nhat.io/authenticator"
+)
+
+func TestUseStorage_InMemory(t *testing.T) {
+	setConfigFile(t)
+	setAccountClock(t)
+
+	prev := authenticator.CurrentStorage()
+
+	accounts := authenticator.NewInMemoryStorage[authenticator.Account]()
+	namespaces := authenticator.NewInMemoryStorage[authenticator.Namespace]()
+
+	restore := authenticator.UseStorage(accounts, namespaces)
+
+	t.Cleanup(restore)
+
+	current := authenticator.CurrentStorage()
+
+	assert.Same(t, accounts, current.Accounts)
+	assert.Same(t, namespaces, current.Namespaces)
+
+	err := authenticator.CreateNamespace("ns", "Namespace")
+	require.NoError(t, err)
+
+	account := authenticator.Account{
+		Name:       "john.doe@example.com",
+		TOTPSecret: "NBSWY3DP",
+		Issuer:     "example.com",
+	}
+
+	err = authenticator.SetAccount("ns", account)
+	require.NoError(t, err)
+
+	actual, err := authenticator.GetAccount("ns", "john.doe@example.com")
+	require.NoError(t, err)
+
+	account.CreatedAt = accountTime
+	account.UpdatedAt = accountTime
+
+	assert.Equal(t, account, actual)
+
+	restore()
+
+	assert.Equal(t, prev, authenticator.CurrentStorage())
+}
@@ -3,39 +3,486 @@ package authenticator
 import (
 	"context"
 	"errors"
+	"fmt"
+	"slices"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/bool64/ctxd"
+	potp "github.com/pquerna/otp"
+	"github.com/pquerna/otp/hotp"
 	"go.nhat.io/clock"
 	"go.nhat.io/otp"
 )
 
 const envTOTPSecret = "AUTHENTICATOR_TOTP_SECRET"
 
+const (
+	defaultDigits = 6
+	minDigits     = 6
+	maxDigits     = 10
+	defaultPeriod = 30 * time.Second
+)
+
+const (
+	// AlgorithmSHA1 is the SHA1 hash algorithm, the default.
+	AlgorithmSHA1 = "SHA1"
+	// AlgorithmSHA256 is the SHA256 hash algorithm.
+	AlgorithmSHA256 = "SHA256"
+	// AlgorithmSHA512 is the SHA512 hash algorithm.
+	AlgorithmSHA512 = "SHA512"
+)
+
+var (
+	// ErrInvalidDigits indicates that the number of digits is not supported.
+	ErrInvalidDigits = errors.New("invalid number of digits")
+	// ErrUnsupportedAlgorithm indicates that the hash algorithm is not supported.
+	ErrUnsupportedAlgorithm = errors.New("unsupported algorithm")
+	// ErrInvalidPeriod indicates that the period is not a positive number of seconds.
+	ErrInvalidPeriod = errors.New("invalid period")
+	// ErrInvalidWindow indicates that the number of time steps before or after the current one is negative.
+	ErrInvalidWindow = errors.New("invalid window")
+	// ErrInvalidRange indicates that the end of a time range is before its start.
+	ErrInvalidRange = errors.New("invalid range")
+	// ErrRangeTooLarge indicates that a time range has more time steps than MaxTOTPSeriesSteps.
+	ErrRangeTooLarge = errors.New("range too large")
+	// ErrInvalidEpoch indicates that the time is before the epoch of the TOTP time steps.
+	ErrInvalidEpoch = errors.New("invalid epoch")
+	// ErrHOTPAccount indicates that a TOTP code is requested for a HOTP account.
+	ErrHOTPAccount = errors.New("account is a hotp account, use GenerateHOTPNext instead")
+)
+
 type generateTOTPConfig struct {
 	secretGetter otp.TOTPSecretGetter
+	gettersFirst otp.TOTPSecretGetters
+	gettersLast  otp.TOTPSecretGetters
 	logger       ctxd.Logger
+	clock        clock.Clock
+	digits       int
+	algorithm    string
+	period       time.Duration
+	epoch        time.Time
 	options      []otp.TOTPGeneratorOption
+	onGenerate   func(ctx context.Context, namespace, account string)
+	codeTTL      time.Duration
+	// accountType is the type of the account the secret comes from, if any.
+	accountType string
+	// generatorOptions tells whether the options are given by WithTOTPGeneratorOption.
+	generatorOptions bool
 }
 
-// GenerateTOTP generates a TOTP code for the given account.
-func GenerateTOTP(ctx context.Context, namespace, account string, opts ...GenerateTOTPOption) (otp.OTP, error) {
+// validate validates the parameters that are set.
+func (c *generateTOTPConfig) validate() error {
+	if c.digits != 0 && (c.digits < minDigits || c.digits > maxDigits) {
+		return fmt.Errorf("%w: %d, must be between %d and %d", ErrInvalidDigits, c.digits, minDigits, maxDigits)
+	}
+
+	if c.algorithm != "" {
+		if _, err := parseAlgorithm(c.algorithm); err != nil {
+			return err
+		}
+	}
+
+	if c.period < 0 || c.period%time.Second != 0 {
+		return fmt.Errorf("%w: %s, must be a positive number of seconds", ErrInvalidPeriod, c.period)
+	}
+
+	return nil
+}
+
+// withDefaults sets the parameters that are not explicitly set to the ones of the account, or to the defaults if the
+// account does not have them either.
+func (c *generateTOTPConfig) withDefaults(a Account) error {
+	if c.digits == 0 {
+		c.digits = a.Digits
+	}
+
+	if c.algorithm == "" {
+		c.algorithm = a.Algorithm
+	}
+
+	if c.period == 0 {
+		c.period = time.Duration(a.Period) * time.Second //nolint: gosec
+	}
+
+	if c.epoch.IsZero() && a.Epoch != 0 {
+		c.epoch = time.Unix(a.Epoch, 0)
+	}
+
+	if err := c.validate(); err != nil {
+		return err
+	}
+
+	if c.digits == 0 {
+		c.digits = defaultDigits
+	}
+
+	if c.algorithm == "" {
+		c.algorithm = AlgorithmSHA1
+	}
+
+	if c.period == 0 {
+		c.period = defaultPeriod
+	}
+
+	return nil
+}
+
+// totpSecret returns the secret from the additional getters that come first, then from the secret getter if it is set.
+// Otherwise, it looks up the secret in the env, then in the account, and uses the parameters of the account for the
+// parameters that are not explicitly set. The additional getters that come last are the fallback.
+func (c *generateTOTPConfig) totpSecret(ctx context.Context, namespace, account string) (otp.TOTPSecret, error) {
+	if s := c.gettersFirst.TOTPSecret(ctx); s != otp.NoTOTPSecret {
+		return s, c.withDefaults(Account{})
+	}
+
+	s, a := c.defaultTOTPSecret(ctx, namespace, account)
+	if s == otp.NoTOTPSecret {
+		if s = c.gettersLast.TOTPSecret(ctx); s != otp.NoTOTPSecret {
+			return s, c.withDefaults(Account{})
+		}
+	}
+
+	c.accountType = a.Type
+
+	return s, c.withDefaults(a)
+}
+
+// timeBasedTOTPSecret returns the secret like totpSecret, but rejects the secret of a HOTP account.
+func (c *generateTOTPConfig) timeBasedTOTPSecret(ctx context.Context, namespace, account string) (otp.TOTPSecret, error) {
+	s, err := c.totpSecret(ctx, namespace, account)
+	if err != nil {
+		return otp.NoTOTPSecret, err
+	}
+
+	if c.accountType == TypeHOTP {
+		return otp.NoTOTPSecret, fmt.Errorf("failed to generate totp for account %s in namespace %s: %w", account, namespace, ErrHOTPAccount)
+	}
+
+	return s, nil
+}
+
+// defaultTOTPSecret returns the secret from the secret getter, the env or the account, along with the account it comes
+// from, if any.
+func (c *generateTOTPConfig) defaultTOTPSecret(ctx context.Context, namespace, account string) (otp.TOTPSecret, Account) {
+	if c.secretGetter != nil {
+		return c.secretGetter.TOTPSecret(ctx), Account{}
+	}
+
+	if s := TOTPSecretFromEnv().TOTPSecret(ctx); s != otp.NoTOTPSecret {
+		return s, Account{}
+	}
+
+	p := TOTPSecretFromAccount(namespace, account, WithLogger(c.logger))
+	s := p.TOTPSecret(ctx)
+
+	return s, c.withNamespaceDefaults(ctx, namespace, p.fetchedAccount())
+}
+
+// withNamespaceDefaults fills the parameters that the account does not set with the defaults of its namespace.
+func (c *generateTOTPConfig) withNamespaceDefaults(ctx context.Context, namespace string, a Account) Account {
+	if a.Name == "" || (a.Digits != 0 && a.Algorithm != "" && a.Period != 0) {
+		return a
+	}
+
+	n, err := GetNamespace(namespace)
+	if err != nil {
+		c.logger.Debug(ctx, "could not get namespace defaults", "error", err, "namespace", namespace)
+
+		return a
+	}
+
+	return n.withDefaults(a)
+}
+
+// usesAccountSecret tells whether the secret can only come from the stored account. The codes of the other sources are
+// not cached, because the cache cannot tell when their secret changes.
+func (c *generateTOTPConfig) usesAccountSecret(ctx context.Context) bool {
+	return c.secretGetter == nil && len(c.gettersFirst) == 0 && len(c.gettersLast) == 0 &&
+		TOTPSecretFromEnv().TOTPSecret(ctx) == otp.NoTOTPSecret
+}
+
+func (c *generateTOTPConfig) isDefault() bool {
+	return c.digits == defaultDigits && strings.EqualFold(c.algorithm, AlgorithmSHA1) && c.period == defaultPeriod &&
+		c.epochUnix() == 0
+}
+
+// epochUnix returns the epoch of the time steps in seconds, 0 is the unix epoch.
+func (c *generateTOTPConfig) epochUnix() int64 {
+	if c.epoch.IsZero() {
+		return 0
+	}
+
+	return c.epoch.Unix()
+}
+
+// step returns the time step that contains the time, counted from the epoch.
+func (c *generateTOTPConfig) step(t time.Time) int64 {
+	return (t.Unix() - c.epochUnix()) / int64(c.period/time.Second)
+}
+
+// stepStart returns the time when the time step starts.
+func (c *generateTOTPConfig) stepStart(step int64) time.Time {
+	return time.Unix(c.epochUnix()+step*int64(c.period/time.Second), 0)
+}
+
+func (c *generateTOTPConfig) hotpOpts() hotp.ValidateOpts {
+	algo, _ := parseAlgorithm(c.algorithm) //nolint: errcheck // Validated by withDefaults.
+
+	return hotp.ValidateOpts{
+		Digits:    potp.Digits(c.digits),
+		Algorithm: algo,
+	}
+}
+
+func (c *generateTOTPConfig) generateTOTP(ctx context.Context, s otp.TOTPSecret) (otp.OTP, error) {
+	s = normalizeSecret(s)
+
+	// The options of WithTOTPGeneratorOption only apply to the otp generator.
+	if c.isDefault() && c.generatorOptions {
+		code, err := otp.GenerateTOTP(ctx, s, c.options...)
+		if err != nil {
+			return "", wrapInvalidSecret(err)
+		}
+
+		return code, nil
+	}
+
+	now := c.clock.Now()
+
+	if epoch := c.epochUnix(); epoch != 0 {
+		if now.Unix() < epoch {
+			return "", fmt.Errorf("could not generate otp: %w: %s is before %s", ErrInvalidEpoch,
+				now.Format(time.RFC3339), time.Unix(epoch, 0).Format(time.RFC3339))
+		}
+
+		// The time step of the library is counted from the unix epoch.
+		now = time.Unix(now.Unix()-epoch, 0)
+	}
+
+	return generateCode(s, uint64(now.Unix()/int64(c.period/time.Second)), c.hotpOpts()) //nolint: gosec
+}
+
+func newGenerateTOTPConfig(opts ...GenerateTOTPOption) (*generateTOTPConfig, error) {
 	c := &generateTOTPConfig{
 		logger: ctxd.NoOpLogger{},
+		clock:  clock.New(),
 	}
 
 	for _, opt := range opts {
 		opt.applyGenerateTOTPOption(c)
 	}
 
-	if c.secretGetter == nil {
-		c.secretGetter = otp.ChainTOTPSecretGetters(
-			TOTPSecretFromEnv(),
-			TOTPSecretFromAccount(namespace, account, WithLogger(c.logger)),
+	if err := c.validate(); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+// GenerateTOTP generates a TOTP code for the given account.
+//
+// The number of digits, the period and the hash algorithm are taken from the options, then from the account and its
+// namespace if the secret comes from the account, then the defaults.
+func GenerateTOTP(ctx context.Context, namespace, account string, opts ...GenerateTOTPOption) (otp.OTP, error) {
+	c, err := newGenerateTOTPConfig(opts...)
+	if err != nil {
+		return "", err
+	}
+
+	var (
+		cacheKey     string
+		cacheVersion uint64
+		now          time.Time
+	)
+
+	useCache := c.codeTTL > 0 && c.usesAccountSecret(ctx)
+
+	if useCache {
+		var (
+			code otp.OTP
+			ok   bool
 		)
+
+		cacheKey = codeCache.key(namespace, account, c)
+		now = c.clock.Now()
+
+		if code, cacheVersion, ok = codeCache.get(cacheKey, now); ok {
+			if c.onGenerate != nil {
+				c.onGenerate(ctx, namespace, account)
+			}
+
+			return code, nil
+		}
+
+		// Pin the time so that the cached code matches its time step.
+		c.clock = clock.Fix(now)
+		c.options = append(c.options, otp.WithClock(c.clock))
 	}
 
-	return otp.GenerateTOTP(ctx, c.secretGetter, c.options...) //nolint: wrapcheck
+	secret, err := c.timeBasedTOTPSecret(ctx, namespace, account)
+	if err != nil {
+		return "", err
+	}
+
+	code, err := c.generateTOTP(ctx, secret)
+	if err != nil {
+		return "", err
+	}
+
+	if useCache {
+		codeCache.set(cacheKey, cacheVersion, code, now, c.stepStart(c.step(now)), c.period, c.codeTTL)
+	}
+
+	if c.onGenerate != nil {
+		c.onGenerate(ctx, namespace, account)
+	}
+
+	return code, nil
+}
+
+// GenerateTOTPFromSecret generates a TOTP code from the given secret, without looking up the storage, the env or the
+// secret getters. The clock, digits, period and algorithm options are honored.
+func GenerateTOTPFromSecret(ctx context.Context, secret otp.TOTPSecret, opts ...GenerateTOTPOption) (otp.OTP, error) {
+	c, err := newGenerateTOTPConfig(opts...)
+	if err != nil {
+		return "", err
+	}
+
+	if err := c.withDefaults(Account{}); err != nil {
+		return "", err
+	}
+
+	return c.generateTOTP(ctx, secret)
+}
+
+// TOTPInfo is a TOTP code with its validity window.
+type TOTPInfo struct {
+	Code      otp.OTP
+	ExpiresAt time.Time
+	Period    time.Duration
+}
+
+// GenerateTOTPWithInfo generates a TOTP code for the given account and reports when the code expires.
+func GenerateTOTPWithInfo(ctx context.Context, namespace, account string, opts ...GenerateTOTPOption) (TOTPInfo, error) {
+	c, err := newGenerateTOTPConfig(opts...)
+	if err != nil {
+		return TOTPInfo{}, err
+	}
+
+	secret, err := c.timeBasedTOTPSecret(ctx, namespace, account)
+	if err != nil {
+		return TOTPInfo{}, err
+	}
+
+	// Pin the time so that the code and its expiry are computed from the same instant.
+	now := c.clock.Now()
+	c.clock = clock.Fix(now)
+	c.options = append(c.options, otp.WithClock(c.clock))
+
+	code, err := c.generateTOTP(ctx, secret)
+	if err != nil {
+		return TOTPInfo{}, err
+	}
+
+	expiresAt := c.stepStart(c.step(now) + 1).In(now.Location())
+
+	return TOTPInfo{
+		Code:      code,
+		ExpiresAt: expiresAt,
+		Period:    c.period,
+	}, nil
+}
+
+// GenerateTOTPWindow generates the TOTP codes of the time steps around the current one, ordered from the oldest to the
+// newest. The code of the current time step is at index before.
+func GenerateTOTPWindow(ctx context.Context, namespace, account string, before, after int, opts ...GenerateTOTPOption) ([]otp.OTP, error) {
+	if before < 0 || after < 0 {
+		return nil, fmt.Errorf("%w: %d before and %d after, must not be negative", ErrInvalidWindow, before, after)
+	}
+
+	c, err := newGenerateTOTPConfig(opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	secret, err := c.timeBasedTOTPSecret(ctx, namespace, account)
+	if err != nil {
+		return nil, err
+	}
+
+	now := c.clock.Now()
+	options := slices.Clip(c.options)
+	codes := make([]otp.OTP, 0, before+after+1)
+
+	for i := -before; i <= after; i++ {
+		c.clock = clock.Fix(now.Add(time.Duration(i) * c.period))
+		c.options = append(options, otp.WithClock(c.clock))
+
+		code, err := c.generateTOTP(ctx, secret)
+		if err != nil {
+			return nil, err
+		}
+
+		codes = append(codes, code)
+	}
+
+	return codes, nil
+}
+
+// MaxTOTPSeriesSteps is the maximum number of codes that GenerateTOTPSeries generates.
+const MaxTOTPSeriesSteps = 10_000
+
+// TOTPSeriesEntry is the TOTP code that is valid from the given time until the end of its time step.
+type TOTPSeriesEntry struct {
+	Time time.Time
+	Code otp.OTP
+}
+
+// GenerateTOTPSeries generates the TOTP codes of the time steps from the one containing from to the one containing to,
+// e.g. to find out which code was valid at a given time. The time of each entry is the start of its time step. It
+// returns ErrRangeTooLarge if there are more than MaxTOTPSeriesSteps time steps.
+func GenerateTOTPSeries(ctx context.Context, namespace, account string, from, to time.Time, opts ...GenerateTOTPOption) ([]TOTPSeriesEntry, error) {
+	if to.Before(from) {
+		return nil, fmt.Errorf("%w: %s is before %s", ErrInvalidRange, to.Format(time.RFC3339), from.Format(time.RFC3339))
+	}
+
+	c, err := newGenerateTOTPConfig(opts...)
+	if err != nil {
+		return nil, err
+	}
+
+	secret, err := c.timeBasedTOTPSecret(ctx, namespace, account)
+	if err != nil {
+		return nil, err
+	}
+
+	first := c.step(from)
+	last := c.step(to)
+
+	if steps := last - first + 1; steps > MaxTOTPSeriesSteps {
+		return nil, fmt.Errorf("%w: %d time steps, must not exceed %d", ErrRangeTooLarge, steps, MaxTOTPSeriesSteps)
+	}
+
+	options := slices.Clip(c.options)
+	series := make([]TOTPSeriesEntry, 0, last-first+1)
+
+	for step := first; step <= last; step++ {
+		at := c.stepStart(step).In(from.Location())
+
+		c.clock = clock.Fix(at)
+		c.options = append(options, otp.WithClock(c.clock))
+
+		code, err := c.generateTOTP(ctx, secret)
+		if err != nil {
+			return nil, err
+		}
+
+		series = append(series, TOTPSeriesEntry{Time: at, Code: code})
+	}
+
+	return series, nil
 }
 
 // GenerateTOTPOption is an option to configure generateTOTPConfig.
@@ -63,71 +510,300 @@ func WithTOTPSecretGetter(secretGetter otp.TOTPSecretGetter) GenerateTOTPOption
 	})
 }
 
+// WithAdditionalSecretGetter adds a secret getter to the default chain, which is the env then the account, or the getter
+// set by WithTOTPSecretGetter. With before, the getter is tried before the chain, otherwise it is the fallback when the
+// chain has no secret. The getters added on the same side are tried in the order they are added.
+func WithAdditionalSecretGetter(g otp.TOTPSecretGetter, before bool) GenerateTOTPOption {
+	return generateTOTPOptionFunc(func(cfg *generateTOTPConfig) {
+		if before {
+			cfg.gettersFirst = append(cfg.gettersFirst, g)
+		} else {
+			cfg.gettersLast = append(cfg.gettersLast, g)
+		}
+	})
+}
+
 // WithClock sets the clock to use.
-func WithClock(clock clock.Clock) GenerateTOTPOption {
+func WithClock(clock clock.Clock) Option {
+	return option{
+		GenerateTOTPOption: generateTOTPOptionFunc(func(cfg *generateTOTPConfig) {
+			cfg.clock = clock
+			cfg.options = append(cfg.options, otp.WithClock(clock))
+		}),
+		TOTPSecretProviderOption: totpSecretProviderOptionFunc(func(p *TOTPSecretProvider) {
+			p.clock = clock
+		}),
+	}
+}
+
+// WithCodeCache caches the code of the account in the process, so that the calls of GenerateTOTP within the same time
+// step do not look up the secret and compute the code again. A cached code is used for at most ttl, and never after the
+// end of its time step according to the clock. The cache is cleared when the account is updated or deleted.
+//
+// Only the codes from the secret of the stored account are cached. The cache is bypassed when the secret is given by
+// WithTOTPSecret, WithTOTPSecretGetter, WithAdditionalSecretGetter or the env.
+func WithCodeCache(ttl time.Duration) GenerateTOTPOption {
+	return generateTOTPOptionFunc(func(cfg *generateTOTPConfig) {
+		cfg.codeTTL = ttl
+	})
+}
+
+// WithTOTPGeneratorOption passes the options through to the otp generator, for the features that this package does not
+// wrap. The options only apply when the code is generated with the default digits, period and algorithm.
+func WithTOTPGeneratorOption(opts ...otp.TOTPGeneratorOption) GenerateTOTPOption {
 	return generateTOTPOptionFunc(func(cfg *generateTOTPConfig) {
-		cfg.options = append(cfg.options, otp.WithClock(clock))
+		cfg.options = append(cfg.options, opts...)
+		cfg.generatorOptions = true
 	})
 }
 
+// WithTime generates the code as of the given time. It is a shortcut for WithClock(clock.Fix(t)), the last time or clock
+// wins.
+func WithTime(t time.Time) GenerateTOTPOption {
+	return WithClock(clock.Fix(t))
+}
+
+// WithDigits sets the number of digits of the generated code. It must be between 6 and 10, 0 means the default, 6.
+func WithDigits(n int) GenerateTOTPOption {
+	return generateTOTPOptionFunc(func(cfg *generateTOTPConfig) {
+		cfg.digits = n
+	})
+}
+
+// WithPeriod sets the period of time a TOTP code is valid for. It must be a whole number of seconds, 0 means the default,
+// 30 seconds.
+func WithPeriod(period time.Duration) GenerateTOTPOption {
+	return generateTOTPOptionFunc(func(cfg *generateTOTPConfig) {
+		cfg.period = period
+	})
+}
+
+// WithEpoch sets the time the TOTP time steps are counted from, T0 in RFC 6238, for the systems that do not use the
+// unix epoch. The zero time means the unix epoch.
+func WithEpoch(t0 time.Time) GenerateTOTPOption {
+	return generateTOTPOptionFunc(func(cfg *generateTOTPConfig) {
+		cfg.epoch = t0
+	})
+}
+
+// WithAlgorithm sets the hash algorithm, one of SHA1, SHA256 or SHA512 (case-insensitive). Defaults to SHA1.
+func WithAlgorithm(algo string) GenerateTOTPOption {
+	return generateTOTPOptionFunc(func(cfg *generateTOTPConfig) {
+		cfg.algorithm = algo
+	})
+}
+
+// WithOnGenerate sets a callback that GenerateTOTP calls after a code is successfully generated, e.g. to count the
+// generations per account. It is not called when the generation fails.
+func WithOnGenerate(fn func(ctx context.Context, namespace, account string)) GenerateTOTPOption {
+	return generateTOTPOptionFunc(func(cfg *generateTOTPConfig) {
+		cfg.onGenerate = fn
+	})
+}
+
+func parseAlgorithm(algo string) (potp.Algorithm, error) {
+	switch strings.ToUpper(algo) {
+	case AlgorithmSHA1:
+		return potp.AlgorithmSHA1, nil
+
+	case AlgorithmSHA256:
+		return potp.AlgorithmSHA256, nil
+
+	case AlgorithmSHA512:
+		return potp.AlgorithmSHA512, nil
+	}
+
+	return 0, fmt.Errorf("%w: %s", ErrUnsupportedAlgorithm, algo)
+}
+
 // TOTPSecretFromEnv returns a TOTP secret from the environment.
 func TOTPSecretFromEnv() otp.TOTPSecretProvider {
 	return otp.TOTPSecretFromEnv(envTOTPSecret)
 }
 
+// TOTPSecretFromFunc adapts a function that fetches the secret, e.g. from Vault or a KMS, into a secret getter. The
+// errors are logged with the logger from WithLogger, and the getter returns otp.NoTOTPSecret.
+func TOTPSecretFromFunc(fn func(ctx context.Context) (otp.TOTPSecret, error), opts ...TOTPSecretProviderOption) otp.TOTPSecretGetter {
+	p := &TOTPSecretProvider{logger: ctxd.NoOpLogger{}}
+
+	for _, opt := range opts {
+		opt.applyTOTPSecretProviderOption(p)
+	}
+
+	return funcTOTPSecretGetter{fn: fn, logger: p.logger}
+}
+
+type funcTOTPSecretGetter struct {
+	fn     func(ctx context.Context) (otp.TOTPSecret, error)
+	logger ctxd.Logger
+}
+
+// TOTPSecret returns the secret from the function, or otp.NoTOTPSecret if it fails.
+func (g funcTOTPSecretGetter) TOTPSecret(ctx context.Context) otp.TOTPSecret {
+	s, err := g.fn(ctx)
+	if err != nil {
+		g.logger.Error(ctx, "could not get totp secret", "error", err)
+
+		return otp.NoTOTPSecret
+	}
+
+	return s
+}
+
 var _ otp.TOTPSecretProvider = (*TOTPSecretProvider)(nil)
 
 // TOTPSecretProvider manages the TOTP secret.
 type TOTPSecretProvider struct {
-	logger ctxd.Logger
+	logger          ctxd.Logger
+	clock           clock.Clock
+	refreshInterval time.Duration
 
 	namespace string
 	account   string
 	secret    otp.TOTPSecret
+	fetched   Account
+	fetchedAt time.Time
 
 	mu        sync.Mutex
 	fetchOnce sync.Once
 }
 
-func (s *TOTPSecretProvider) fetch(ctx context.Context) otp.TOTPSecret {
+// fetch gets the account from the storage. It only returns an error when the context is done before the account is
+// fetched, the other errors are logged.
+func (s *TOTPSecretProvider) fetch(ctx context.Context) (Account, error) {
 	ctx = ctxd.AddFields(ctx, "namespace", s.namespace, "account", s.account)
 
 	if s.namespace == "" {
 		s.logger.Debug(ctx, "failed to fetch totp secret due to missing namespace")
 
-		return otp.NoTOTPSecret
+		return Account{}, nil
 	} else if s.account == "" {
 		s.logger.Debug(ctx, "failed to fetch totp secret due to missing account")
 
-		return otp.NoTOTPSecret
+		return Account{}, nil
 	}
 
-	a, err := GetAccount(s.namespace, s.account)
-	if err != nil {
-		if errors.Is(err, ErrAccountNotFound) {
-			s.logger.Debug(ctx, "could not get totp secret", "error", err)
+	if err := ctx.Err(); err != nil {
+		s.logger.Debug(ctx, "could not get totp secret", "error", err)
+
+		return Account{}, err
+	}
+
+	type result struct {
+		account Account
+		err     error
+	}
+
+	// The storage does not support context, so the lookup is abandoned, not cancelled, when the context is done. The late
+	// result goes to the buffered channel that nobody reads, it is dropped and never cached.
+	done := make(chan result, 1)
+
+	go func() {
+		// The lookup is skipped if the context is done before it starts.
+		if err := ctx.Err(); err != nil {
+			done <- result{err: err}
+
+			return
+		}
+
+		a, err := GetAccount(s.namespace, s.account)
+
+		done <- result{account: a, err: err}
+	}()
+
+	var r result
+
+	select {
+	case <-ctx.Done():
+		s.logger.Debug(ctx, "could not get totp secret", "error", ctx.Err())
+
+		return Account{}, ctx.Err()
+
+	case r = <-done:
+	}
+
+	if r.err != nil {
+		if errors.Is(r.err, ErrAccountNotFound) {
+			s.logger.Debug(ctx, "could not get totp secret", "error", r.err)
 		} else {
-			s.logger.Error(ctx, "could not get totp secret", "error", err)
+			s.logger.Error(ctx, "could not get totp secret", "error", r.err)
 		}
 
-		return otp.NoTOTPSecret
+		return Account{}, nil
+	}
+
+	return r.account, nil
+}
+
+func (s *TOTPSecretProvider) now() time.Time {
+	if s.clock == nil {
+		return time.Now()
+	}
+
+	return s.clock.Now()
+}
+
+// load fetches the account and caches its secret. When the context is done, nothing is cached.
+func (s *TOTPSecretProvider) load(ctx context.Context) {
+	a, err := s.fetch(ctx)
+	if err != nil {
+		s.fetched = Account{}
+		s.secret = otp.NoTOTPSecret
+		s.fetchedAt = time.Time{}
+
+		return
 	}
 
-	return a.TOTPSecret
+	s.fetched = a
+	s.secret = a.TOTPSecret
+	s.fetchedAt = s.now()
+}
+
+// loadOnce loads the secret if it is not loaded yet. A load that was interrupted by the context is retried on the next
+// call.
+func (s *TOTPSecretProvider) loadOnce(ctx context.Context) {
+	s.fetchOnce.Do(func() {
+		s.load(ctx)
+	})
+
+	if s.fetchedAt.IsZero() {
+		s.fetchOnce = sync.Once{}
+	}
 }
 
-// TOTPSecret returns the TOTP secret from the keyring.
+// TOTPSecret returns the TOTP secret from the keyring. The secret is fetched once, then again after the refresh interval
+// if one is set.
 func (s *TOTPSecretProvider) TOTPSecret(ctx context.Context) otp.TOTPSecret {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	s.fetchOnce.Do(func() {
-		s.secret = s.fetch(ctx)
-	})
+	if s.refreshInterval > 0 && !s.fetchedAt.IsZero() && s.now().Sub(s.fetchedAt) >= s.refreshInterval {
+		s.fetchOnce = sync.Once{}
+	}
+
+	s.loadOnce(ctx)
 
 	return s.secret
 }
 
+// Refresh discards the cached secret and fetches it again, e.g. after the secret was rotated by another process.
+func (s *TOTPSecretProvider) Refresh(ctx context.Context) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.fetchOnce = sync.Once{}
+
+	s.loadOnce(ctx)
+}
+
+// fetchedAccount returns the account that was fetched along with the TOTP secret.
+func (s *TOTPSecretProvider) fetchedAccount() Account {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.fetched
+}
+
 // SetTOTPSecret sets the TOTP secret to the keyring.
 func (s *TOTPSecretProvider) SetTOTPSecret(_ context.Context, secret otp.TOTPSecret, issuer string) error {
 	s.mu.Lock()
@@ -152,6 +828,8 @@ func (s *TOTPSecretProvider) SetTOTPSecret(_ context.Context, secret otp.TOTPSec
 	account.TOTPSecret = secret
 	account.Issuer = issuer
 	s.secret = secret
+	s.fetched = account
+	s.fetchedAt = s.now()
 
 	return SetAccount(s.namespace, account)
 }
@@ -164,6 +842,8 @@ func (s *TOTPSecretProvider) DeleteTOTPSecret(context.Context) error {
 	s.fetchOnce.Do(func() {})
 
 	s.secret = otp.NoTOTPSecret
+	s.fetched = Account{}
+	s.fetchedAt = s.now()
 
 	return DeleteAccount(s.namespace, s.account)
 }
@@ -172,6 +852,7 @@ func (s *TOTPSecretProvider) DeleteTOTPSecret(context.Context) error {
 func TOTPSecretFromAccount(namespace, account string, opts ...TOTPSecretProviderOption) *TOTPSecretProvider {
 	p := &TOTPSecretProvider{
 		logger:    ctxd.NoOpLogger{},
+		clock:     clock.New(),
 		namespace: namespace,
 		account:   account,
 	}
@@ -193,3 +874,11 @@ type totpSecretProviderOptionFunc func(p *TOTPSecretProvider)
 func (f totpSecretProviderOptionFunc) applyTOTPSecretProviderOption(p *TOTPSecretProvider) {
 	f(p)
 }
+
+// WithRefreshInterval makes the TOTPSecretProvider fetch the secret again when the cached one is older than the interval.
+// By default, the secret is fetched only once.
+func WithRefreshInterval(d time.Duration) TOTPSecretProviderOption {
+	return totpSecretProviderOptionFunc(func(p *TOTPSecretProvider) {
+		p.refreshInterval = d
+	})
+}
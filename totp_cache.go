@@ -0,0 +1,91 @@
+package authenticator
+
+import (
+	"strconv"
+	"sync"
+	"time"
+
+	"go.nhat.io/otp"
+)
+
+// codeCacheSweepSize is the number of cached codes above which the expired ones are removed on each store.
+const codeCacheSweepSize = 1024
+
+// codeCache keeps the codes generated with WithCodeCache.
+var codeCache = &totpCodeCache{entries: make(map[string]cachedTOTPCode)}
+
+type cachedTOTPCode struct {
+	code      otp.OTP
+	validFrom time.Time
+	expiresAt time.Time
+}
+
+type totpCodeCache struct {
+	mu      sync.Mutex
+	entries map[string]cachedTOTPCode
+	// version changes on every invalidation, so that a code generated from a secret read before an update is not cached.
+	version uint64
+}
+
+// key identifies the code of the account generated with the explicit parameters of the config, before the defaults
+// apply.
+func (*totpCodeCache) key(namespace, account string, c *generateTOTPConfig) string {
+	return formatAccount(namespace, account) + "\x00" + strconv.Itoa(c.digits) + "\x00" + c.algorithm + "\x00" + c.period.String() +
+		"\x00" + strconv.FormatInt(c.epochUnix(), 10)
+}
+
+// get returns the cached code if it is still valid, and the version to pass to set otherwise.
+func (cc *totpCodeCache) get(key string, now time.Time) (otp.OTP, uint64, bool) {
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+
+	e, ok := cc.entries[key]
+	if !ok || now.Before(e.validFrom) || !now.Before(e.expiresAt) {
+		return "", cc.version, false
+	}
+
+	return e.code, cc.version, true
+}
+
+// set caches the code until the end of its time step, or until the ttl elapses if it is sooner. The code is not cached
+// if the cache has been invalidated since the version was read.
+func (cc *totpCodeCache) set(key string, version uint64, code otp.OTP, now, validFrom time.Time, period, ttl time.Duration) {
+	expiresAt := validFrom.Add(period)
+
+	if t := now.Add(ttl); t.Before(expiresAt) {
+		expiresAt = t
+	}
+
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+
+	if version != cc.version {
+		return
+	}
+
+	if len(cc.entries) >= codeCacheSweepSize {
+		for k, e := range cc.entries {
+			if !now.Before(e.expiresAt) {
+				delete(cc.entries, k)
+			}
+		}
+	}
+
+	cc.entries[key] = cachedTOTPCode{code: code, validFrom: validFrom, expiresAt: expiresAt}
+}
+
+// invalidate removes the codes of the account, e.g. when it is updated.
+func (cc *totpCodeCache) invalidate(namespace, account string) {
+	prefix := formatAccount(namespace, account) + "\x00"
+
+	cc.mu.Lock()
+	defer cc.mu.Unlock()
+
+	cc.version++
+
+	for k := range cc.entries {
+		if len(k) >= len(prefix) && k[:len(prefix)] == prefix {
+			delete(cc.entries, k)
+		}
+	}
+}
@@ -3,6 +3,7 @@ package authenticator_test
 import (
 	"context"
 	"fmt"
+	"sync"
 	"testing"
 	"time"
 
@@ -91,6 +92,150 @@ func TestGenerateTOTP_Success_FromAccount(t *testing.T) {
 	require.Equal(t, expected, actual)
 }
 
+func TestGenerateTOTP_Failure_HOTPAccount(t *testing.T) {
+	setConfigFile(t)
+
+	useInMemoryStorage(t)
+
+	t.Setenv("AUTHENTICATOR_TOTP_SECRET", "")
+
+	err := authenticator.CreateNamespace("namespace", "Namespace")
+	require.NoError(t, err)
+
+	err = authenticator.SetAccount("namespace", authenticator.Account{Name: "john.doe@example.com", TOTPSecret: "NBSWY3DP", Type: authenticator.TypeHOTP})
+	require.NoError(t, err)
+
+	actual, err := authenticator.GenerateTOTP(context.Background(), "namespace", "john.doe@example.com")
+	require.ErrorIs(t, err, authenticator.ErrHOTPAccount)
+	require.EqualError(t, err, "failed to generate totp for account john.doe@example.com in namespace namespace: account is a hotp account, use GenerateHOTPNext instead")
+	assert.Empty(t, actual)
+
+	// The secret of the account can still be used with the hotp api.
+	actual, err = authenticator.GenerateHOTP(context.Background(), "namespace", "john.doe@example.com", 0)
+	require.NoError(t, err)
+	assert.NotEmpty(t, actual)
+}
+
+func TestGenerateTOTP_Success_FromAccountWithParameters(t *testing.T) {
+	setAccountStorage(t, func(s *mockss.Storage[authenticator.Account]) {
+		s.On("Get", "go.nhat.io/authenticator", fmt.Sprintf("%s/%s", t.Name(), "john.doe@example.com")).
+			Return(authenticator.Account{
+				Name:       "john.doe@example.com",
+				TOTPSecret: "NBSWY3DP",
+				Digits:     8,
+				Period:     60,
+			}, nil)
+	})
+
+	c := clock.Fix(time.Date(2024, time.January, 1, 0, 0, 18, 0, time.UTC))
+
+	actual, err := authenticator.GenerateTOTP(context.Background(), t.Name(), "john.doe@example.com",
+		authenticator.WithClock(c),
+	)
+	require.NoError(t, err)
+
+	assert.Equal(t, otp.OTP("43637663"), actual)
+}
+
+func TestGenerateTOTP_Success_OptionsOverrideAccountParameters(t *testing.T) {
+	setAccountStorage(t, func(s *mockss.Storage[authenticator.Account]) {
+		s.On("Get", "go.nhat.io/authenticator", fmt.Sprintf("%s/%s", t.Name(), "john.doe@example.com")).
+			Return(authenticator.Account{
+				Name:       "john.doe@example.com",
+				TOTPSecret: "NBSWY3DP",
+				Digits:     8,
+			}, nil)
+	})
+
+	c := clock.Fix(time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC))
+
+	actual, err := authenticator.GenerateTOTP(context.Background(), t.Name(), "john.doe@example.com",
+		authenticator.WithClock(c),
+		authenticator.WithDigits(6),
+	)
+	require.NoError(t, err)
+
+	assert.Equal(t, otp.OTP("191882"), actual)
+}
+
+func TestGenerateTOTP_Success_NamespaceDefaults(t *testing.T) {
+	setConfigFile(t)
+
+	useInMemoryStorage(t)
+
+	t.Setenv("AUTHENTICATOR_TOTP_SECRET", "")
+
+	c := clock.Fix(time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC))
+
+	err := authenticator.CreateNamespace("bank", "Bank")
+	require.NoError(t, err)
+
+	n, err := authenticator.GetNamespace("bank")
+	require.NoError(t, err)
+
+	n.Digits = 8
+	n.Algorithm = authenticator.AlgorithmSHA256
+
+	err = authenticator.UpdateNamespace("bank", n)
+	require.NoError(t, err)
+
+	err = authenticator.SetAccount("bank", authenticator.Account{Name: "default", TOTPSecret: "NBSWY3DP"})
+	require.NoError(t, err)
+
+	err = authenticator.SetAccount("bank", authenticator.Account{Name: "override", TOTPSecret: "NBSWY3DP", Algorithm: authenticator.AlgorithmSHA1})
+	require.NoError(t, err)
+
+	testCases := []struct {
+		scenario string
+		account  string
+		opts     []authenticator.GenerateTOTPOption
+		expected otp.OTP
+	}{
+		{
+			scenario: "namespace defaults",
+			account:  "default",
+			expected: "28733626",
+		},
+		{
+			scenario: "account overrides namespace",
+			account:  "override",
+			expected: "74191882",
+		},
+		{
+			scenario: "options override namespace",
+			account:  "default",
+			opts:     []authenticator.GenerateTOTPOption{authenticator.WithDigits(6), authenticator.WithAlgorithm(authenticator.AlgorithmSHA1)},
+			expected: "191882",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.scenario, func(t *testing.T) {
+			actual, err := authenticator.GenerateTOTP(context.Background(), "bank", tc.account,
+				append([]authenticator.GenerateTOTPOption{authenticator.WithClock(c)}, tc.opts...)...,
+			)
+			require.NoError(t, err)
+
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+}
+
+func TestGenerateTOTP_Failure_InvalidAccountParameters(t *testing.T) {
+	setAccountStorage(t, func(s *mockss.Storage[authenticator.Account]) {
+		s.On("Get", "go.nhat.io/authenticator", fmt.Sprintf("%s/%s", t.Name(), "john.doe@example.com")).
+			Return(authenticator.Account{
+				Name:       "john.doe@example.com",
+				TOTPSecret: "NBSWY3DP",
+				Algorithm:  "MD5",
+			}, nil)
+	})
+
+	actual, err := authenticator.GenerateTOTP(context.Background(), t.Name(), "john.doe@example.com")
+	require.ErrorIs(t, err, authenticator.ErrUnsupportedAlgorithm)
+	assert.Empty(t, actual)
+}
+
 func TestGenerateTOTP_Failure_FailedToGetAccount(t *testing.T) {
 	setAccountStorage(t, func(s *mockss.Storage[authenticator.Account]) {
 		s.On("Get", "go.nhat.io/authenticator", fmt.Sprintf("%s/%s", t.Name(), "john.doe@example.com")).
@@ -128,6 +273,61 @@ func TestGenerateTOTP_Failure_FromTOTPSecret(t *testing.T) {
 	assert.Empty(t, actual)
 }
 
+func TestGenerateTOTP_Failure_InvalidSecret_ErrorsIs(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		scenario string
+		generate func(ctx context.Context) error
+	}{
+		{
+			scenario: "default parameters",
+			generate: func(ctx context.Context) error {
+				_, err := authenticator.GenerateTOTP(ctx, "namespace", "john.doe@example.com", authenticator.WithTOTPSecret("secret"))
+
+				return err
+			},
+		},
+		{
+			scenario: "custom parameters",
+			generate: func(ctx context.Context) error {
+				_, err := authenticator.GenerateTOTP(ctx, "namespace", "john.doe@example.com",
+					authenticator.WithTOTPSecret("secret"),
+					authenticator.WithAlgorithm(authenticator.AlgorithmSHA256),
+				)
+
+				return err
+			},
+		},
+		{
+			scenario: "with info",
+			generate: func(ctx context.Context) error {
+				_, err := authenticator.GenerateTOTPWithInfo(ctx, "namespace", "john.doe@example.com", authenticator.WithTOTPSecret("secret"))
+
+				return err
+			},
+		},
+		{
+			scenario: "hotp",
+			generate: func(ctx context.Context) error {
+				_, err := authenticator.GenerateHOTP(ctx, "namespace", "john.doe@example.com", 0, authenticator.WithTOTPSecret("secret"))
+
+				return err
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.scenario, func(t *testing.T) {
+			t.Parallel()
+
+			err := tc.generate(context.Background())
+			require.ErrorIs(t, err, authenticator.ErrInvalidSecret)
+			require.EqualError(t, err, `could not generate otp: Decoding of secret as base32 failed.`)
+		})
+	}
+}
+
 func TestGenerateTOTP_Success_FromSecretGetter(t *testing.T) {
 	c := clock.Fix(time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC))
 	s := mockotp.MockTOTPSecretGetter(func(g *mockotp.TOTPSecretGetter) {
@@ -159,6 +359,713 @@ func TestGenerateTOTP_Failure_FromSecretGetter(t *testing.T) {
 	assert.Empty(t, actual)
 }
 
+func TestGenerateTOTP_Success_WithDigits(t *testing.T) {
+	t.Parallel()
+
+	c := clock.Fix(time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC))
+
+	testCases := []struct {
+		digits   int
+		expected otp.OTP
+	}{
+		{digits: 6, expected: "191882"},
+		{digits: 8, expected: "74191882"},
+		{digits: 10, expected: "1274191882"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(fmt.Sprintf("%d digits", tc.digits), func(t *testing.T) {
+			t.Parallel()
+
+			actual, err := authenticator.GenerateTOTP(context.Background(), t.Name(), "john.doe@example.com",
+				authenticator.WithTOTPSecret("NBSWY3DP"),
+				authenticator.WithClock(c),
+				authenticator.WithDigits(tc.digits),
+			)
+			require.NoError(t, err)
+
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+}
+
+func TestGenerateTOTP_Success_EightDigitsDiffersFromSixDigits(t *testing.T) {
+	t.Parallel()
+
+	c := clock.Fix(time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC))
+
+	sixDigits, err := authenticator.GenerateTOTP(context.Background(), t.Name(), "john.doe@example.com",
+		authenticator.WithTOTPSecret("NBSWY3DP"),
+		authenticator.WithClock(c),
+	)
+	require.NoError(t, err)
+
+	eightDigits, err := authenticator.GenerateTOTP(context.Background(), t.Name(), "john.doe@example.com",
+		authenticator.WithTOTPSecret("NBSWY3DP"),
+		authenticator.WithClock(c),
+		authenticator.WithDigits(8),
+	)
+	require.NoError(t, err)
+
+	assert.Len(t, sixDigits, 6)
+	assert.Len(t, eightDigits, 8)
+	assert.NotEqual(t, sixDigits, eightDigits)
+}
+
+func TestGenerateTOTP_Failure_InvalidDigits(t *testing.T) {
+	t.Parallel()
+
+	for _, digits := range []int{-1, 5, 11} {
+		t.Run(fmt.Sprintf("%d digits", digits), func(t *testing.T) {
+			t.Parallel()
+
+			actual, err := authenticator.GenerateTOTP(context.Background(), t.Name(), "john.doe@example.com",
+				authenticator.WithTOTPSecret("NBSWY3DP"),
+				authenticator.WithDigits(digits),
+			)
+			require.ErrorIs(t, err, authenticator.ErrInvalidDigits)
+			require.EqualError(t, err, fmt.Sprintf("invalid number of digits: %d, must be between 6 and 10", digits))
+			assert.Empty(t, actual)
+		})
+	}
+}
+
+func TestGenerateTOTP_Success_WithAlgorithm(t *testing.T) {
+	t.Parallel()
+
+	// Test vectors from RFC 6238, Appendix B.
+	const (
+		sha1Secret   = otp.TOTPSecret("GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQ")
+		sha256Secret = otp.TOTPSecret("GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQGEZA====")
+		sha512Secret = otp.TOTPSecret("GEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQGEZDGNBVGY3TQOJQGEZDGNA=")
+	)
+
+	testCases := []struct {
+		algorithm string
+		secret    otp.TOTPSecret
+		time      int64
+		expected  otp.OTP
+	}{
+		{algorithm: "SHA1", secret: sha1Secret, time: 59, expected: "94287082"},
+		{algorithm: "SHA256", secret: sha256Secret, time: 59, expected: "46119246"},
+		{algorithm: "SHA512", secret: sha512Secret, time: 59, expected: "90693936"},
+		{algorithm: "sha1", secret: sha1Secret, time: 1111111109, expected: "07081804"},
+		{algorithm: "sha256", secret: sha256Secret, time: 1111111109, expected: "68084774"},
+		{algorithm: "Sha512", secret: sha512Secret, time: 1111111109, expected: "25091201"},
+	}
+
+	for _, tc := range testCases {
+		t.Run(fmt.Sprintf("%s at %d", tc.algorithm, tc.time), func(t *testing.T) {
+			t.Parallel()
+
+			actual, err := authenticator.GenerateTOTP(context.Background(), t.Name(), "john.doe@example.com",
+				authenticator.WithTOTPSecret(tc.secret),
+				authenticator.WithClock(clock.Fix(time.Unix(tc.time, 0))),
+				authenticator.WithDigits(8),
+				authenticator.WithAlgorithm(tc.algorithm),
+			)
+			require.NoError(t, err)
+
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+}
+
+func TestGenerateTOTP_Failure_UnsupportedAlgorithm(t *testing.T) {
+	t.Parallel()
+
+	actual, err := authenticator.GenerateTOTP(context.Background(), t.Name(), "john.doe@example.com",
+		authenticator.WithTOTPSecret("NBSWY3DP"),
+		authenticator.WithAlgorithm("MD5"),
+	)
+	require.ErrorIs(t, err, authenticator.ErrUnsupportedAlgorithm)
+	require.EqualError(t, err, `unsupported algorithm: MD5`)
+	assert.Empty(t, actual)
+}
+
+func TestGenerateTOTP_Failure_WithDigits_NoSecret(t *testing.T) {
+	setAccountStorage(t, func(s *mockss.Storage[authenticator.Account]) {
+		s.On("Get", "go.nhat.io/authenticator", fmt.Sprintf("%s/%s", t.Name(), "john.doe@example.com")).
+			Return(authenticator.Account{}, secretstorage.ErrNotFound)
+	})
+
+	actual, err := authenticator.GenerateTOTP(context.Background(), t.Name(), "john.doe@example.com",
+		authenticator.WithDigits(8),
+	)
+	require.EqualError(t, err, `could not generate otp: no totp secret`)
+	assert.Empty(t, actual)
+}
+
+func TestGenerateTOTP_Failure_WithDigits_InvalidSecret(t *testing.T) {
+	t.Parallel()
+
+	actual, err := authenticator.GenerateTOTP(context.Background(), t.Name(), "john.doe@example.com",
+		authenticator.WithTOTPSecret("secret"),
+		authenticator.WithDigits(8),
+	)
+	require.EqualError(t, err, `could not generate otp: Decoding of secret as base32 failed.`)
+	assert.Empty(t, actual)
+}
+
+func TestGenerateTOTP_Success_WithPeriod(t *testing.T) {
+	t.Parallel()
+
+	c := clock.Fix(time.Date(2024, time.January, 1, 0, 0, 18, 0, time.UTC))
+
+	actual, err := authenticator.GenerateTOTP(context.Background(), t.Name(), "john.doe@example.com",
+		authenticator.WithTOTPSecret("NBSWY3DP"),
+		authenticator.WithClock(c),
+		authenticator.WithPeriod(time.Minute),
+	)
+	require.NoError(t, err)
+
+	assert.Equal(t, otp.OTP("637663"), actual)
+}
+
+func TestGenerateTOTP_Failure_InvalidPeriod(t *testing.T) {
+	t.Parallel()
+
+	for _, period := range []time.Duration{-time.Second, 1500 * time.Millisecond} {
+		t.Run(period.String(), func(t *testing.T) {
+			t.Parallel()
+
+			actual, err := authenticator.GenerateTOTP(context.Background(), t.Name(), "john.doe@example.com",
+				authenticator.WithTOTPSecret("NBSWY3DP"),
+				authenticator.WithPeriod(period),
+			)
+			require.ErrorIs(t, err, authenticator.ErrInvalidPeriod)
+			assert.Empty(t, actual)
+		})
+	}
+}
+
+func TestGenerateTOTP_WithTime(t *testing.T) {
+	t.Parallel()
+
+	t1 := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+	t2 := t1.Add(time.Hour)
+
+	generate := func(t *testing.T, opts ...authenticator.GenerateTOTPOption) otp.OTP {
+		t.Helper()
+
+		opts = append([]authenticator.GenerateTOTPOption{authenticator.WithTOTPSecret("NBSWY3DP")}, opts...)
+
+		code, err := authenticator.GenerateTOTP(context.Background(), "namespace", "john.doe@example.com", opts...)
+		require.NoError(t, err)
+
+		return code
+	}
+
+	testCases := []struct {
+		scenario string
+		options  []authenticator.GenerateTOTPOption
+		expected []authenticator.GenerateTOTPOption
+	}{
+		{
+			scenario: "time",
+			options:  []authenticator.GenerateTOTPOption{authenticator.WithTime(t1)},
+			expected: []authenticator.GenerateTOTPOption{authenticator.WithClock(clock.Fix(t1))},
+		},
+		{
+			scenario: "time after clock",
+			options:  []authenticator.GenerateTOTPOption{authenticator.WithClock(clock.Fix(t1)), authenticator.WithTime(t2)},
+			expected: []authenticator.GenerateTOTPOption{authenticator.WithClock(clock.Fix(t2))},
+		},
+		{
+			scenario: "clock after time",
+			options:  []authenticator.GenerateTOTPOption{authenticator.WithTime(t2), authenticator.WithClock(clock.Fix(t1))},
+			expected: []authenticator.GenerateTOTPOption{authenticator.WithClock(clock.Fix(t1))},
+		},
+		{
+			scenario: "custom parameters",
+			options:  []authenticator.GenerateTOTPOption{authenticator.WithDigits(8), authenticator.WithTime(t2)},
+			expected: []authenticator.GenerateTOTPOption{authenticator.WithDigits(8), authenticator.WithClock(clock.Fix(t2))},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.scenario, func(t *testing.T) {
+			t.Parallel()
+
+			assert.Equal(t, generate(t, tc.expected...), generate(t, tc.options...))
+		})
+	}
+
+	assert.NotEqual(t, generate(t, authenticator.WithTime(t1)), generate(t, authenticator.WithTime(t2)))
+}
+
+func TestGenerateTOTP_WithTOTPGeneratorOption(t *testing.T) {
+	t.Parallel()
+
+	c := clock.Fix(time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC))
+
+	actual, err := authenticator.GenerateTOTP(context.Background(), "namespace", "john.doe@example.com",
+		authenticator.WithTOTPSecret("NBSWY3DP"),
+		authenticator.WithTOTPGeneratorOption(otp.WithClock(c)),
+	)
+	require.NoError(t, err)
+
+	assert.Equal(t, otp.OTP("191882"), actual)
+}
+
+func TestGenerateTOTP_Success_DirtySecret(t *testing.T) {
+	t.Parallel()
+
+	c := clock.Fix(time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC))
+
+	testCases := []string{
+		"nbswy3dp",
+		"nbsw y3dp",
+		" NBSW Y3DP ",
+		"NBSW-Y3DP",
+		"NBSWY3DP=",
+		"NBSWY3DP========",
+		"nbsw\ty3dp\n",
+	}
+
+	for _, secret := range testCases {
+		t.Run(secret, func(t *testing.T) {
+			t.Parallel()
+
+			actual, err := authenticator.GenerateTOTP(context.Background(), "namespace", "john.doe@example.com",
+				authenticator.WithTOTPSecret(otp.TOTPSecret(secret)),
+				authenticator.WithClock(c),
+			)
+			require.NoError(t, err)
+
+			assert.Equal(t, otp.OTP("191882"), actual)
+		})
+	}
+}
+
+func TestGenerateTOTP_WithAdditionalSecretGetter(t *testing.T) {
+	c := clock.Fix(time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC))
+
+	testCases := []struct {
+		scenario string
+		env      string
+		before   bool
+		expected otp.OTP
+	}{
+		{
+			scenario: "before the env",
+			env:      "JBSWY3DP",
+			before:   true,
+			expected: "191882",
+		},
+		{
+			scenario: "after the env",
+			env:      "JBSWY3DP",
+			expected: "180965",
+		},
+		{
+			scenario: "fallback",
+			expected: "191882",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.scenario, func(t *testing.T) {
+			setConfigFile(t)
+
+			useInMemoryStorage(t)
+
+			t.Setenv("AUTHENTICATOR_TOTP_SECRET", tc.env)
+
+			actual, err := authenticator.GenerateTOTP(context.Background(), "namespace", "john.doe@example.com",
+				authenticator.WithAdditionalSecretGetter(otp.NoTOTPSecret, tc.before),
+				authenticator.WithAdditionalSecretGetter(otp.TOTPSecret("NBSWY3DP"), tc.before),
+				authenticator.WithAdditionalSecretGetter(otp.TOTPSecret("KBSWY3DP"), tc.before),
+				authenticator.WithClock(c),
+			)
+			require.NoError(t, err)
+
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+}
+
+func TestTOTPSecretFromFunc(t *testing.T) {
+	t.Parallel()
+
+	c := clock.Fix(time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC))
+
+	t.Run("success", func(t *testing.T) {
+		t.Parallel()
+
+		g := authenticator.TOTPSecretFromFunc(func(context.Context) (otp.TOTPSecret, error) {
+			return "NBSWY3DP", nil
+		})
+
+		actual, err := authenticator.GenerateTOTP(context.Background(), "namespace", "john.doe@example.com",
+			authenticator.WithTOTPSecretGetter(g),
+			authenticator.WithClock(c),
+		)
+		require.NoError(t, err)
+
+		assert.Equal(t, otp.OTP("191882"), actual)
+	})
+
+	t.Run("error", func(t *testing.T) {
+		t.Parallel()
+
+		logger := &ctxd.LoggerMock{}
+
+		g := authenticator.TOTPSecretFromFunc(func(context.Context) (otp.TOTPSecret, error) {
+			return "NBSWY3DP", assert.AnError
+		}, authenticator.WithLogger(logger))
+
+		assert.Equal(t, otp.NoTOTPSecret, g.TOTPSecret(context.Background()))
+		assert.Contains(t, logger.String(), "could not get totp secret")
+	})
+}
+
+func TestGenerateTOTP_WithOnGenerate(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		scenario      string
+		secret        otp.TOTPSecret
+		expectedCalls []string
+	}{
+		{
+			scenario:      "success",
+			secret:        "NBSWY3DP",
+			expectedCalls: []string{"namespace/john.doe@example.com"},
+		},
+		{
+			scenario: "failure",
+			secret:   "secret",
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.scenario, func(t *testing.T) {
+			t.Parallel()
+
+			var calls []string
+
+			_, _ = authenticator.GenerateTOTP(context.Background(), "namespace", "john.doe@example.com", //nolint: errcheck
+				authenticator.WithTOTPSecret(tc.secret),
+				authenticator.WithOnGenerate(func(_ context.Context, namespace, account string) {
+					calls = append(calls, namespace+"/"+account)
+				}),
+			)
+
+			assert.Equal(t, tc.expectedCalls, calls)
+		})
+	}
+}
+
+func TestGenerateTOTPFromSecret(t *testing.T) {
+	t.Parallel()
+
+	c := clock.Fix(time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC))
+
+	testCases := []struct {
+		scenario string
+		opts     []authenticator.GenerateTOTPOption
+		expected otp.OTP
+	}{
+		{
+			scenario: "defaults",
+			opts:     []authenticator.GenerateTOTPOption{authenticator.WithClock(c)},
+			expected: "191882",
+		},
+		{
+			scenario: "custom parameters",
+			opts: []authenticator.GenerateTOTPOption{
+				authenticator.WithClock(c),
+				authenticator.WithDigits(8),
+				authenticator.WithPeriod(60 * time.Second),
+				authenticator.WithAlgorithm(authenticator.AlgorithmSHA256),
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.scenario, func(t *testing.T) {
+			t.Parallel()
+
+			expected, err := authenticator.GenerateTOTP(context.Background(), "namespace", "john.doe@example.com",
+				append([]authenticator.GenerateTOTPOption{authenticator.WithTOTPSecret("NBSWY3DP")}, tc.opts...)...,
+			)
+			require.NoError(t, err)
+
+			actual, err := authenticator.GenerateTOTPFromSecret(context.Background(), "NBSWY3DP", tc.opts...)
+			require.NoError(t, err)
+
+			assert.Equal(t, expected, actual)
+
+			if tc.expected != "" {
+				assert.Equal(t, tc.expected, actual)
+			}
+		})
+	}
+}
+
+func TestGenerateTOTPFromSecret_Error(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		scenario      string
+		secret        otp.TOTPSecret
+		opts          []authenticator.GenerateTOTPOption
+		expectedError error
+	}{
+		{
+			scenario:      "no secret",
+			expectedError: otp.ErrNoTOTPSecret,
+		},
+		{
+			scenario:      "invalid secret",
+			secret:        "secret",
+			expectedError: authenticator.ErrInvalidSecret,
+		},
+		{
+			scenario:      "invalid digits",
+			secret:        "NBSWY3DP",
+			opts:          []authenticator.GenerateTOTPOption{authenticator.WithDigits(3)},
+			expectedError: authenticator.ErrInvalidDigits,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.scenario, func(t *testing.T) {
+			t.Parallel()
+
+			actual, err := authenticator.GenerateTOTPFromSecret(context.Background(), tc.secret, tc.opts...)
+			require.ErrorIs(t, err, tc.expectedError)
+			assert.Empty(t, actual)
+		})
+	}
+}
+
+func TestGenerateTOTPWithInfo_Success(t *testing.T) {
+	t.Parallel()
+
+	testCases := []struct {
+		scenario string
+		time     time.Time
+		options  []authenticator.GenerateTOTPOption
+		expected authenticator.TOTPInfo
+	}{
+		{
+			scenario: "start of the period",
+			time:     time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC),
+			expected: authenticator.TOTPInfo{
+				Code:      "191882",
+				ExpiresAt: time.Date(2024, time.January, 1, 0, 0, 30, 0, time.UTC),
+				Period:    30 * time.Second,
+			},
+		},
+		{
+			scenario: "middle of the period",
+			time:     time.Date(2024, time.January, 1, 0, 0, 18, 0, time.UTC),
+			expected: authenticator.TOTPInfo{
+				Code:      "191882",
+				ExpiresAt: time.Date(2024, time.January, 1, 0, 0, 30, 0, time.UTC),
+				Period:    30 * time.Second,
+			},
+		},
+		{
+			scenario: "custom period",
+			time:     time.Date(2024, time.January, 1, 0, 0, 18, 0, time.UTC),
+			options:  []authenticator.GenerateTOTPOption{authenticator.WithPeriod(time.Minute)},
+			expected: authenticator.TOTPInfo{
+				Code:      "637663",
+				ExpiresAt: time.Date(2024, time.January, 1, 0, 1, 0, 0, time.UTC),
+				Period:    time.Minute,
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.scenario, func(t *testing.T) {
+			t.Parallel()
+
+			opts := append([]authenticator.GenerateTOTPOption{
+				authenticator.WithTOTPSecret("NBSWY3DP"),
+				authenticator.WithClock(clock.Fix(tc.time)),
+			}, tc.options...)
+
+			actual, err := authenticator.GenerateTOTPWithInfo(context.Background(), t.Name(), "john.doe@example.com", opts...)
+			require.NoError(t, err)
+
+			assert.Equal(t, tc.expected, actual)
+		})
+	}
+}
+
+func TestGenerateTOTPWindow_Success(t *testing.T) {
+	t.Parallel()
+
+	now := time.Date(2024, time.January, 1, 0, 0, 18, 0, time.UTC)
+
+	testCases := []struct {
+		scenario string
+		before   int
+		after    int
+		options  []authenticator.GenerateTOTPOption
+		period   time.Duration
+	}{
+		{
+			scenario: "current only",
+			period:   30 * time.Second,
+		},
+		{
+			scenario: "one before and one after",
+			before:   1,
+			after:    1,
+			period:   30 * time.Second,
+		},
+		{
+			scenario: "next two",
+			after:    2,
+			period:   30 * time.Second,
+		},
+		{
+			scenario: "custom parameters",
+			before:   2,
+			after:    1,
+			options:  []authenticator.GenerateTOTPOption{authenticator.WithPeriod(time.Minute), authenticator.WithDigits(8)},
+			period:   time.Minute,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.scenario, func(t *testing.T) {
+			t.Parallel()
+
+			opts := append([]authenticator.GenerateTOTPOption{authenticator.WithTOTPSecret("NBSWY3DP")}, tc.options...)
+
+			actual, err := authenticator.GenerateTOTPWindow(context.Background(), t.Name(), "john.doe@example.com", tc.before, tc.after,
+				append(opts, authenticator.WithClock(clock.Fix(now)))...,
+			)
+			require.NoError(t, err)
+			require.Len(t, actual, tc.before+tc.after+1)
+
+			for i, code := range actual {
+				at := now.Add(time.Duration(i-tc.before) * tc.period)
+
+				expected, err := authenticator.GenerateTOTP(context.Background(), t.Name(), "john.doe@example.com",
+					append(opts, authenticator.WithClock(clock.Fix(at)))...,
+				)
+				require.NoError(t, err)
+
+				assert.Equal(t, expected, code, "code #%d", i)
+			}
+		})
+	}
+}
+
+func TestGenerateTOTPWindow_Failure(t *testing.T) {
+	t.Parallel()
+
+	actual, err := authenticator.GenerateTOTPWindow(context.Background(), t.Name(), "john.doe@example.com", -1, 1,
+		authenticator.WithTOTPSecret("NBSWY3DP"),
+	)
+	require.ErrorIs(t, err, authenticator.ErrInvalidWindow)
+	assert.Empty(t, actual)
+
+	actual, err = authenticator.GenerateTOTPWindow(context.Background(), t.Name(), "john.doe@example.com", 1, 1,
+		authenticator.WithTOTPSecret("secret"),
+	)
+	require.ErrorIs(t, err, authenticator.ErrInvalidSecret)
+	assert.Empty(t, actual)
+}
+
+func TestGenerateTOTPSeries_Success(t *testing.T) {
+	t.Parallel()
+
+	from := time.Date(2024, time.January, 1, 0, 0, 18, 0, time.UTC)
+	to := time.Date(2024, time.January, 1, 0, 1, 0, 0, time.UTC)
+
+	actual, err := authenticator.GenerateTOTPSeries(context.Background(), t.Name(), "john.doe@example.com", from, to,
+		authenticator.WithTOTPSecret("NBSWY3DP"),
+	)
+	require.NoError(t, err)
+
+	expected := make([]authenticator.TOTPSeriesEntry, 0, 3)
+
+	for _, at := range []time.Time{
+		time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC),
+		time.Date(2024, time.January, 1, 0, 0, 30, 0, time.UTC),
+		time.Date(2024, time.January, 1, 0, 1, 0, 0, time.UTC),
+	} {
+		code, err := authenticator.GenerateTOTPFromSecret(context.Background(), "NBSWY3DP", authenticator.WithClock(clock.Fix(at)))
+		require.NoError(t, err)
+
+		expected = append(expected, authenticator.TOTPSeriesEntry{Time: at, Code: code})
+	}
+
+	assert.Equal(t, expected, actual)
+	assert.Equal(t, otp.OTP("191882"), actual[0].Code)
+}
+
+func TestGenerateTOTPSeries_Failure(t *testing.T) {
+	t.Parallel()
+
+	from := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+	testCases := []struct {
+		scenario      string
+		to            time.Time
+		options       []authenticator.GenerateTOTPOption
+		expectedError error
+	}{
+		{
+			scenario:      "reversed range",
+			to:            from.Add(-time.Second),
+			expectedError: authenticator.ErrInvalidRange,
+		},
+		{
+			scenario:      "range too large",
+			to:            from.Add(authenticator.MaxTOTPSeriesSteps * 30 * time.Second),
+			expectedError: authenticator.ErrRangeTooLarge,
+		},
+		{
+			scenario:      "invalid secret",
+			to:            from,
+			options:       []authenticator.GenerateTOTPOption{authenticator.WithTOTPSecret("secret")},
+			expectedError: authenticator.ErrInvalidSecret,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.scenario, func(t *testing.T) {
+			t.Parallel()
+
+			opts := append([]authenticator.GenerateTOTPOption{authenticator.WithTOTPSecret("NBSWY3DP")}, tc.options...)
+
+			actual, err := authenticator.GenerateTOTPSeries(context.Background(), t.Name(), "john.doe@example.com", from, tc.to, opts...)
+			require.ErrorIs(t, err, tc.expectedError)
+			assert.Empty(t, actual)
+		})
+	}
+}
+
+func TestGenerateTOTPWithInfo_Failure(t *testing.T) {
+	t.Parallel()
+
+	actual, err := authenticator.GenerateTOTPWithInfo(context.Background(), t.Name(), "john.doe@example.com",
+		authenticator.WithTOTPSecret("secret"),
+	)
+	require.EqualError(t, err, `could not generate otp: Decoding of secret as base32 failed.`)
+	assert.Empty(t, actual)
+}
+
+func TestGenerateTOTPWithInfo_InvalidOption(t *testing.T) {
+	t.Parallel()
+
+	actual, err := authenticator.GenerateTOTPWithInfo(context.Background(), t.Name(), "john.doe@example.com",
+		authenticator.WithTOTPSecret("NBSWY3DP"),
+		authenticator.WithDigits(1),
+	)
+	require.ErrorIs(t, err, authenticator.ErrInvalidDigits)
+	assert.Empty(t, actual)
+}
+
 func TestTOTPSecretProvider_TOTPSecret_MissingNamespace(t *testing.T) {
 	setAccountStorage(t)
 
@@ -220,6 +1127,152 @@ func TestTOTPSecretProvider_TOTPSecret_Success(t *testing.T) {
 	assert.Equal(t, otp.TOTPSecret("secret"), actual)
 }
 
+func TestTOTPSecretProvider_TOTPSecret_ContextCancelled(t *testing.T) {
+	setAccountStorage(t, func(s *mockss.Storage[authenticator.Account]) {
+		s.On("Get", "go.nhat.io/authenticator", fmt.Sprintf("%s/%s", t.Name(), "john.doe@example.com")).Once().
+			Return(authenticator.Account{TOTPSecret: "NBSWY3DP"}, nil)
+	})
+
+	p := authenticator.TOTPSecretFromAccount(t.Name(), "john.doe@example.com")
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// The storage is not called.
+	actual := p.TOTPSecret(ctx)
+
+	assert.Equal(t, otp.NoTOTPSecret, actual)
+
+	// The secret is fetched on the next call.
+	actual = p.TOTPSecret(context.Background())
+
+	assert.Equal(t, otp.TOTPSecret("NBSWY3DP"), actual)
+}
+
+func TestTOTPSecretProvider_TOTPSecret_ContextDeadlineExceeded(t *testing.T) {
+	release := make(chan time.Time)
+
+	setAccountStorage(t, func(s *mockss.Storage[authenticator.Account]) {
+		s.On("Get", "go.nhat.io/authenticator", fmt.Sprintf("%s/%s", t.Name(), "john.doe@example.com")).Once().
+			WaitUntil(release).
+			Return(authenticator.Account{TOTPSecret: "NBSWY3DP"}, nil)
+	})
+
+	t.Cleanup(func() {
+		close(release)
+	})
+
+	p := authenticator.TOTPSecretFromAccount(t.Name(), "john.doe@example.com")
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	start := time.Now()
+	actual := p.TOTPSecret(ctx)
+
+	assert.Equal(t, otp.NoTOTPSecret, actual)
+	assert.Less(t, time.Since(start), time.Second)
+}
+
+func TestTOTPSecretProvider_TOTPSecret_LateFetchAfterCancellation(t *testing.T) {
+	started := make(chan struct{})
+	release := make(chan struct{})
+
+	setAccountStorage(t, func(s *mockss.Storage[authenticator.Account]) {
+		s.On("Get", "go.nhat.io/authenticator", fmt.Sprintf("%s/%s", t.Name(), "john.doe@example.com")).Once().
+			Run(func(mock.Arguments) {
+				close(started)
+				<-release
+			}).
+			Return(authenticator.Account{TOTPSecret: "NBSWY3DP"}, nil)
+
+		s.On("Get", "go.nhat.io/authenticator", fmt.Sprintf("%s/%s", t.Name(), "john.doe@example.com")).Once().
+			Return(authenticator.Account{TOTPSecret: "JBSWY3DP"}, nil)
+	})
+
+	p := authenticator.TOTPSecretFromAccount(t.Name(), "john.doe@example.com")
+
+	ctx, cancel := context.WithCancel(context.Background())
+
+	go func() {
+		<-started
+		cancel()
+	}()
+
+	assert.Equal(t, otp.NoTOTPSecret, p.TOTPSecret(ctx))
+
+	// The refresh fetches the new secret while the first fetch is still running.
+	p.Refresh(context.Background())
+
+	assert.Equal(t, otp.TOTPSecret("JBSWY3DP"), p.TOTPSecret(context.Background()))
+
+	// The first fetch finishes after the cancellation, its result is dropped.
+	close(release)
+
+	assert.Never(t, func() bool {
+		return p.TOTPSecret(context.Background()) != "JBSWY3DP"
+	}, 50*time.Millisecond, 5*time.Millisecond)
+}
+
+func TestTOTPSecretProvider_Refresh(t *testing.T) {
+	accounts, _ := useInMemoryStorage(t)
+
+	setSecret := func(secret otp.TOTPSecret) {
+		err := accounts.Set("go.nhat.io/authenticator", t.Name()+"/john.doe@example.com", authenticator.Account{
+			Name:       "john.doe@example.com",
+			TOTPSecret: secret,
+		})
+		require.NoError(t, err)
+	}
+
+	setSecret("NBSWY3DP")
+
+	p := authenticator.TOTPSecretFromAccount(t.Name(), "john.doe@example.com")
+
+	assert.Equal(t, otp.TOTPSecret("NBSWY3DP"), p.TOTPSecret(context.Background()))
+
+	// Rotated by another process.
+	setSecret("JBSWY3DP")
+
+	assert.Equal(t, otp.TOTPSecret("NBSWY3DP"), p.TOTPSecret(context.Background()), "the secret is cached")
+
+	p.Refresh(context.Background())
+
+	assert.Equal(t, otp.TOTPSecret("JBSWY3DP"), p.TOTPSecret(context.Background()))
+}
+
+func TestTOTPSecretProvider_WithRefreshInterval(t *testing.T) {
+	accounts, _ := useInMemoryStorage(t)
+
+	setSecret := func(secret otp.TOTPSecret) {
+		err := accounts.Set("go.nhat.io/authenticator", t.Name()+"/john.doe@example.com", authenticator.Account{
+			Name:       "john.doe@example.com",
+			TOTPSecret: secret,
+		})
+		require.NoError(t, err)
+	}
+
+	c := &stepClock{now: time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)}
+
+	setSecret("NBSWY3DP")
+
+	p := authenticator.TOTPSecretFromAccount(t.Name(), "john.doe@example.com",
+		authenticator.WithClock(c),
+		authenticator.WithRefreshInterval(time.Minute),
+	)
+
+	assert.Equal(t, otp.TOTPSecret("NBSWY3DP"), p.TOTPSecret(context.Background()))
+
+	setSecret("JBSWY3DP")
+	c.Add(59 * time.Second)
+
+	assert.Equal(t, otp.TOTPSecret("NBSWY3DP"), p.TOTPSecret(context.Background()), "the secret is not expired yet")
+
+	c.Add(time.Second)
+
+	assert.Equal(t, otp.TOTPSecret("JBSWY3DP"), p.TOTPSecret(context.Background()))
+}
+
 func TestTOTPSecretProvider_SetTOTPSecret_NamespaceNotFound_FailedToCreateNamespace(t *testing.T) {
 	setConfigFile(t)
 
@@ -288,6 +1341,7 @@ func TestTOTPSecretProvider_SetTOTPSecret_NamespaceNotFound_AccountNotFound_Fail
 }
 
 func TestTOTPSecretProvider_SetTOTPSecret_NamespaceNotFound_AccountNotFound_Success(t *testing.T) {
+	setAccountClock(t)
 	setConfigFile(t)
 
 	setNamespaceStorage(t, func(s *mockss.Storage[authenticator.Namespace]) {
@@ -304,6 +1358,7 @@ func TestTOTPSecretProvider_SetTOTPSecret_NamespaceNotFound_AccountNotFound_Succ
 			authenticator.Namespace{
 				Name:     t.Name(),
 				Accounts: []string{"john.doe@example.com"},
+				Issuers:  map[string][]string{"issuer": {"john.doe@example.com"}},
 			}).
 			Once().
 			Return(nil)
@@ -318,6 +1373,8 @@ func TestTOTPSecretProvider_SetTOTPSecret_NamespaceNotFound_AccountNotFound_Succ
 				Name:       "john.doe@example.com",
 				TOTPSecret: "secret",
 				Issuer:     "issuer",
+				CreatedAt:  accountTime,
+				UpdatedAt:  accountTime,
 			}).
 			Return(nil)
 	})
@@ -364,6 +1421,7 @@ func TestTOTPSecretProvider_SetTOTPSecret_NamespaceNotFound_AccountExists_Failed
 }
 
 func TestTOTPSecretProvider_SetTOTPSecret_NamespaceNotFound_AccountExists_Success(t *testing.T) {
+	setAccountClock(t)
 	setConfigFile(t)
 
 	setNamespaceStorage(t, func(s *mockss.Storage[authenticator.Namespace]) {
@@ -380,6 +1438,7 @@ func TestTOTPSecretProvider_SetTOTPSecret_NamespaceNotFound_AccountExists_Succes
 			authenticator.Namespace{
 				Name:     t.Name(),
 				Accounts: []string{"john.doe@example.com"},
+				Issuers:  map[string][]string{"issuer": {"john.doe@example.com"}},
 			}).
 			Once().
 			Return(nil)
@@ -398,6 +1457,8 @@ func TestTOTPSecretProvider_SetTOTPSecret_NamespaceNotFound_AccountExists_Succes
 				Name:       "john.doe@example.com",
 				TOTPSecret: "secret",
 				Issuer:     "issuer",
+				CreatedAt:  accountTime,
+				UpdatedAt:  accountTime,
 			}).
 			Return(nil)
 	})
@@ -449,6 +1510,7 @@ func TestTOTPSecretProvider_SetTOTPSecret_NamespaceExists_AccountNotFound_Failed
 }
 
 func TestTOTPSecretProvider_SetTOTPSecret_NamespaceExists_AccountNotFound_Success(t *testing.T) {
+	setAccountClock(t)
 	setConfigFileWithContent(t, fmt.Sprintf(`namespaces = [%q]`, t.Name()))
 
 	setNamespaceStorage(t, func(s *mockss.Storage[authenticator.Namespace]) {
@@ -467,6 +1529,8 @@ func TestTOTPSecretProvider_SetTOTPSecret_NamespaceExists_AccountNotFound_Succes
 				Name:       "john.doe@example.com",
 				TOTPSecret: "secret",
 				Issuer:     "issuer",
+				CreatedAt:  accountTime,
+				UpdatedAt:  accountTime,
 			}).
 			Return(nil)
 	})
@@ -506,6 +1570,7 @@ func TestTOTPSecretProvider_SetTOTPSecret_NamespaceExists_AccountExists_FailedTo
 }
 
 func TestTOTPSecretProvider_SetTOTPSecret_NamespaceExists_AccountExists_Success(t *testing.T) {
+	setAccountClock(t)
 	setConfigFileWithContent(t, fmt.Sprintf(`namespaces = [%q]`, t.Name()))
 
 	setNamespaceStorage(t, func(s *mockss.Storage[authenticator.Namespace]) {
@@ -528,6 +1593,8 @@ func TestTOTPSecretProvider_SetTOTPSecret_NamespaceExists_AccountExists_Success(
 				Name:       "john.doe@example.com",
 				TOTPSecret: "secret",
 				Issuer:     "issuer",
+				CreatedAt:  accountTime,
+				UpdatedAt:  accountTime,
 			}).
 			Return(nil)
 	})
@@ -571,3 +1638,270 @@ func TestTOTPSecretProvider_DeleteTOTPSecret_Success(t *testing.T) {
 
 	assert.Equal(t, otp.NoTOTPSecret, actual)
 }
+
+type stepClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *stepClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.now
+}
+
+func (c *stepClock) Add(d time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.now = c.now.Add(d)
+}
+
+func TestGenerateTOTP_WithCodeCache(t *testing.T) {
+	setConfigFile(t)
+
+	accounts, _ := useInMemoryStorage(t)
+
+	t.Setenv("AUTHENTICATOR_TOTP_SECRET", "")
+
+	t0 := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+	// store changes the secret behind the back of the cache, like another process would.
+	store := func(t *testing.T, account string, secret otp.TOTPSecret) {
+		t.Helper()
+
+		err := accounts.Set("go.nhat.io/authenticator", "namespace/"+account, authenticator.Account{Name: account, TOTPSecret: secret})
+		require.NoError(t, err)
+	}
+
+	generate := func(t *testing.T, account string, ttl time.Duration, at time.Time, opts ...authenticator.GenerateTOTPOption) otp.OTP {
+		t.Helper()
+
+		opts = append([]authenticator.GenerateTOTPOption{
+			authenticator.WithClock(clock.Fix(at)),
+			authenticator.WithCodeCache(ttl),
+		}, opts...)
+
+		code, err := authenticator.GenerateTOTP(context.Background(), "namespace", account, opts...)
+		require.NoError(t, err)
+
+		return code
+	}
+
+	expected := func(t *testing.T, secret otp.TOTPSecret, at time.Time) otp.OTP {
+		t.Helper()
+
+		code, err := authenticator.GenerateTOTPFromSecret(context.Background(), secret, authenticator.WithClock(clock.Fix(at)))
+		require.NoError(t, err)
+
+		return code
+	}
+
+	t.Run("reused within the time step", func(t *testing.T) {
+		const account = "cache-step@example.com"
+
+		store(t, account, "NBSWY3DP")
+		assert.Equal(t, otp.OTP("191882"), generate(t, account, time.Minute, t0))
+
+		// The secret is not read again until the code expires.
+		store(t, account, "JBSWY3DP")
+		assert.Equal(t, otp.OTP("191882"), generate(t, account, time.Minute, t0.Add(29*time.Second)))
+	})
+
+	t.Run("expired at the step boundary", func(t *testing.T) {
+		const account = "cache-boundary@example.com"
+
+		at := t0.Add(29 * time.Second)
+		boundary := t0.Add(30 * time.Second)
+
+		store(t, account, "NBSWY3DP")
+		assert.Equal(t, expected(t, "NBSWY3DP", at), generate(t, account, time.Minute, at))
+
+		store(t, account, "JBSWY3DP")
+		assert.Equal(t, expected(t, "JBSWY3DP", boundary), generate(t, account, time.Minute, boundary))
+	})
+
+	t.Run("expired after the ttl", func(t *testing.T) {
+		const account = "cache-ttl@example.com"
+
+		at := t0.Add(5 * time.Second)
+
+		store(t, account, "NBSWY3DP")
+		assert.Equal(t, otp.OTP("191882"), generate(t, account, 5*time.Second, t0))
+
+		store(t, account, "JBSWY3DP")
+		assert.Equal(t, expected(t, "JBSWY3DP", at), generate(t, account, 5*time.Second, at))
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		const account = "cache-disabled@example.com"
+
+		store(t, account, "NBSWY3DP")
+		assert.Equal(t, otp.OTP("191882"), generate(t, account, 0, t0))
+
+		store(t, account, "JBSWY3DP")
+		assert.Equal(t, otp.OTP("180965"), generate(t, account, 0, t0))
+	})
+
+	t.Run("bypassed with the secret option", func(t *testing.T) {
+		const account = "cache-secret@example.com"
+
+		store(t, account, "NBSWY3DP")
+		assert.Equal(t, otp.OTP("191882"), generate(t, account, time.Minute, t0))
+
+		// The code of the stored account is cached, but it is not the one of the given secret.
+		assert.Equal(t, otp.OTP("180965"), generate(t, account, time.Minute, t0, authenticator.WithTOTPSecret("JBSWY3DP")))
+		assert.Equal(t, otp.OTP("180965"), generate(t, account, time.Minute, t0,
+			authenticator.WithTOTPSecretGetter(otp.TOTPSecret("JBSWY3DP"))))
+		assert.Equal(t, otp.OTP("180965"), generate(t, account, time.Minute, t0,
+			authenticator.WithAdditionalSecretGetter(otp.TOTPSecret("JBSWY3DP"), true)))
+	})
+
+	t.Run("bypassed with the env secret", func(t *testing.T) {
+		const account = "cache-env@example.com"
+
+		store(t, account, "NBSWY3DP")
+		assert.Equal(t, otp.OTP("191882"), generate(t, account, time.Minute, t0))
+
+		t.Setenv("AUTHENTICATOR_TOTP_SECRET", "JBSWY3DP")
+
+		assert.Equal(t, otp.OTP("180965"), generate(t, account, time.Minute, t0))
+	})
+}
+
+func TestGenerateTOTP_WithCodeCache_InvalidatedOnSetAccount(t *testing.T) {
+	setConfigFile(t)
+
+	useInMemoryStorage(t)
+
+	t.Setenv("AUTHENTICATOR_TOTP_SECRET", "")
+
+	c := clock.Fix(time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC))
+
+	err := authenticator.CreateNamespace("namespace", "Namespace")
+	require.NoError(t, err)
+
+	err = authenticator.SetAccount("namespace", authenticator.Account{Name: "cache-set@example.com", TOTPSecret: "NBSWY3DP"})
+	require.NoError(t, err)
+
+	code, err := authenticator.GenerateTOTP(context.Background(), "namespace", "cache-set@example.com",
+		authenticator.WithClock(c),
+		authenticator.WithCodeCache(time.Minute),
+	)
+	require.NoError(t, err)
+	assert.Equal(t, otp.OTP("191882"), code)
+
+	err = authenticator.SetAccount("namespace", authenticator.Account{Name: "cache-set@example.com", TOTPSecret: "JBSWY3DP"})
+	require.NoError(t, err)
+
+	code, err = authenticator.GenerateTOTP(context.Background(), "namespace", "cache-set@example.com",
+		authenticator.WithClock(c),
+		authenticator.WithCodeCache(time.Minute),
+	)
+	require.NoError(t, err)
+	assert.Equal(t, otp.OTP("180965"), code)
+}
+
+func BenchmarkGenerateTOTP_CodeCache(b *testing.B) {
+	b.Setenv("AUTHENTICATOR_TOTP_SECRET", "")
+
+	accounts, _ := useInMemoryStorage(b)
+
+	err := accounts.Set("go.nhat.io/authenticator", "namespace/bench@example.com", authenticator.Account{Name: "bench@example.com", TOTPSecret: "NBSWY3DP"})
+	require.NoError(b, err)
+
+	c := clock.Fix(time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC))
+
+	for _, ttl := range []time.Duration{0, time.Minute} {
+		b.Run(fmt.Sprintf("ttl=%s", ttl), func(b *testing.B) {
+			for range b.N {
+				_, _ = authenticator.GenerateTOTP(context.Background(), "namespace", "bench@example.com", //nolint: errcheck
+					authenticator.WithClock(c),
+					authenticator.WithCodeCache(ttl),
+				)
+			}
+		})
+	}
+}
+
+func TestGenerateTOTP_WithEpoch(t *testing.T) {
+	t.Parallel()
+
+	t0 := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+	// With T0 at 2024-01-01, the code at 2024-01-01 00:00:45 is the one of the second time step, as at 00:00:45 UTC on
+	// 1970-01-01 with the unix epoch.
+	expected, err := authenticator.GenerateTOTPFromSecret(context.Background(), "NBSWY3DP",
+		authenticator.WithClock(clock.Fix(time.Unix(45, 0))),
+	)
+	require.NoError(t, err)
+
+	actual, err := authenticator.GenerateTOTP(context.Background(), t.Name(), "john.doe@example.com",
+		authenticator.WithTOTPSecret("NBSWY3DP"),
+		authenticator.WithClock(clock.Fix(t0.Add(45*time.Second))),
+		authenticator.WithEpoch(t0),
+	)
+	require.NoError(t, err)
+	assert.Equal(t, expected, actual)
+
+	// The unix epoch gives another code.
+	actual, err = authenticator.GenerateTOTP(context.Background(), t.Name(), "john.doe@example.com",
+		authenticator.WithTOTPSecret("NBSWY3DP"),
+		authenticator.WithClock(clock.Fix(t0.Add(45*time.Second))),
+		authenticator.WithEpoch(time.Unix(0, 0)),
+	)
+	require.NoError(t, err)
+	assert.NotEqual(t, expected, actual)
+
+	// The time steps start at T0.
+	info, err := authenticator.GenerateTOTPWithInfo(context.Background(), t.Name(), "john.doe@example.com",
+		authenticator.WithTOTPSecret("NBSWY3DP"),
+		authenticator.WithClock(clock.Fix(time.Date(2024, time.January, 1, 0, 0, 45, 0, time.UTC))),
+		authenticator.WithEpoch(time.Date(2024, time.January, 1, 0, 0, 10, 0, time.UTC)),
+	)
+	require.NoError(t, err)
+	assert.Equal(t, time.Date(2024, time.January, 1, 0, 1, 10, 0, time.UTC), info.ExpiresAt)
+}
+
+func TestGenerateTOTP_WithEpoch_BeforeEpoch(t *testing.T) {
+	t.Parallel()
+
+	t0 := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+	actual, err := authenticator.GenerateTOTP(context.Background(), t.Name(), "john.doe@example.com",
+		authenticator.WithTOTPSecret("NBSWY3DP"),
+		authenticator.WithClock(clock.Fix(t0.Add(-time.Second))),
+		authenticator.WithEpoch(t0),
+	)
+	require.ErrorIs(t, err, authenticator.ErrInvalidEpoch)
+	assert.Empty(t, actual)
+}
+
+func TestGenerateTOTP_Success_AccountEpoch(t *testing.T) {
+	setConfigFile(t)
+
+	useInMemoryStorage(t)
+
+	t.Setenv("AUTHENTICATOR_TOTP_SECRET", "")
+
+	t0 := time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+	err := authenticator.CreateNamespace("namespace", "Namespace")
+	require.NoError(t, err)
+
+	err = authenticator.SetAccount("namespace", authenticator.Account{Name: "john.doe@example.com", TOTPSecret: "NBSWY3DP", Epoch: t0.Unix()})
+	require.NoError(t, err)
+
+	actual, err := authenticator.GenerateTOTP(context.Background(), "namespace", "john.doe@example.com",
+		authenticator.WithClock(clock.Fix(t0.Add(45*time.Second))),
+	)
+	require.NoError(t, err)
+
+	expected, err := authenticator.GenerateTOTPFromSecret(context.Background(), "NBSWY3DP",
+		authenticator.WithClock(clock.Fix(time.Unix(45, 0))),
+	)
+	require.NoError(t, err)
+
+	assert.Equal(t, expected, actual)
+}